@@ -12,6 +12,7 @@ import (
 	"github.com/maplelabs/github-audit/internal/configurator"
 	"github.com/maplelabs/github-audit/internal/taskmanager"
 	"github.com/maplelabs/github-audit/logger"
+	"github.com/maplelabs/github-audit/publisher"
 	"github.com/spf13/cobra"
 )
 
@@ -110,5 +111,7 @@ func StartGitAudit(ctx context.Context) error {
 	}
 	//blocking call returns only if github-audit stops or github-audit crashes
 	taskmanager.StartTasks(ctx, tasks)
+	// uploading documents buffered by targets before exiting
+	publisher.Flush()
 	return nil
 }
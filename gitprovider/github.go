@@ -3,12 +3,22 @@ package gitprovider
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/google/go-github/v48/github"
 	"golang.org/x/oauth2"
 )
 
+const (
+	// statsPollAttempts is the max number of requests made to a stats endpoint while github computes it
+	statsPollAttempts = 5
+
+	// statsPollInterval is the wait between two requests to a stats endpoint
+	statsPollInterval = 3 * time.Second
+)
+
 // GithubClient represents new Github client to access github APIs
 type GithubClient struct {
 	// Client is github access client
@@ -140,3 +150,746 @@ func (gc *GithubClient) GetIssues(from time.Time) ([]byte, error) {
 	allIssuesByte, err := json.Marshal(allIssues)
 	return allIssuesByte, err
 }
+
+// GetContributorStats fetches weekly additions , deletions and commits for each contributor
+func (gc *GithubClient) GetContributorStats() ([]byte, error) {
+	log.Debugf("contributor stats to be fetched for repository %v", gc.RepositoryName)
+	var stats []*github.ContributorStats
+	err := gc.waitForStats(func() (err error) {
+		stats, _, err = gc.Client.Repositories.ListContributorsStats(gc.ctx, gc.RepositoryOwner, gc.RepositoryName)
+		return err
+	})
+	if err != nil {
+		log.Errorf("error[%v] in fetching contributor stats for repository %v", err, gc.RepositoryName)
+		return nil, err
+	}
+	return json.Marshal(stats)
+}
+
+// GetCodeFrequency fetches weekly additions and deletions for the repository
+func (gc *GithubClient) GetCodeFrequency() ([]byte, error) {
+	log.Debugf("code frequency to be fetched for repository %v", gc.RepositoryName)
+	var stats []*github.WeeklyStats
+	err := gc.waitForStats(func() (err error) {
+		stats, _, err = gc.Client.Repositories.ListCodeFrequency(gc.ctx, gc.RepositoryOwner, gc.RepositoryName)
+		return err
+	})
+	if err != nil {
+		log.Errorf("error[%v] in fetching code frequency for repository %v", err, gc.RepositoryName)
+		return nil, err
+	}
+	return json.Marshal(stats)
+}
+
+// GetCommitActivity fetches weekly commit counts for the last year of the repository
+func (gc *GithubClient) GetCommitActivity() ([]byte, error) {
+	log.Debugf("commit activity to be fetched for repository %v", gc.RepositoryName)
+	var stats []*github.WeeklyCommitActivity
+	err := gc.waitForStats(func() (err error) {
+		stats, _, err = gc.Client.Repositories.ListCommitActivity(gc.ctx, gc.RepositoryOwner, gc.RepositoryName)
+		return err
+	})
+	if err != nil {
+		log.Errorf("error[%v] in fetching commit activity for repository %v", err, gc.RepositoryName)
+		return nil, err
+	}
+	return json.Marshal(stats)
+}
+
+// waitForStats calls a stats endpoint until github has finished computing the statistics.
+// Github answers 202 Accepted while statistics are being computed in background , in that case
+// the request is retried after statsPollInterval for at most statsPollAttempts times.
+// Github answers 422 Unprocessable Entity when it does not compute the statistics for the repository.
+func (gc *GithubClient) waitForStats(fetch func() error) error {
+	for attempt := 1; attempt <= statsPollAttempts; attempt++ {
+		err := fetch()
+		if errResp, ok := err.(*github.ErrorResponse); ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnprocessableEntity {
+			return ErrStatsUnavailable
+		}
+		if _, ok := err.(*github.AcceptedError); !ok {
+			return err
+		}
+		log.Debugf("statistics are being computed for repository %v , attempt %v of %v", gc.RepositoryName, attempt, statsPollAttempts)
+		time.Sleep(statsPollInterval)
+	}
+	return ErrStatsNotReady
+}
+
+// Deployment represents a github deployment along with its statuses , latest status first
+type Deployment struct {
+	*github.Deployment
+
+	// Statuses of the deployment
+	Statuses []*github.DeploymentStatus `json:"statuses"`
+}
+
+// GetDeployments fetches deployments to an environment created after from , along with their statuses
+func (gc *GithubClient) GetDeployments(environment string, from time.Time) ([]byte, error) {
+	log.Debugf("deployments to be fetched for environment %v after %v for repository %v", environment, from, gc.RepositoryName)
+	opt := &github.DeploymentsListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		Environment: environment,
+	}
+	var allDeployments []*Deployment
+	for {
+		deployments, resp, err := gc.Client.Repositories.ListDeployments(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching deployments for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		done := false
+		// deployments are listed with latest first
+		for _, d := range deployments {
+			if d.GetCreatedAt().Before(from) {
+				done = true
+				break
+			}
+			statuses, _, err := gc.Client.Repositories.ListDeploymentStatuses(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, d.GetID(), &github.ListOptions{PerPage: 100})
+			if err != nil {
+				log.Errorf("error[%v] in fetching statuses of deployment %v for repository %v", err, d.GetID(), gc.RepositoryName)
+				return nil, err
+			}
+			allDeployments = append(allDeployments, &Deployment{Deployment: d, Statuses: statuses})
+		}
+		if done || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allDeploymentsByte, err := json.Marshal(allDeployments)
+	return allDeploymentsByte, err
+}
+
+// GetReleases fetches releases created after from
+func (gc *GithubClient) GetReleases(from time.Time) ([]byte, error) {
+	log.Debugf("releases to be fetched after %v for repository %v", from, gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var allReleases []*github.RepositoryRelease
+	for {
+		releases, resp, err := gc.Client.Repositories.ListReleases(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching releases for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		done := false
+		// releases are listed with latest first
+		for _, r := range releases {
+			if r.GetCreatedAt().Before(from) {
+				done = true
+				break
+			}
+			allReleases = append(allReleases, r)
+		}
+		if done || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allReleasesByte, err := json.Marshal(allReleases)
+	return allReleasesByte, err
+}
+
+// GetWorkflowRuns fetches completed workflow runs of github actions created after from
+func (gc *GithubClient) GetWorkflowRuns(from time.Time) ([]byte, error) {
+	log.Debugf("workflow runs to be fetched after %v for repository %v", from, gc.RepositoryName)
+	opt := &github.ListWorkflowRunsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		Status:      "completed",
+		Created:     ">=" + from.UTC().Format(time.RFC3339),
+	}
+	var allRuns []*github.WorkflowRun
+	for {
+		runs, resp, err := gc.Client.Actions.ListRepositoryWorkflowRuns(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching workflow runs for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		allRuns = append(allRuns, runs.WorkflowRuns...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allRunsByte, err := json.Marshal(allRuns)
+	return allRunsByte, err
+}
+
+// GetIssueCount fetches the number of issues in a state using search , pull requests are not counted
+func (gc *GithubClient) GetIssueCount(state string) (int, error) {
+	log.Debugf("%v issues to be counted for repository %v", state, gc.RepositoryName)
+	query := fmt.Sprintf("repo:%s/%s is:issue state:%s", gc.RepositoryOwner, gc.RepositoryName, state)
+	// only the total count is needed , so a single issue is requested
+	result, _, err := gc.Client.Search.Issues(gc.ctx, query, &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 1}})
+	if err != nil {
+		log.Errorf("error[%v] in counting %v issues for repository %v", err, state, gc.RepositoryName)
+		return 0, err
+	}
+	return result.GetTotal(), nil
+}
+
+// GetClosedPullRequests fetches pull requests closed or merged after from
+func (gc *GithubClient) GetClosedPullRequests(from time.Time) ([]byte, error) {
+	log.Debugf("closed pull requests to be fetched after %v for repository %v", from, gc.RepositoryName)
+	opt := &github.PullRequestListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		State:       "closed",
+		Sort:        "updated",
+		Direction:   "desc",
+	}
+	var allPullRequests []*github.PullRequest
+	for {
+		pullRequests, resp, err := gc.Client.PullRequests.List(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching closed pull requests for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		done := false
+		for _, pr := range pullRequests {
+			// closing a pull request updates it , so nothing older than from can be closed after from
+			if pr.GetUpdatedAt().Before(from) {
+				done = true
+				break
+			}
+			if !pr.GetClosedAt().Before(from) {
+				allPullRequests = append(allPullRequests, pr)
+			}
+		}
+		if done || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allPullRequestsByte, err := json.Marshal(allPullRequests)
+	return allPullRequestsByte, err
+}
+
+// GetPullRequestCommits fetches commits of a pull request
+func (gc *GithubClient) GetPullRequestCommits(number int) ([]byte, error) {
+	log.Debugf("commits to be fetched for pull_request no. %v repository %v", number, gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var allCommits []*github.RepositoryCommit
+	for {
+		commits, resp, err := gc.Client.PullRequests.ListCommits(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, number, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching commits of pull request %v for repository %v", err, number, gc.RepositoryName)
+			return nil, err
+		}
+		allCommits = append(allCommits, commits...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allCommitsByte, err := json.Marshal(allCommits)
+	return allCommitsByte, err
+}
+
+// GetIssuesByLabels fetches issues updated after from having any of the labels
+func (gc *GithubClient) GetIssuesByLabels(labels []string, from time.Time) ([]byte, error) {
+	log.Debugf("issues with labels %v to be fetched after %v for repository %v", labels, from, gc.RepositoryName)
+	var allIssues []*github.Issue
+	seen := make(map[int64]bool)
+	// github matches issues having all of the labels , so each label is fetched separately
+	for _, label := range labels {
+		opt := &github.IssueListByRepoOptions{
+			ListOptions: github.ListOptions{PerPage: 100},
+			State:       "all",
+			Since:       from,
+			Labels:      []string{label},
+		}
+		for {
+			issues, resp, err := gc.Client.Issues.ListByRepo(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+			if err != nil {
+				log.Errorf("error[%v] in fetching issues with label %v for repository %v", err, label, gc.RepositoryName)
+				return nil, err
+			}
+			for _, i := range issues {
+				if !seen[i.GetID()] {
+					seen[i.GetID()] = true
+					allIssues = append(allIssues, i)
+				}
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opt.Page = resp.NextPage
+		}
+	}
+	allIssuesByte, err := json.Marshal(allIssues)
+	return allIssuesByte, err
+}
+
+// GetPullRequestReviews fetches reviews of a pull request
+func (gc *GithubClient) GetPullRequestReviews(number int) ([]byte, error) {
+	log.Debugf("reviews to be fetched for pull_request no. %v repository %v", number, gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var allReviews []*github.PullRequestReview
+	for {
+		reviews, resp, err := gc.Client.PullRequests.ListReviews(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, number, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching reviews of pull request %v for repository %v", err, number, gc.RepositoryName)
+			return nil, err
+		}
+		allReviews = append(allReviews, reviews...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allReviewsByte, err := json.Marshal(allReviews)
+	return allReviewsByte, err
+}
+
+// GetPullRequestComments fetches review comments of a pull request
+func (gc *GithubClient) GetPullRequestComments(number int) ([]byte, error) {
+	log.Debugf("review comments to be fetched for pull_request no. %v repository %v", number, gc.RepositoryName)
+	opt := &github.PullRequestListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var allComments []*github.PullRequestComment
+	for {
+		comments, resp, err := gc.Client.PullRequests.ListComments(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, number, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching review comments of pull request %v for repository %v", err, number, gc.RepositoryName)
+			return nil, err
+		}
+		allComments = append(allComments, comments...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allCommentsByte, err := json.Marshal(allComments)
+	return allCommentsByte, err
+}
+
+// GetIssueComments fetches comments of an issue or pull request
+func (gc *GithubClient) GetIssueComments(number int) ([]byte, error) {
+	log.Debugf("comments to be fetched for issue no. %v repository %v", number, gc.RepositoryName)
+	opt := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var allComments []*github.IssueComment
+	for {
+		comments, resp, err := gc.Client.Issues.ListComments(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, number, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching comments of issue %v for repository %v", err, number, gc.RepositoryName)
+			return nil, err
+		}
+		allComments = append(allComments, comments...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allCommentsByte, err := json.Marshal(allComments)
+	return allCommentsByte, err
+}
+
+// GetIssueTimeline fetches timeline events of an issue or pull request , like comments and changes of draft state
+func (gc *GithubClient) GetIssueTimeline(number int) ([]byte, error) {
+	log.Debugf("timeline to be fetched for issue no. %v repository %v", number, gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var allEvents []*github.Timeline
+	for {
+		events, resp, err := gc.Client.Issues.ListIssueTimeline(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, number, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching timeline of issue %v for repository %v", err, number, gc.RepositoryName)
+			return nil, err
+		}
+		allEvents = append(allEvents, events...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allEventsByte, err := json.Marshal(allEvents)
+	return allEventsByte, err
+}
+
+// GetOpenIssues fetches all open issues
+func (gc *GithubClient) GetOpenIssues() ([]byte, error) {
+	log.Debugf("open issues to be fetched for repository %v", gc.RepositoryName)
+	opt := &github.IssueListByRepoOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		State:       "open",
+	}
+	var allIssues []*github.Issue
+	for {
+		issues, resp, err := gc.Client.Issues.ListByRepo(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching open issues for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		allIssues = append(allIssues, issues...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allIssuesByte, err := json.Marshal(allIssues)
+	return allIssuesByte, err
+}
+
+// codeOwnersPaths are the locations of CODEOWNERS file in order of precedence
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// GetCodeOwners fetches the content of CODEOWNERS file from default branch , nil if repository has no such file
+func (gc *GithubClient) GetCodeOwners() ([]byte, error) {
+	log.Debugf("CODEOWNERS to be fetched for repository %v", gc.RepositoryName)
+	for _, path := range codeOwnersPaths {
+		file, _, resp, err := gc.Client.Repositories.GetContents(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, path, nil)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			log.Errorf("error[%v] in fetching %v for repository %v", err, path, gc.RepositoryName)
+			return nil, err
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			log.Errorf("error[%v] in decoding %v for repository %v", err, path, gc.RepositoryName)
+			return nil, err
+		}
+		return []byte(content), nil
+	}
+	return nil, nil
+}
+
+// GetCommitFiles fetches files changed in a commit
+func (gc *GithubClient) GetCommitFiles(sha string) ([]byte, error) {
+	log.Debugf("files to be fetched for commit %v repository %v", sha, gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var allFiles []*github.CommitFile
+	for {
+		commit, resp, err := gc.Client.Repositories.GetCommit(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, sha, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching files of commit %v for repository %v", err, sha, gc.RepositoryName)
+			return nil, err
+		}
+		allFiles = append(allFiles, commit.Files...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allFilesByte, err := json.Marshal(allFiles)
+	return allFilesByte, err
+}
+
+// GetPullRequestFiles fetches files changed in a pull request
+func (gc *GithubClient) GetPullRequestFiles(number int) ([]byte, error) {
+	log.Debugf("files to be fetched for pull_request no. %v repository %v", number, gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var allFiles []*github.CommitFile
+	for {
+		files, resp, err := gc.Client.PullRequests.ListFiles(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, number, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching files of pull request %v for repository %v", err, number, gc.RepositoryName)
+			return nil, err
+		}
+		allFiles = append(allFiles, files...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allFilesByte, err := json.Marshal(allFiles)
+	return allFilesByte, err
+}
+
+// Team represents a github team along with its members
+type Team struct {
+	*github.Team
+
+	// Members of the team
+	Members []*github.User `json:"members"`
+}
+
+// GetTeams fetches teams having access to the repository along with their members ,
+// only repositories owned by an organization have teams
+func (gc *GithubClient) GetTeams() ([]byte, error) {
+	log.Debugf("teams to be fetched for repository %v", gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var allTeams []*Team
+	for {
+		teams, resp, err := gc.Client.Repositories.ListTeams(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching teams for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		for _, t := range teams {
+			team := &Team{Team: t}
+			memberOpt := &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: 100}}
+			for {
+				members, memberResp, err := gc.Client.Teams.ListTeamMembersBySlug(gc.ctx, gc.RepositoryOwner, t.GetSlug(), memberOpt)
+				if err != nil {
+					log.Errorf("error[%v] in fetching members of team %v for repository %v", err, t.GetSlug(), gc.RepositoryName)
+					return nil, err
+				}
+				team.Members = append(team.Members, members...)
+				if memberResp.NextPage == 0 {
+					break
+				}
+				memberOpt.Page = memberResp.NextPage
+			}
+			allTeams = append(allTeams, team)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allTeamsByte, err := json.Marshal(allTeams)
+	return allTeamsByte, err
+}
+
+// CommitChecks represents check runs and statuses reported for a commit
+type CommitChecks struct {
+	// CheckRuns reported by github apps
+	CheckRuns []*github.CheckRun `json:"check_runs"`
+
+	// Statuses are the latest status of each context
+	Statuses []*github.RepoStatus `json:"statuses"`
+}
+
+// GetCommitChecks fetches check runs and statuses reported for a commit
+func (gc *GithubClient) GetCommitChecks(ref string) ([]byte, error) {
+	log.Debugf("checks to be fetched for ref %v repository %v", ref, gc.RepositoryName)
+	var checks CommitChecks
+	opt := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		result, resp, err := gc.Client.Checks.ListCheckRunsForRef(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, ref, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching check runs of ref %v for repository %v", err, ref, gc.RepositoryName)
+			return nil, err
+		}
+		checks.CheckRuns = append(checks.CheckRuns, result.CheckRuns...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	statusOpt := &github.ListOptions{PerPage: 100}
+	for {
+		status, resp, err := gc.Client.Repositories.GetCombinedStatus(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, ref, statusOpt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching statuses of ref %v for repository %v", err, ref, gc.RepositoryName)
+			return nil, err
+		}
+		checks.Statuses = append(checks.Statuses, status.Statuses...)
+		if resp.NextPage == 0 {
+			break
+		}
+		statusOpt.Page = resp.NextPage
+	}
+	checksByte, err := json.Marshal(checks)
+	return checksByte, err
+}
+
+// GetCommitPullRequests fetches pull requests associated with a commit
+func (gc *GithubClient) GetCommitPullRequests(sha string) ([]byte, error) {
+	log.Debugf("pull requests to be fetched for commit %v repository %v", sha, gc.RepositoryName)
+	opt := &github.PullRequestListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	var allPullRequests []*github.PullRequest
+	for {
+		pullRequests, resp, err := gc.Client.PullRequests.ListPullRequestsWithCommit(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, sha, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching pull requests of commit %v for repository %v", err, sha, gc.RepositoryName)
+			return nil, err
+		}
+		allPullRequests = append(allPullRequests, pullRequests...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allPullRequestsByte, err := json.Marshal(allPullRequests)
+	return allPullRequestsByte, err
+}
+
+// GetBranchHead fetches the sha of the head commit of a branch
+func (gc *GithubClient) GetBranchHead(branch string) (string, error) {
+	log.Debugf("head to be fetched for branch %v repository %v", branch, gc.RepositoryName)
+	br, _, err := gc.Client.Repositories.GetBranch(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, branch, true)
+	if err != nil {
+		log.Errorf("error[%v] in fetching branch %v for repository %v", err, branch, gc.RepositoryName)
+		return "", err
+	}
+	return br.GetCommit().GetSHA(), nil
+}
+
+// IsAncestor checks if ancestor commit is reachable from descendant commit
+func (gc *GithubClient) IsAncestor(ancestor string, descendant string) (bool, error) {
+	// only the status is needed , so a single commit is requested
+	comparison, resp, err := gc.Client.Repositories.CompareCommits(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, ancestor, descendant, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		// ancestor is no more present in repository once rewritten history is garbage collected
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		log.Errorf("error[%v] in comparing commits %v and %v for repository %v", err, ancestor, descendant, gc.RepositoryName)
+		return false, err
+	}
+	// descendant is ahead of or identical to ancestor if no commit of ancestor is missing
+	return comparison.GetBehindBy() == 0, nil
+}
+
+// GetComparedCommits fetches commits reachable from head but not from base , nil if either commit is not present
+func (gc *GithubClient) GetComparedCommits(base string, head string) ([]byte, error) {
+	log.Debugf("commits to be compared between %v and %v for repository %v", base, head, gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var allCommits []*github.RepositoryCommit
+	for {
+		comparison, resp, err := gc.Client.Repositories.CompareCommits(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, base, head, opt)
+		if err != nil {
+			// commits of rewritten history are no more present once garbage collected
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, nil
+			}
+			log.Errorf("error[%v] in comparing commits %v and %v for repository %v", err, base, head, gc.RepositoryName)
+			return nil, err
+		}
+		allCommits = append(allCommits, comparison.Commits...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allCommitsByte, err := json.Marshal(allCommits)
+	return allCommitsByte, err
+}
+
+// GetPushEvents fetches recent push events of the repository , github keeps at most 300 events of last 90 days
+func (gc *GithubClient) GetPushEvents() ([]byte, error) {
+	log.Debugf("push events to be fetched for repository %v", gc.RepositoryName)
+	opt := &github.ListOptions{PerPage: 100}
+	var pushEvents []*github.Event
+	for {
+		events, resp, err := gc.Client.Activity.ListRepositoryEvents(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching events for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		for _, e := range events {
+			if e.GetType() == "PushEvent" {
+				pushEvents = append(pushEvents, e)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	pushEventsByte, err := json.Marshal(pushEvents)
+	return pushEventsByte, err
+}
+
+// GetOpenPullRequests fetches all open pull requests
+func (gc *GithubClient) GetOpenPullRequests() ([]byte, error) {
+	log.Debugf("open pull requests to be fetched for repository %v", gc.RepositoryName)
+	opt := &github.PullRequestListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		State:       "open",
+	}
+	var allPullRequests []*github.PullRequest
+	for {
+		pullRequests, resp, err := gc.Client.PullRequests.List(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching open pull requests for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		allPullRequests = append(allPullRequests, pullRequests...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allPullRequestsByte, err := json.Marshal(allPullRequests)
+	return allPullRequestsByte, err
+}
+
+// BranchDetail represents a branch along with its head commit and comparison with default branch
+type BranchDetail struct {
+	// Branch as listed
+	*github.Branch
+
+	// DefaultBranch is the name of default branch of the repository
+	DefaultBranch string `json:"default_branch"`
+
+	// HeadCommit is the head commit of branch
+	HeadCommit *github.RepositoryCommit `json:"head_commit"`
+
+	// AheadBy is the number of commits of branch missing in default branch
+	AheadBy int `json:"ahead_by"`
+
+	// BehindBy is the number of commits of default branch missing in branch
+	BehindBy int `json:"behind_by"`
+
+	// MergedPullRequest is a merged pull request raised from branch at its head commit , nil if there is none
+	MergedPullRequest *github.PullRequest `json:"merged_pull_request"`
+}
+
+// GetBranches fetches branches other than default branch along with their head commit , comparison with default branch
+// and pull request merged from branch , needs three requests per branch
+func (gc *GithubClient) GetBranches() ([]byte, error) {
+	log.Debugf("branches to be fetched for repository %v", gc.RepositoryName)
+	repo, _, err := gc.Client.Repositories.Get(gc.ctx, gc.RepositoryOwner, gc.RepositoryName)
+	if err != nil {
+		log.Errorf("error[%v] in fetching repository %v", err, gc.RepositoryName)
+		return nil, err
+	}
+	defaultBranch := repo.GetDefaultBranch()
+	opt := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	var allBranches []BranchDetail
+	for {
+		branches, resp, err := gc.Client.Repositories.ListBranches(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, opt)
+		if err != nil {
+			log.Errorf("error[%v] in fetching branches for repository %v", err, gc.RepositoryName)
+			return nil, err
+		}
+		for _, b := range branches {
+			if b.GetName() == defaultBranch {
+				continue
+			}
+			branch := BranchDetail{Branch: b, DefaultBranch: defaultBranch}
+			commits, _, err := gc.Client.Repositories.ListCommits(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, &github.CommitsListOptions{SHA: b.GetCommit().GetSHA(), ListOptions: github.ListOptions{PerPage: 1}})
+			if err != nil {
+				log.Errorf("error[%v] in fetching head commit of branch %v for repository %v", err, b.GetName(), gc.RepositoryName)
+				return nil, err
+			}
+			if len(commits) > 0 {
+				branch.HeadCommit = commits[0]
+			}
+			// only the counts are needed , so a single commit is requested
+			comparison, _, err := gc.Client.Repositories.CompareCommits(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, defaultBranch, b.GetCommit().GetSHA(), &github.ListOptions{PerPage: 1})
+			if err != nil {
+				log.Errorf("error[%v] in comparing branch %v with %v for repository %v", err, b.GetName(), defaultBranch, gc.RepositoryName)
+				return nil, err
+			}
+			branch.AheadBy = comparison.GetAheadBy()
+			branch.BehindBy = comparison.GetBehindBy()
+			// branches squashed or rebased into default branch stay ahead of it , so merged pull requests are checked too
+			prOpt := &github.PullRequestListOptions{State: "closed", Head: gc.RepositoryOwner + ":" + b.GetName(), ListOptions: github.ListOptions{PerPage: 100}}
+			pullRequests, _, err := gc.Client.PullRequests.List(gc.ctx, gc.RepositoryOwner, gc.RepositoryName, prOpt)
+			if err != nil {
+				log.Errorf("error[%v] in fetching pull requests of branch %v for repository %v", err, b.GetName(), gc.RepositoryName)
+				return nil, err
+			}
+			for _, pr := range pullRequests {
+				// commits pushed after the merge are not merged
+				if !pr.GetMergedAt().IsZero() && pr.GetHead().GetSHA() == b.GetCommit().GetSHA() {
+					branch.MergedPullRequest = pr
+					break
+				}
+			}
+			allBranches = append(allBranches, branch)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	allBranchesByte, err := json.Marshal(allBranches)
+	return allBranchesByte, err
+}
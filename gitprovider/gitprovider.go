@@ -2,6 +2,7 @@
 package gitprovider
 
 import (
+	"errors"
 	"time"
 
 	"github.com/maplelabs/github-audit/logger"
@@ -9,6 +10,11 @@ import (
 
 var (
 	log logger.Logger
+	// ErrStatsNotReady is returned when statistics are still being computed by the git provider.
+	ErrStatsNotReady = errors.New("repository statistics are not ready yet")
+	// ErrStatsUnavailable is returned when the git provider does not compute statistics for the repository ,
+	// github does not compute code frequency for repositories with 10000 or more commits.
+	ErrStatsUnavailable = errors.New("repository statistics are not available")
 )
 
 func init() {
@@ -28,6 +34,88 @@ type GitProvider interface {
 
 	// GetIssues fetches issues using APIs
 	GetIssues(to time.Time) ([]byte, error)
+
+	// GetContributorStats fetches weekly additions , deletions and commits per contributor
+	GetContributorStats() ([]byte, error)
+
+	// GetCodeFrequency fetches weekly additions and deletions for the repository
+	GetCodeFrequency() ([]byte, error)
+
+	// GetCommitActivity fetches weekly commit counts for the repository
+	GetCommitActivity() ([]byte, error)
+
+	// GetDeployments fetches deployments to an environment created after from , along with their statuses
+	GetDeployments(environment string, from time.Time) ([]byte, error)
+
+	// GetReleases fetches releases created after from
+	GetReleases(from time.Time) ([]byte, error)
+
+	// GetWorkflowRuns fetches completed workflow runs created after from
+	GetWorkflowRuns(from time.Time) ([]byte, error)
+
+	// GetIssueCount fetches the number of issues , not pull requests , in a state
+	GetIssueCount(state string) (int, error)
+
+	// GetClosedPullRequests fetches pull requests closed or merged after from
+	GetClosedPullRequests(from time.Time) ([]byte, error)
+
+	// GetPullRequestCommits fetches commits of a pull request
+	GetPullRequestCommits(int) ([]byte, error)
+
+	// GetIssuesByLabels fetches issues updated after from having any of the labels
+	GetIssuesByLabels(labels []string, from time.Time) ([]byte, error)
+
+	// GetPullRequestReviews fetches reviews of a pull request
+	GetPullRequestReviews(int) ([]byte, error)
+
+	// GetPullRequestComments fetches review comments of a pull request
+	GetPullRequestComments(int) ([]byte, error)
+
+	// GetIssueComments fetches comments of an issue or pull request
+	GetIssueComments(int) ([]byte, error)
+
+	// GetIssueTimeline fetches timeline events of an issue or pull request
+	GetIssueTimeline(int) ([]byte, error)
+
+	// GetOpenIssues fetches all open issues
+	GetOpenIssues() ([]byte, error)
+
+	// GetCodeOwners fetches the content of CODEOWNERS file , nil if repository has no such file
+	GetCodeOwners() ([]byte, error)
+
+	// GetCommitFiles fetches files changed in a commit
+	GetCommitFiles(sha string) ([]byte, error)
+
+	// GetPullRequestFiles fetches files changed in a pull request
+	GetPullRequestFiles(int) ([]byte, error)
+
+	// GetTeams fetches teams having access to the repository along with their members
+	GetTeams() ([]byte, error)
+
+	// GetCommitChecks fetches check runs and statuses reported for a commit
+	GetCommitChecks(ref string) ([]byte, error)
+
+	// GetCommitPullRequests fetches pull requests associated with a commit
+	GetCommitPullRequests(sha string) ([]byte, error)
+
+	// GetBranchHead fetches the sha of the head commit of a branch
+	GetBranchHead(branch string) (string, error)
+
+	// IsAncestor checks if ancestor commit is reachable from descendant commit
+	IsAncestor(ancestor string, descendant string) (bool, error)
+
+	// GetComparedCommits fetches commits reachable from head but not from base , nil if either commit is not present
+	GetComparedCommits(base string, head string) ([]byte, error)
+
+	// GetPushEvents fetches recent push events of the repository
+	GetPushEvents() ([]byte, error)
+
+	// GetOpenPullRequests fetches all open pull requests
+	GetOpenPullRequests() ([]byte, error)
+
+	// GetBranches fetches branches other than default branch along with their head commit , comparison with default branch
+	// and pull request merged from branch
+	GetBranches() ([]byte, error)
 }
 
 // NewGitProvider returns a new git provider based on git cloud type
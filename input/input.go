@@ -6,9 +6,13 @@ package input
 import (
 	"errors"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/maplelabs/github-audit/logger"
+	"github.com/maplelabs/github-audit/utils"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,6 +20,76 @@ const (
 	DefaultMasterBranch = "master"
 	DefaultMainBranch   = "main"
 	PRIVATE             = "private"
+
+	// DORA deployment and failure sources
+	DoraSourceDeployments = "deployments"
+	DoraSourceReleases    = "releases"
+	DoraSourceIssues      = "issues"
+	DoraSourceAll         = "all"
+
+	// compliance rule ids
+	ComplianceMissingApproval      = "missing_approval"
+	ComplianceSelfApproval         = "self_approval"
+	ComplianceNonCodeOwnerApproval = "non_codeowner_approval"
+	ComplianceUnsignedCommit       = "unsigned_commit"
+	ComplianceForcePush            = "force_push"
+	ComplianceFailingChecks        = "failing_checks"
+	ComplianceDirectCommit         = "direct_commit"
+
+	// signature verification modes
+	SignatureModeGithub = "github"
+	SignatureModeLocal  = "local"
+)
+
+var (
+	// DefaultDoraWindows are the windows used for DORA metrics if none are configured.
+	DefaultDoraWindows = []string{"7d", "30d"}
+
+	// DefaultIncidentLabels are the labels used for incident issues if none are configured.
+	DefaultIncidentLabels = []string{"incident"}
+
+	// DefaultWorkingDays are the working days used if none are configured.
+	DefaultWorkingDays = []string{"monday", "tuesday", "wednesday", "thursday", "friday"}
+
+	// ComplianceRules are all compliance rules , checked if none are configured.
+	ComplianceRules = []string{
+		ComplianceMissingApproval,
+		ComplianceSelfApproval,
+		ComplianceNonCodeOwnerApproval,
+		ComplianceUnsignedCommit,
+		ComplianceForcePush,
+		ComplianceFailingChecks,
+		ComplianceDirectCommit,
+	}
+)
+
+const (
+	DefaultDoraEnvironment    = "production"
+	DefaultDoraReportInterval = "1h"
+
+	DefaultStalePullRequestAge  = "14d"
+	DefaultStaleBranchAge       = "30d"
+	DefaultStaleMergedBranchAge = "1d"
+	DefaultStaleReportInterval  = "1d"
+
+	DefaultHotspotWindow         = "30d"
+	DefaultHotspotReworkWindow   = "21d"
+	DefaultHotspotDirectoryDepth = 2
+	DefaultHotspotTop            = 100
+	DefaultHotspotReportInterval = "1d"
+
+	DefaultKnowledgeWindow         = "90d"
+	DefaultKnowledgeReportInterval = "7d"
+
+	DefaultAnomalyMinScore        = 0.9
+	DefaultAnomalyMinSamples      = 10
+	DefaultAnomalyBaselineSamples = 100
+
+	DefaultItemCountsReportInterval = "5m"
+
+	DefaultWorkingHoursTimezone = "UTC"
+	DefaultWorkingHoursStart    = "09:00"
+	DefaultWorkingHoursEnd      = "18:00"
 )
 
 var (
@@ -32,6 +106,23 @@ var (
 	ErrMissingTargetName      = errors.New("missing target name")
 	ErrMissingTargetType      = errors.New("missing target type")
 	ErrPollingIntervalFormat  = errors.New("polling interval format is incorrect")
+	ErrDoraDeploymentSource   = errors.New("dora deployment source must be deployments or releases")
+	ErrDoraFailureSource      = errors.New("dora failure source must be issues , deployments or all")
+	ErrDoraWindowFormat       = errors.New("dora window or report interval format is incorrect or not positive")
+	ErrMissingSLARuleName     = errors.New("missing sla rule name")
+	ErrMissingSLARuleLimit    = errors.New("sla rule needs first_response or close time limit")
+	ErrSLARuleLimitFormat     = errors.New("sla rule time limit format is incorrect or not positive")
+	ErrTicketPatternFormat    = errors.New("ticket pattern is not a valid regular expression")
+	ErrBotPatternFormat       = errors.New("bot pattern is not a valid regular expression")
+	ErrUnknownComplianceRule  = errors.New("unknown compliance rule")
+	ErrSignatureMode          = errors.New("signature verification mode must be github or local")
+	ErrMissingSignatureKeys   = errors.New("local signature verification needs gpg_keyring or ssh_allowed_signers")
+	ErrStaleAgeFormat         = errors.New("stale age or report interval format is incorrect or not positive")
+	ErrHotspotWindowFormat    = errors.New("hotspot window or report interval format is incorrect or not positive")
+	ErrKnowledgeWindowFormat  = errors.New("knowledge window or report interval format is incorrect or not positive")
+	ErrAnomalyMinScore        = errors.New("anomaly min score must be between 0 and 1")
+	ErrItemCountsInterval     = errors.New("item counts report interval format is incorrect or not positive")
+	ErrWorkingHoursFormat     = errors.New("working hours timezone , start , end or days are incorrect")
 )
 
 var (
@@ -85,6 +176,51 @@ type AuditJob struct {
 
 	// RepositoryConfig defines repository config.
 	RepositoryConfig `yaml:"repo_config" json:"repo_config"`
+
+	// RepositoryStats defines the collection of weekly contributor , code frequency and commit activity stats.
+	RepositoryStats RepositoryStatsConfig `yaml:"repo_stats,omitempty" json:"repo_stats,omitempty"`
+
+	// WorkflowRuns defines the collection of completed workflow runs of github actions.
+	WorkflowRuns WorkflowRunsConfig `yaml:"workflow_runs,omitempty" json:"workflow_runs,omitempty"`
+
+	// ItemCounts defines the periodic counts of open pull requests and of issues by state.
+	ItemCounts ItemCountsConfig `yaml:"item_counts,omitempty" json:"item_counts,omitempty"`
+
+	// Dora defines the DORA metrics computation for the audit job.
+	Dora DoraConfig `yaml:"dora,omitempty" json:"dora,omitempty"`
+
+	// SLARules defines response and resolution SLAs for issues.
+	SLARules []SLARule `yaml:"sla_rules,omitempty" json:"sla_rules,omitempty"`
+
+	// TicketPatterns are regular expressions matching ticket keys in commit messages and pull requests.
+	TicketPatterns []string `yaml:"ticket_patterns,omitempty" json:"ticket_patterns,omitempty"`
+
+	// Identity defines how contributor aliases are merged and bots are detected.
+	Identity IdentityConfig `yaml:"identity,omitempty" json:"identity,omitempty"`
+
+	// Ownership defines how documents are enriched with owning teams of changed files and teams of authors.
+	Ownership OwnershipConfig `yaml:"ownership,omitempty" json:"ownership,omitempty"`
+
+	// Compliance defines the policy checks of merged pull requests and direct commits to protected branches.
+	Compliance ComplianceConfig `yaml:"compliance,omitempty" json:"compliance,omitempty"`
+
+	// SignatureVerification defines how signatures of commits are verified.
+	SignatureVerification SignatureConfig `yaml:"signature_verification,omitempty" json:"signature_verification,omitempty"`
+
+	// Stale defines the thresholds of stale pull request and stale branch reports.
+	Stale StaleConfig `yaml:"stale,omitempty" json:"stale,omitempty"`
+
+	// Hotspots defines the windows of file hotspot and code churn analytics.
+	Hotspots HotspotConfig `yaml:"hotspots,omitempty" json:"hotspots,omitempty"`
+
+	// Knowledge defines the window of knowledge distribution and bus factor metrics.
+	Knowledge KnowledgeConfig `yaml:"knowledge,omitempty" json:"knowledge,omitempty"`
+
+	// Anomalies defines the sensitivity of anomaly detection on repository activity.
+	Anomalies AnomalyConfig `yaml:"anomalies,omitempty" json:"anomalies,omitempty"`
+
+	// WorkPatterns defines the working hours of teams for weekly work pattern reports.
+	WorkPatterns WorkPatternConfig `yaml:"work_patterns,omitempty" json:"work_patterns,omitempty"`
 }
 
 // RepositoryConfig represents repostory configurations.
@@ -111,6 +247,221 @@ type RepositoryCredentials struct {
 	AccessToken string `yaml:"access_token" json:"access_token"`
 }
 
+// RepositoryStatsConfig represents the collection of weekly repository stats.
+type RepositoryStatsConfig struct {
+	// Enabled turns on weekly contributor , code frequency and commit activity stats.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+}
+
+// WorkflowRunsConfig represents the collection of completed workflow runs.
+type WorkflowRunsConfig struct {
+	// Enabled turns on workflow run documents.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+}
+
+// ItemCountsConfig represents the periodic counts of pull requests and issues.
+type ItemCountsConfig struct {
+	// Enabled turns on item count documents.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// ReportInterval is the interval between two counts , needs two search requests and one request per 100 open pull requests.
+	// Format: 5m , 1h , Default: 5m
+	ReportInterval string `yaml:"report_interval,omitempty" json:"report_interval,omitempty"`
+}
+
+// DoraConfig represents the data sources and label conventions used to compute DORA metrics.
+type DoraConfig struct {
+	// Enabled turns on DORA metrics computation.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// DeploymentSource is where deployments are read from , possible values (deployments , releases).
+	DeploymentSource string `yaml:"deployment_source,omitempty" json:"deployment_source,omitempty"`
+
+	// Environment is the deployment environment considered as production.
+	Environment string `yaml:"environment,omitempty" json:"environment,omitempty"`
+
+	// FailureSource is what marks a change as failed , possible values (issues , deployments , all).
+	FailureSource string `yaml:"failure_source,omitempty" json:"failure_source,omitempty"`
+
+	// IncidentLabels are issue labels which mark an issue as production incident.
+	IncidentLabels []string `yaml:"incident_labels,omitempty" json:"incident_labels,omitempty"`
+
+	// Windows are the time windows for which metrics are computed. Format: 1d , 7d , 720h
+	Windows []string `yaml:"windows,omitempty" json:"windows,omitempty"`
+
+	// ReportInterval is the interval between two computations of the metrics. Format: 30m , 1h , 1d
+	ReportInterval string `yaml:"report_interval,omitempty" json:"report_interval,omitempty"`
+}
+
+// SLARule represents the time limits within which matching issues must get a first response and be closed.
+type SLARule struct {
+	// Name of the rule.
+	Name string `yaml:"name" json:"name"`
+
+	// Labels an issue must have any of to match the rule , all issues match if empty.
+	Labels []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// FirstResponse is the time limit for the first comment by someone other than the author. Format: 30m , 4h , 1d
+	FirstResponse string `yaml:"first_response,omitempty" json:"first_response,omitempty"`
+
+	// Close is the time limit for closing the issue. Format: 30m , 4h , 3d
+	Close string `yaml:"close,omitempty" json:"close,omitempty"`
+
+	// BusinessHours are the working hours in which time limits are counted , team is not used.
+	// Default: time limits are counted all the time.
+	BusinessHours *WorkingHours `yaml:"business_hours,omitempty" json:"business_hours,omitempty"`
+}
+
+// IdentityConfig represents the files and patterns used to resolve contributor identities.
+type IdentityConfig struct {
+	// Mailmap is the path of a git mailmap style file merging names and emails to a canonical identity.
+	Mailmap string `yaml:"mailmap,omitempty" json:"mailmap,omitempty"`
+
+	// Teams is the path of a yaml file with team name as key and list of emails , names or logins as value.
+	Teams string `yaml:"teams,omitempty" json:"teams,omitempty"`
+
+	// Bots are logins , names or emails of bot accounts in addition to the known bots.
+	Bots []string `yaml:"bots,omitempty" json:"bots,omitempty"`
+
+	// BotPatterns are regular expressions matching logins , names or emails of bot accounts.
+	BotPatterns []string `yaml:"bot_patterns,omitempty" json:"bot_patterns,omitempty"`
+}
+
+// OwnershipConfig represents the sources of owning teams of changed files and teams of authors.
+type OwnershipConfig struct {
+	// CodeOwners maps changed files to owning teams using CODEOWNERS file of the repository.
+	// Files changed in a commit need one request per commit.
+	CodeOwners bool `yaml:"codeowners,omitempty" json:"codeowners,omitempty"`
+
+	// GithubTeams maps users to teams having access to the repository when they are not in the identity teams file.
+	GithubTeams bool `yaml:"github_teams,omitempty" json:"github_teams,omitempty"`
+}
+
+// ComplianceConfig represents the policy checks of changes to protected branches.
+type ComplianceConfig struct {
+	// Enabled turns on compliance checks.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// ProtectedBranches are the branches whose changes are checked , Default: branches of the audit job.
+	ProtectedBranches []string `yaml:"protected_branches,omitempty" json:"protected_branches,omitempty"`
+
+	// Rules are the ids of rules to check , Default: all rules.
+	Rules []string `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// SignatureConfig represents how signatures of commits are verified.
+type SignatureConfig struct {
+	// Mode is github to use the verification of the git provider or local to verify signatures against
+	// the configured keys , Default: github.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+
+	// GPGKeyring is the path of an armored file with public gpg keys of signers.
+	GPGKeyring string `yaml:"gpg_keyring,omitempty" json:"gpg_keyring,omitempty"`
+
+	// SSHAllowedSigners is the path of a file with public ssh keys of signers in git allowed signers format.
+	SSHAllowedSigners string `yaml:"ssh_allowed_signers,omitempty" json:"ssh_allowed_signers,omitempty"`
+}
+
+// StaleConfig represents the thresholds after which open pull requests and branches are reported as stale.
+type StaleConfig struct {
+	// Enabled turns on stale reports.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// PullRequestAge is the time without activity after which an open pull request is stale. Format: 7d , 12h , Default: 14d
+	PullRequestAge string `yaml:"pull_request_age,omitempty" json:"pull_request_age,omitempty"`
+
+	// BranchAge is the time without commits after which a branch not merged to default branch is stale , Default: 30d
+	BranchAge string `yaml:"branch_age,omitempty" json:"branch_age,omitempty"`
+
+	// MergedBranchAge is the time without commits after which a branch merged to default branch but not deleted is stale , Default: 1d
+	MergedBranchAge string `yaml:"merged_branch_age,omitempty" json:"merged_branch_age,omitempty"`
+
+	// ExcludeBranches are branches never reported in addition to default branch and branches of the audit job.
+	ExcludeBranches []string `yaml:"exclude_branches,omitempty" json:"exclude_branches,omitempty"`
+
+	// ReportInterval is the interval between two reports. Format: 12h , 1d , Default: 1d
+	ReportInterval string `yaml:"report_interval,omitempty" json:"report_interval,omitempty"`
+}
+
+// HotspotConfig represents the windows and limits of file hotspot and code churn analytics.
+type HotspotConfig struct {
+	// Enabled turns on hotspot analytics.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Window is the period of commits analysed in each report. Format: 7d , 30d , Default: 30d
+	Window string `yaml:"window,omitempty" json:"window,omitempty"`
+
+	// ReworkWindow is the time within which lines changed again are counted as rework , Default: 21d
+	ReworkWindow string `yaml:"rework_window,omitempty" json:"rework_window,omitempty"`
+
+	// DirectoryDepth is the number of leading path elements of directories churn is aggregated for , Default: 2
+	DirectoryDepth int `yaml:"directory_depth,omitempty" json:"directory_depth,omitempty"`
+
+	// Top is the number of files and of directories with most changes published in each report , Default: 100
+	Top int `yaml:"top,omitempty" json:"top,omitempty"`
+
+	// ReportInterval is the interval between two reports , needs one request per commit in window. Format: 12h , 1d , Default: 1d
+	ReportInterval string `yaml:"report_interval,omitempty" json:"report_interval,omitempty"`
+}
+
+// KnowledgeConfig represents the window of knowledge distribution and bus factor metrics.
+type KnowledgeConfig struct {
+	// Enabled turns on knowledge distribution metrics.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Window is the period of commits analysed in each report. Format: 30d , 90d , Default: 90d
+	Window string `yaml:"window,omitempty" json:"window,omitempty"`
+
+	// ReportInterval is the interval between two reports , needs one request per commit in window. Format: 1d , 7d , Default: 7d
+	ReportInterval string `yaml:"report_interval,omitempty" json:"report_interval,omitempty"`
+}
+
+// AnomalyConfig represents the sensitivity of anomaly detection on repository activity.
+type AnomalyConfig struct {
+	// Enabled turns on anomaly detection.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MinScore is the least score of a published anomaly , between 0 and 1 , Default: 0.9
+	MinScore float64 `yaml:"min_score,omitempty" json:"min_score,omitempty"`
+
+	// MinSamples is the number of observations in a baseline before anomalies are detected against it , Default: 10
+	MinSamples int `yaml:"min_samples,omitempty" json:"min_samples,omitempty"`
+
+	// BaselineSamples is the number of recent observations a baseline mostly reflects , older ones fade out , Default: 100
+	BaselineSamples int `yaml:"baseline_samples,omitempty" json:"baseline_samples,omitempty"`
+
+	// ProtectedBranches are the branches whose first time pushers are detected , Default: branches of the audit job.
+	ProtectedBranches []string `yaml:"protected_branches,omitempty" json:"protected_branches,omitempty"`
+}
+
+// WorkPatternConfig represents the working hours of teams for weekly work pattern reports.
+type WorkPatternConfig struct {
+	// Enabled turns on work pattern reports.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// WorkingHours are the working hours of teams , the ones without team apply to authors not in any listed team.
+	// Default: 09:00 to 18:00 on monday to friday in UTC for authors not in any listed team.
+	WorkingHours []WorkingHours `yaml:"working_hours,omitempty" json:"working_hours,omitempty"`
+}
+
+// WorkingHours represents the working hours of a team.
+type WorkingHours struct {
+	// Team is the team name as in identity teams file , empty for authors not in any listed team.
+	Team string `yaml:"team,omitempty" json:"team,omitempty"`
+
+	// Timezone is the IANA timezone of team , for ex. Asia/Kolkata , used when the timezone of an activity is not known , Default: UTC
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+
+	// Start is the start of working hours. Format: 09:00 , Default: 09:00
+	Start string `yaml:"start,omitempty" json:"start,omitempty"`
+
+	// End is the end of working hours. Format: 18:00 , Default: 18:00
+	End string `yaml:"end,omitempty" json:"end,omitempty"`
+
+	// Days are the working days , for ex. monday , Default: monday to friday
+	Days []string `yaml:"days,omitempty" json:"days,omitempty"`
+}
+
 // Output represents the target where data will be sent.
 type Output struct {
 	//TargetName consists of the target names to which auditjob data needs to be sent.
@@ -171,6 +522,60 @@ func (c *Config) validate() error {
 		if len(j.TargetName) == 0 {
 			return ErrMissingTargetName
 		}
+		// checking dora metrics configuration.
+		if err := j.Dora.validate(); err != nil {
+			return err
+		}
+		// checking sla rules.
+		for _, r := range j.SLARules {
+			if err := r.validate(); err != nil {
+				return err
+			}
+		}
+		// checking ticket patterns.
+		for _, p := range j.TicketPatterns {
+			if _, err := regexp.Compile(p); err != nil {
+				return ErrTicketPatternFormat
+			}
+		}
+		// checking compliance rules.
+		if err := j.Compliance.validate(); err != nil {
+			return err
+		}
+		// checking signature verification.
+		if err := j.SignatureVerification.validate(); err != nil {
+			return err
+		}
+		// checking item counts interval.
+		if err := j.ItemCounts.validate(); err != nil {
+			return err
+		}
+		// checking stale thresholds.
+		if err := j.Stale.validate(); err != nil {
+			return err
+		}
+		// checking hotspot windows.
+		if err := j.Hotspots.validate(); err != nil {
+			return err
+		}
+		// checking knowledge window.
+		if err := j.Knowledge.validate(); err != nil {
+			return err
+		}
+		// checking anomaly sensitivity.
+		if err := j.Anomalies.validate(); err != nil {
+			return err
+		}
+		// checking working hours.
+		if err := j.WorkPatterns.validate(); err != nil {
+			return err
+		}
+		// checking bot patterns.
+		for _, p := range j.Identity.BotPatterns {
+			if _, err := regexp.Compile(p); err != nil {
+				return ErrBotPatternFormat
+			}
+		}
 		// checking if polling interval is not empty.
 		if j.PollingInterval == "" {
 			return ErrMissingPollingInterval
@@ -192,6 +597,329 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// validate checks the sources and that windows of dora config are positive durations if it is enabled.
+func (d *DoraConfig) validate() error {
+	if !d.Enabled {
+		return nil
+	}
+	if d.DeploymentSource != DoraSourceDeployments && d.DeploymentSource != DoraSourceReleases {
+		return ErrDoraDeploymentSource
+	}
+	if d.FailureSource != DoraSourceIssues && d.FailureSource != DoraSourceDeployments && d.FailureSource != DoraSourceAll {
+		return ErrDoraFailureSource
+	}
+	for _, w := range append([]string{d.ReportInterval}, d.Windows...) {
+		if !isPositiveDuration(w) {
+			return ErrDoraWindowFormat
+		}
+	}
+	return nil
+}
+
+// validate checks the name of sla rule and that its time limits are positive durations.
+func (r *SLARule) validate() error {
+	if r.Name == "" {
+		return ErrMissingSLARuleName
+	}
+	if r.FirstResponse == "" && r.Close == "" {
+		return ErrMissingSLARuleLimit
+	}
+	for _, limit := range []string{r.FirstResponse, r.Close} {
+		if limit == "" {
+			continue
+		}
+		if !isPositiveDuration(limit) {
+			return ErrSLARuleLimitFormat
+		}
+	}
+	if r.BusinessHours != nil {
+		return r.BusinessHours.validate()
+	}
+	return nil
+}
+
+// validate checks that compliance rules are known.
+func (cc *ComplianceConfig) validate() error {
+	for _, r := range cc.Rules {
+		known := false
+		for _, k := range ComplianceRules {
+			known = known || r == k
+		}
+		if !known {
+			return ErrUnknownComplianceRule
+		}
+	}
+	return nil
+}
+
+// validate checks the mode of signature verification and that local verification has keys.
+func (sc *SignatureConfig) validate() error {
+	switch sc.Mode {
+	case "", SignatureModeGithub:
+		return nil
+	case SignatureModeLocal:
+		if sc.GPGKeyring == "" && sc.SSHAllowedSigners == "" {
+			return ErrMissingSignatureKeys
+		}
+		return nil
+	}
+	return ErrSignatureMode
+}
+
+// validate checks that report interval of item counts is a positive duration if item counts are enabled.
+func (ic *ItemCountsConfig) validate() error {
+	if !ic.Enabled {
+		return nil
+	}
+	if !isPositiveDuration(ic.ReportInterval) {
+		return ErrItemCountsInterval
+	}
+	return nil
+}
+
+// validate checks that stale thresholds and report interval are positive durations if stale reports are enabled.
+func (sc *StaleConfig) validate() error {
+	if !sc.Enabled {
+		return nil
+	}
+	for _, d := range []string{sc.PullRequestAge, sc.BranchAge, sc.MergedBranchAge, sc.ReportInterval} {
+		if !isPositiveDuration(d) {
+			return ErrStaleAgeFormat
+		}
+	}
+	return nil
+}
+
+// validate checks that hotspot windows and report interval are positive durations if hotspots are enabled.
+func (hc *HotspotConfig) validate() error {
+	if !hc.Enabled {
+		return nil
+	}
+	for _, d := range []string{hc.Window, hc.ReworkWindow, hc.ReportInterval} {
+		if !isPositiveDuration(d) {
+			return ErrHotspotWindowFormat
+		}
+	}
+	return nil
+}
+
+// validate checks that knowledge window and report interval are positive durations if knowledge metrics are enabled.
+func (kc *KnowledgeConfig) validate() error {
+	if !kc.Enabled {
+		return nil
+	}
+	for _, d := range []string{kc.Window, kc.ReportInterval} {
+		if !isPositiveDuration(d) {
+			return ErrKnowledgeWindowFormat
+		}
+	}
+	return nil
+}
+
+// validate checks the min score of anomalies if anomaly detection is enabled.
+func (ac *AnomalyConfig) validate() error {
+	if !ac.Enabled {
+		return nil
+	}
+	if ac.MinScore <= 0 || ac.MinScore >= 1 {
+		return ErrAnomalyMinScore
+	}
+	return nil
+}
+
+// validate checks timezones , hours and days of working hours if work pattern reports are enabled.
+func (wc *WorkPatternConfig) validate() error {
+	if !wc.Enabled {
+		return nil
+	}
+	for _, wh := range wc.WorkingHours {
+		if err := wh.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks timezone , hours and days of working hours.
+func (wh *WorkingHours) validate() error {
+	if _, err := time.LoadLocation(wh.Timezone); err != nil {
+		return ErrWorkingHoursFormat
+	}
+	start, errStart := time.Parse("15:04", wh.Start)
+	end, errEnd := time.Parse("15:04", wh.End)
+	if errStart != nil || errEnd != nil || !start.Before(end) {
+		return ErrWorkingHoursFormat
+	}
+	for _, d := range wh.Days {
+		if !isWeekday(d) {
+			return ErrWorkingHoursFormat
+		}
+	}
+	return nil
+}
+
+// isWeekday checks if day is the name of a day of week ignoring case
+func isWeekday(day string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), day) {
+			return true
+		}
+	}
+	return false
+}
+
+// populateDefaultValues puts default values to optional dora fields.
+func (d *DoraConfig) populateDefaultValues() {
+	if !d.Enabled {
+		return
+	}
+	if d.DeploymentSource == "" {
+		d.DeploymentSource = DoraSourceDeployments
+	}
+	if d.Environment == "" {
+		d.Environment = DefaultDoraEnvironment
+	}
+	if d.FailureSource == "" {
+		d.FailureSource = DoraSourceAll
+	}
+	if len(d.IncidentLabels) == 0 {
+		d.IncidentLabels = DefaultIncidentLabels
+	}
+	if len(d.Windows) == 0 {
+		d.Windows = DefaultDoraWindows
+	}
+	if d.ReportInterval == "" {
+		d.ReportInterval = DefaultDoraReportInterval
+	}
+}
+
+// populateDefaultValues puts default values to optional compliance fields , branches are the branches of the audit job.
+func (cc *ComplianceConfig) populateDefaultValues(branches []string) {
+	if !cc.Enabled {
+		return
+	}
+	if len(cc.ProtectedBranches) == 0 {
+		cc.ProtectedBranches = branches
+	}
+	if len(cc.Rules) == 0 {
+		cc.Rules = ComplianceRules
+	}
+}
+
+// populateDefaultValues puts default report interval of item counts.
+func (ic *ItemCountsConfig) populateDefaultValues() {
+	if ic.Enabled && ic.ReportInterval == "" {
+		ic.ReportInterval = DefaultItemCountsReportInterval
+	}
+}
+
+// populateDefaultValues puts default values to optional stale fields.
+func (sc *StaleConfig) populateDefaultValues() {
+	if !sc.Enabled {
+		return
+	}
+	if sc.PullRequestAge == "" {
+		sc.PullRequestAge = DefaultStalePullRequestAge
+	}
+	if sc.BranchAge == "" {
+		sc.BranchAge = DefaultStaleBranchAge
+	}
+	if sc.MergedBranchAge == "" {
+		sc.MergedBranchAge = DefaultStaleMergedBranchAge
+	}
+	if sc.ReportInterval == "" {
+		sc.ReportInterval = DefaultStaleReportInterval
+	}
+}
+
+// populateDefaultValues puts default values to optional hotspot fields.
+func (hc *HotspotConfig) populateDefaultValues() {
+	if !hc.Enabled {
+		return
+	}
+	if hc.Window == "" {
+		hc.Window = DefaultHotspotWindow
+	}
+	if hc.ReworkWindow == "" {
+		hc.ReworkWindow = DefaultHotspotReworkWindow
+	}
+	if hc.DirectoryDepth <= 0 {
+		hc.DirectoryDepth = DefaultHotspotDirectoryDepth
+	}
+	if hc.Top <= 0 {
+		hc.Top = DefaultHotspotTop
+	}
+	if hc.ReportInterval == "" {
+		hc.ReportInterval = DefaultHotspotReportInterval
+	}
+}
+
+// populateDefaultValues puts default values to optional knowledge fields.
+func (kc *KnowledgeConfig) populateDefaultValues() {
+	if !kc.Enabled {
+		return
+	}
+	if kc.Window == "" {
+		kc.Window = DefaultKnowledgeWindow
+	}
+	if kc.ReportInterval == "" {
+		kc.ReportInterval = DefaultKnowledgeReportInterval
+	}
+}
+
+// populateDefaultValues puts default values to optional anomaly fields , branches are the branches of the audit job.
+func (ac *AnomalyConfig) populateDefaultValues(branches []string) {
+	if !ac.Enabled {
+		return
+	}
+	if ac.MinScore == 0 {
+		ac.MinScore = DefaultAnomalyMinScore
+	}
+	if ac.MinSamples <= 0 {
+		ac.MinSamples = DefaultAnomalyMinSamples
+	}
+	if ac.BaselineSamples <= 0 {
+		ac.BaselineSamples = DefaultAnomalyBaselineSamples
+	}
+	if len(ac.ProtectedBranches) == 0 {
+		ac.ProtectedBranches = branches
+	}
+}
+
+// populateDefaultValues puts default values to optional work pattern fields , default working hours are added
+// for authors not in any listed team if there are none.
+func (wc *WorkPatternConfig) populateDefaultValues() {
+	if !wc.Enabled {
+		return
+	}
+	hasDefault := false
+	for i := range wc.WorkingHours {
+		if wc.WorkingHours[i].Team == "" {
+			hasDefault = true
+		}
+		wc.WorkingHours[i].populateDefaultValues()
+	}
+	if !hasDefault {
+		wc.WorkingHours = append(wc.WorkingHours, WorkingHours{Timezone: DefaultWorkingHoursTimezone, Start: DefaultWorkingHoursStart, End: DefaultWorkingHoursEnd, Days: DefaultWorkingDays})
+	}
+}
+
+// populateDefaultValues puts default values to optional working hours fields.
+func (wh *WorkingHours) populateDefaultValues() {
+	if wh.Timezone == "" {
+		wh.Timezone = DefaultWorkingHoursTimezone
+	}
+	if wh.Start == "" {
+		wh.Start = DefaultWorkingHoursStart
+	}
+	if wh.End == "" {
+		wh.End = DefaultWorkingHoursEnd
+	}
+	if len(wh.Days) == 0 {
+		wh.Days = DefaultWorkingDays
+	}
+}
+
 // populateDefaultValues puts default values to optional fields in config.
 func (c *Config) populateDefaultValues() {
 	for i := range c.AuditJobs {
@@ -205,6 +933,19 @@ func (c *Config) populateDefaultValues() {
 			log.Debugf("adding access token from environment variable for auditjob %v", c.AuditJobs[i].Name)
 			c.AuditJobs[i].AccessToken = accessTokenFromEnv
 		}
+		c.AuditJobs[i].Dora.populateDefaultValues()
+		for _, r := range c.AuditJobs[i].SLARules {
+			if r.BusinessHours != nil {
+				r.BusinessHours.populateDefaultValues()
+			}
+		}
+		c.AuditJobs[i].Compliance.populateDefaultValues(c.AuditJobs[i].Branches)
+		c.AuditJobs[i].ItemCounts.populateDefaultValues()
+		c.AuditJobs[i].Stale.populateDefaultValues()
+		c.AuditJobs[i].Hotspots.populateDefaultValues()
+		c.AuditJobs[i].Knowledge.populateDefaultValues()
+		c.AuditJobs[i].Anomalies.populateDefaultValues(c.AuditJobs[i].Branches)
+		c.AuditJobs[i].WorkPatterns.populateDefaultValues()
 	}
 }
 
@@ -229,6 +970,12 @@ func checkPollingIntervalFormat(pi string) error {
 	return nil
 }
 
+// isPositiveDuration checks if string is a duration accepted by utils.ParseDuration and longer than zero
+func isPositiveDuration(s string) bool {
+	d, err := utils.ParseDuration(s)
+	return err == nil && d > 0
+}
+
 //isNumeric checks if string is numeric or not
 func isNumeric(s string) bool {
 	_, err := strconv.ParseFloat(s, 64)
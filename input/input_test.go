@@ -223,6 +223,27 @@ func Test_isNumeric(t *testing.T) {
 	}
 }
 
+func Test_isPositiveDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{name: "hours", s: "4h", want: true},
+		{name: "days", s: "1.5d", want: true},
+		{name: "zero", s: "0d", want: false},
+		{name: "negative", s: "-1h", want: false},
+		{name: "incorrect format", s: "1w", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPositiveDuration(tt.s); got != tt.want {
+				t.Errorf("isPositiveDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestInitConfig(t *testing.T) {
 	var correct Config = Config{
 		Loglevel: "info",
@@ -0,0 +1,111 @@
+/* Package codeowners resolves owners of files using CODEOWNERS file and teams of the git provider */
+package codeowners
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/maplelabs/github-audit/logger"
+)
+
+var (
+	log logger.Logger
+)
+
+func init() {
+	log = logger.GetLogger()
+}
+
+// rule is a line of CODEOWNERS file , a rule without owners makes matching files unowned
+type rule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// CodeOwners holds rules of CODEOWNERS file in the order they are written
+type CodeOwners struct {
+	rules []rule
+}
+
+// Parse parses CODEOWNERS file , lines with invalid patterns are logged and skipped
+func Parse(r io.Reader) (*CodeOwners, error) {
+	co := new(CodeOwners)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		re, err := regexp.Compile(patternToRegexp(fields[0]))
+		if err != nil {
+			log.Warnf("skipping CODEOWNERS line %v due to error[%v]", line, err)
+			continue
+		}
+		co.rules = append(co.rules, rule{pattern: re, owners: fields[1:]})
+	}
+	return co, scanner.Err()
+}
+
+// patternToRegexp converts a gitignore style CODEOWNERS pattern to a regular expression matching
+// file paths relative to the repository root. Patterns with a leading or middle slash are anchored to the root ,
+// others match at any depth , and a pattern matching a directory also matches everything in it. A wildcard in the
+// last segment only matches names in its directory , so docs/* does not match files of subdirectories of docs.
+func patternToRegexp(pattern string) string {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var sb strings.Builder
+	if anchored {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			sb.WriteString("[^/]*")
+		case pattern[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	lastSegment := pattern[strings.LastIndex(pattern, "/")+1:]
+	switch {
+	case dirOnly:
+		sb.WriteString("/.*$")
+	case strings.ContainsAny(lastSegment, "*?"):
+		sb.WriteString("$")
+	default:
+		sb.WriteString("(?:/.*)?$")
+	}
+	return sb.String()
+}
+
+// Owners returns the owners of a file path as written in CODEOWNERS , the last matching rule takes precedence
+func (co *CodeOwners) Owners(path string) []string {
+	if co == nil {
+		return nil
+	}
+	path = strings.TrimPrefix(path, "/")
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if co.rules[i].pattern.MatchString(path) {
+			return co.rules[i].owners
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package codeowners
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCodeOwners = `# default owners
+*                   @org/core
+*.md                @org/docs docs@example.com
+/build/             @org/infra
+docs/*              @org/writers
+docs/**/*.png       @org/design
+apps/               @org/apps
+/apps/legacy/       # unowned
+`
+
+func TestCodeOwners_Owners(t *testing.T) {
+	co, err := Parse(strings.NewReader(testCodeOwners))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "main.go", want: []string{"@org/core"}},
+		{path: "internal/README.md", want: []string{"@org/docs", "docs@example.com"}},
+		{path: "build/ci/Dockerfile", want: []string{"@org/infra"}},
+		{path: "src/build/main.go", want: []string{"@org/core"}},
+		{path: "docs/images/arch/flow.png", want: []string{"@org/design"}},
+		{path: "docs/flow.png", want: []string{"@org/design"}},
+		{path: "docs/index.txt", want: []string{"@org/writers"}},
+		{path: "docs/guides/setup.md", want: []string{"@org/docs", "docs@example.com"}},
+		{path: "docs/guides/setup.txt", want: []string{"@org/core"}},
+		{path: "services/apps/api.go", want: []string{"@org/apps"}},
+		{path: "apps/legacy/old.go", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := co.Owners(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Owners() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
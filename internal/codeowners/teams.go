@@ -0,0 +1,75 @@
+package codeowners
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+)
+
+// Teams holds members of teams fetched from the git provider
+type Teams struct {
+	// members holds lowercased logins of members with team slug as key
+	members map[string]map[string]bool
+
+	// teamOf holds team slug with lowercased login or account id of member as key
+	teamOf map[string]string
+}
+
+// ParseTeams parses teams fetched with gitprovider GetTeams , a member of more than one team
+// belongs to the first team in alphabetical order
+func ParseTeams(data []byte) (*Teams, error) {
+	t := &Teams{
+		members: make(map[string]map[string]bool),
+		teamOf:  make(map[string]string),
+	}
+	var teams []gitprovider.Team
+	if err := json.Unmarshal(data, &teams); err != nil {
+		log.Errorf("error[%v] in unmarshalling teams", err)
+		return t, err
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].GetSlug() < teams[j].GetSlug() })
+	for _, team := range teams {
+		members := make(map[string]bool, len(team.Members))
+		for _, m := range team.Members {
+			login := strings.ToLower(m.GetLogin())
+			members[login] = true
+			for _, key := range []string{login, strconv.FormatInt(m.GetID(), 10)} {
+				if _, ok := t.teamOf[key]; !ok {
+					t.teamOf[key] = team.GetSlug()
+				}
+			}
+		}
+		t.members[strings.ToLower(team.GetSlug())] = members
+	}
+	return t, nil
+}
+
+// TeamOf returns the team slug of a member by login or account id , empty if not a member of any team
+func (t *Teams) TeamOf(key string) string {
+	if t == nil || key == "" {
+		return ""
+	}
+	return t.teamOf[strings.ToLower(key)]
+}
+
+// IsOwner checks if the user with login is one of the CODEOWNERS owners , either as @login
+// or as member of an @org/team owner. Email owners can not be matched with a login.
+func (t *Teams) IsOwner(owners []string, login string) bool {
+	login = strings.ToLower(login)
+	for _, owner := range owners {
+		owner = strings.ToLower(strings.TrimPrefix(owner, "@"))
+		if i := strings.Index(owner, "/"); i >= 0 {
+			if t != nil && t.members[owner[i+1:]][login] {
+				return true
+			}
+			continue
+		}
+		if owner == login {
+			return true
+		}
+	}
+	return false
+}
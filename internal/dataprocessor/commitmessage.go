@@ -0,0 +1,162 @@
+package dataprocessor
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// conventionalHeader matches the header of a conventional commit like "feat(api)!: add endpoint" ,
+	// the type is checked with ConventionalTypes so prefixes like "WIP:" or "Note:" are not taken as conventional
+	conventionalHeader = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()]*)\))?(!)?: +(\S.*)$`)
+
+	// ConventionalTypes are the commit types of conventional commits and angular convention
+	ConventionalTypes = map[string]bool{
+		"feat": true, "fix": true, "docs": true, "style": true, "refactor": true, "perf": true,
+		"test": true, "build": true, "ci": true, "chore": true, "revert": true,
+	}
+
+	// trailerLine matches a git trailer like "Signed-off-by: name <email>"
+	trailerLine = regexp.MustCompile(`^([A-Za-z][A-Za-z-]*): *(.+)$`)
+
+	// trailerPerson matches the person in a trailer value like "name <email>"
+	trailerPerson = regexp.MustCompile(`^(.*?) *<([^<>]*)>$`)
+
+	// revertHeader matches the header of a commit created by git revert
+	revertHeader = regexp.MustCompile(`^Revert "(.*)"$`)
+
+	// revertedCommit matches the body line of a commit created by git revert
+	revertedCommit = regexp.MustCompile(`This reverts commit ([0-9a-fA-F]{7,40})`)
+)
+
+const (
+	trailerCoAuthoredBy   = "co-authored-by"
+	trailerSignedOffBy    = "signed-off-by"
+	trailerBreakingChange = "breaking-change"
+	conventionalRevert    = "revert"
+)
+
+// CommitMessage represents the structured information parsed from a commit message or a pull request title and body
+type CommitMessage struct {
+	// Conventional is whether the header follows conventional commits
+	Conventional bool `json:"conventional"`
+
+	// Type is the conventional commit type like feat or fix
+	Type string `json:"type"`
+
+	// Scope is the conventional commit scope
+	Scope string `json:"scope"`
+
+	// Breaking is whether the change is marked as breaking with ! or a BREAKING CHANGE footer
+	Breaking bool `json:"breaking"`
+
+	// Subject is the header without conventional commit type and scope
+	Subject string `json:"subject"`
+
+	// TicketKeys are the ticket keys found in message like PROJ-123
+	TicketKeys []string `json:"ticket_keys"`
+
+	// CoAuthors are the people in Co-authored-by trailers
+	CoAuthors []Person `json:"co_authors"`
+
+	// SignedOffBy are the people in Signed-off-by trailers
+	SignedOffBy []Person `json:"signed_off_by"`
+
+	// IsRevert is whether the change reverts an earlier commit
+	IsRevert bool `json:"is_revert"`
+
+	// RevertedSha is the sha of reverted commit if present in message
+	RevertedSha string `json:"reverted_sha"`
+}
+
+// Person represents a person named in a commit trailer
+type Person struct {
+	// Name of the person
+	Name string `json:"name"`
+
+	// Email of the person
+	Email string `json:"email"`
+}
+
+// CompileTicketPatterns compiles ticket key patterns , invalid patterns are logged and skipped.
+// No ticket keys are found if no pattern is passed , as a generic pattern also matches words like UTF-8 or SHA-256.
+func CompileTicketPatterns(patterns []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			log.Errorf("error[%v] in compiling ticket pattern %v", err, p)
+			continue
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled
+}
+
+// ParseCommitMessage parses conventional commit header , ticket keys , trailers and revert markers from message.
+// If a ticket pattern has a capture group , the first group is used as ticket key , else the whole match.
+func ParseCommitMessage(message string, ticketPatterns []*regexp.Regexp) CommitMessage {
+	var cm CommitMessage
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	lines := strings.Split(message, "\n")
+	header := strings.TrimSpace(lines[0])
+	cm.Subject = header
+
+	if m := conventionalHeader.FindStringSubmatch(header); m != nil && ConventionalTypes[strings.ToLower(m[1])] {
+		cm.Conventional = true
+		cm.Type = strings.ToLower(m[1])
+		cm.Scope = strings.TrimSpace(m[2])
+		cm.Breaking = m[3] == "!"
+		cm.Subject = m[4]
+	}
+	if revertHeader.MatchString(header) || cm.Type == conventionalRevert {
+		cm.IsRevert = true
+	}
+	if m := revertedCommit.FindStringSubmatch(message); m != nil {
+		cm.IsRevert = true
+		cm.RevertedSha = m[1]
+	}
+
+	for _, line := range lines[1:] {
+		m := trailerLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			// BREAKING CHANGE has a space so it does not look like other trailers
+			if strings.HasPrefix(strings.TrimSpace(line), "BREAKING CHANGE:") {
+				cm.Breaking = true
+			}
+			continue
+		}
+		switch strings.ToLower(m[1]) {
+		case trailerCoAuthoredBy:
+			cm.CoAuthors = append(cm.CoAuthors, parsePerson(m[2]))
+		case trailerSignedOffBy:
+			cm.SignedOffBy = append(cm.SignedOffBy, parsePerson(m[2]))
+		case trailerBreakingChange:
+			cm.Breaking = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, re := range ticketPatterns {
+		for _, m := range re.FindAllStringSubmatch(message, -1) {
+			key := m[0]
+			if len(m) > 1 && m[1] != "" {
+				key = m[1]
+			}
+			if !seen[key] {
+				seen[key] = true
+				cm.TicketKeys = append(cm.TicketKeys, key)
+			}
+		}
+	}
+	return cm
+}
+
+// parsePerson parses "name <email>" , values without email are kept as name
+func parsePerson(value string) Person {
+	value = strings.TrimSpace(value)
+	if m := trailerPerson.FindStringSubmatch(value); m != nil {
+		return Person{Name: m[1], Email: m[2]}
+	}
+	return Person{Name: value}
+}
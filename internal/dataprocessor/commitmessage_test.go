@@ -0,0 +1,76 @@
+package dataprocessor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommitMessage(t *testing.T) {
+	ticketPatterns := CompileTicketPatterns([]string{`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`, `#([0-9]+)`})
+	tests := []struct {
+		name    string
+		message string
+		want    CommitMessage
+	}{
+		{
+			name:    "plain message without structure",
+			message: "update readme",
+			want: CommitMessage{
+				Subject: "update readme",
+			},
+		},
+		{
+			name:    "conventional commit with scope , breaking marker and tickets",
+			message: "feat(api)!: add audit endpoint PROJ-12\n\nCloses #45 and PROJ-12",
+			want: CommitMessage{
+				Conventional: true,
+				Type:         "feat",
+				Scope:        "api",
+				Breaking:     true,
+				Subject:      "add audit endpoint PROJ-12",
+				TicketKeys:   []string{"PROJ-12", "45"},
+			},
+		},
+		{
+			name:    "conventional commit with breaking change footer and trailers",
+			message: "Fix: handle empty config\r\n\r\nBREAKING CHANGE: config is required\r\nCo-authored-by: Jane Doe <jane@example.com>\r\nSigned-off-by: John Doe <john@example.com>",
+			want: CommitMessage{
+				Conventional: true,
+				Type:         "fix",
+				Breaking:     true,
+				Subject:      "handle empty config",
+				CoAuthors:    []Person{{Name: "Jane Doe", Email: "jane@example.com"}},
+				SignedOffBy:  []Person{{Name: "John Doe", Email: "john@example.com"}},
+			},
+		},
+		{
+			name:    "prefix which is not a conventional commit type",
+			message: "WIP: add audit endpoint PROJ-7",
+			want: CommitMessage{
+				Subject:    "WIP: add audit endpoint PROJ-7",
+				TicketKeys: []string{"PROJ-7"},
+			},
+		},
+		{
+			name:    "revert created by git",
+			message: "Revert \"feat: add endpoint\"\n\nThis reverts commit 9a5a338a2b6f9d435faa9adbda1f952276c1aea8.",
+			want: CommitMessage{
+				Subject:     "Revert \"feat: add endpoint\"",
+				IsRevert:    true,
+				RevertedSha: "9a5a338a2b6f9d435faa9adbda1f952276c1aea8",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseCommitMessage(tt.message, ticketPatterns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommitMessage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// no ticket keys are found without configured patterns
+	if got := ParseCommitMessage("fix: use UTF-8 for PROJ-7", CompileTicketPatterns(nil)); got.TicketKeys != nil {
+		t.Errorf("ParseCommitMessage() ticket keys = %v, want none", got.TicketKeys)
+	}
+}
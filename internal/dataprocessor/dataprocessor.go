@@ -4,7 +4,9 @@ package dataprocessor
 
 import (
 	"encoding/json"
+	"time"
 
+	"github.com/maplelabs/github-audit/internal/signature"
 	"github.com/maplelabs/github-audit/logger"
 )
 
@@ -26,16 +28,80 @@ type DataProcessor interface {
 
 	// ProcessIssues process issue documents , takes data in bytes and tags as input
 	ProcessIssues([]byte, map[string]string) ([]interface{}, error)
+
+	// ProcessContributorStats process weekly contributor stats documents , takes data in bytes , week range and tags as input
+	ProcessContributorStats([]byte, time.Time, time.Time, map[string]string) ([]interface{}, error)
+
+	// ProcessCodeFrequency process weekly code frequency documents , takes data in bytes , week range and tags as input
+	ProcessCodeFrequency([]byte, time.Time, time.Time, map[string]string) ([]interface{}, error)
+
+	// ProcessCommitActivity process weekly commit activity documents , takes data in bytes , week range and tags as input
+	ProcessCommitActivity([]byte, time.Time, time.Time, map[string]string) ([]interface{}, error)
+
+	// ProcessDeployments process deployment documents , takes data in bytes and tags as input
+	ProcessDeployments([]byte, map[string]string) ([]interface{}, error)
+
+	// ProcessReleases process release documents , takes data in bytes and tags as input
+	ProcessReleases([]byte, map[string]string) ([]interface{}, error)
+
+	// ProcessWorkflowRuns process workflow run documents , takes data in bytes , completion time range and tags as input
+	ProcessWorkflowRuns([]byte, time.Time, time.Time, map[string]string) ([]interface{}, error)
+
+	// ProcessItemCounts process item count documents , takes open pull requests in bytes , issue counts by state , time and tags as input
+	ProcessItemCounts([]byte, map[string]int, time.Time, map[string]string) ([]interface{}, error)
+
+	// ProcessPullRequestCycleTime process cycle time document of a merged pull request , takes pull request activity and tags as input
+	ProcessPullRequestCycleTime(PullRequestActivity, map[string]string) ([]interface{}, error)
+
+	// ProcessIssueComments process issue comment documents , takes issue number , data in bytes and tags as input
+	ProcessIssueComments(string, []byte, map[string]string) ([]interface{}, error)
+
+	// ProcessPullRequestReviews process pull request review documents , takes pull request number , data in bytes and tags as input
+	ProcessPullRequestReviews(string, []byte, map[string]string) ([]interface{}, error)
+
+	// ProcessCommitChecks process commit check documents , takes commit sha , data in bytes and tags as input
+	ProcessCommitChecks(string, []byte, map[string]string) ([]interface{}, error)
+
+	// ProcessFirstCommitTime returns the author date of the earliest commit of a pull request , takes commits in bytes as input
+	ProcessFirstCommitTime([]byte) (time.Time, error)
+
+	// ProcessChangedFiles returns paths of changed files along with previous paths of renamed files , takes data in bytes as input
+	ProcessChangedFiles([]byte) ([]string, error)
+
+	// ProcessFileChanges returns changes of files in a commit with line counts and patches , takes data in bytes as input
+	ProcessFileChanges([]byte) ([]FileChange, error)
+
+	// ProcessPushEvents returns pushes of push events , takes data in bytes as input
+	ProcessPushEvents([]byte) ([]Push, error)
+
+	// ProcessBranches returns branches with their head commit , takes data in bytes as input
+	ProcessBranches([]byte) ([]Branch, error)
 }
 
-// NewDataProcessor returns a new data processor based on host type
-func NewDataProcessor(host string, repoName string, repoURL string) DataProcessor {
+// NewDataProcessor returns a new data processor based on host type , ticket patterns are used to find ticket keys in messages
+// and verifier , if not nil , verifies commit signatures locally
+func NewDataProcessor(host string, repoName string, repoURL string, ticketPatterns []string, verifier *signature.Verifier) DataProcessor {
 	if host == "github" {
-		return NewGithubProcessor(repoName, repoURL)
+		return NewGithubProcessor(repoName, repoURL, ticketPatterns, verifier)
 	}
 	return nil
 }
 
+// DecodeDocuments converts processed output documents back to document structs like []Commit or []PullRequest ,
+// used by stages that work on the output of data processor.
+func DecodeDocuments(docs []interface{}, out interface{}) error {
+	b, err := json.Marshal(docs)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling documents for decoding", err)
+		return err
+	}
+	err = json.Unmarshal(b, out)
+	if err != nil {
+		log.Errorf("error[%v] in decoding documents", err)
+	}
+	return err
+}
+
 // AddTags adds tags to data which were passed in config.yaml
 func AddTags(data []byte, tags map[string]string) []interface{} {
 	var docMap []map[string]interface{}
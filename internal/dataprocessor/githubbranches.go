@@ -0,0 +1,70 @@
+package dataprocessor
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+)
+
+// Branch represents a branch other than default branch along with its head commit
+type Branch struct {
+	// Name of branch
+	Name string
+
+	// Protected is whether branch is protected
+	Protected bool
+
+	// DefaultBranch is the name of default branch of the repository
+	DefaultBranch string
+
+	// HeadSha is the sha of head commit
+	HeadSha string
+
+	// LastCommitAt represents at what time the head commit is created
+	LastCommitAt time.Time
+
+	// Author shows the author of head commit
+	Author User
+
+	// AheadBy is the number of commits of branch missing in default branch
+	AheadBy int
+
+	// BehindBy is the number of commits of default branch missing in branch
+	BehindBy int
+
+	// MergedPullRequestNo is the number of a pull request merged from branch at its head commit , empty if there is none
+	MergedPullRequestNo string
+}
+
+// ProcessBranches returns branches with their head commit and comparison with default branch
+func (g GithubProcessor) ProcessBranches(data []byte) ([]Branch, error) {
+	var details []gitprovider.BranchDetail
+	branches := make([]Branch, 0)
+	err := json.Unmarshal(data, &details)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling branches for repository %v", err, g.RepoName)
+		return branches, err
+	}
+	for _, d := range details {
+		var b Branch
+		b.Name = d.GetName()
+		b.Protected = d.GetProtected()
+		b.DefaultBranch = d.DefaultBranch
+		b.HeadSha = d.GetCommit().GetSHA()
+		if c := d.HeadCommit; c != nil {
+			b.LastCommitAt = c.Commit.Committer.GetDate().Local()
+			b.Author.ID = strconv.FormatInt(c.Author.GetID(), 10)
+			b.Author.User = c.Commit.Author.GetName()
+			b.Author.Email = c.Commit.Author.GetEmail()
+		}
+		b.AheadBy = d.AheadBy
+		b.BehindBy = d.BehindBy
+		if d.MergedPullRequest != nil {
+			b.MergedPullRequestNo = strconv.Itoa(d.MergedPullRequest.GetNumber())
+		}
+		branches = append(branches, b)
+	}
+	return branches, nil
+}
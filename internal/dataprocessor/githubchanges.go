@@ -0,0 +1,236 @@
+package dataprocessor
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/maplelabs/github-audit/gitprovider"
+)
+
+const (
+	PULLREQUESTREVIEW = "pull_request_review"
+	COMMITCHECK       = "commit_check"
+
+	// sources of commit checks
+	CheckSourceCheckRun = "check_run"
+	CheckSourceStatus   = "status"
+)
+
+// PullRequestReview represents a review submitted on a pull request
+type PullRequestReview struct {
+	// DocumentType is "pull_request_review"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// PullRequestNo represents pull request number
+	PullRequestNo string `json:"pull_request_no"`
+
+	// ReviewID is review id
+	ReviewID string `json:"review_id"`
+
+	// State represents the state of review , for ex. APPROVED , CHANGES_REQUESTED , COMMENTED , DISMISSED
+	State string `json:"state"`
+
+	// CommitSha is the sha of the commit which was reviewed
+	CommitSha string `json:"commit_sha"`
+
+	// CreatedAt represents at what time the review is submitted
+	CreatedAt time.Time `json:"created_at"`
+
+	// URL is html url to review
+	URL string `json:"url"`
+
+	// CreatedBy shows the reviewer
+	CreatedBy User `json:"created_by"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// CommitCheck represents a check run or status reported for a commit
+type CommitCheck struct {
+	// DocumentType is "commit_check"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// Sha is the sha of checked commit
+	Sha string `json:"sha"`
+
+	// Name is check run name or status context
+	Name string `json:"name"`
+
+	// Source is check_run or status
+	Source string `json:"source"`
+
+	// Status is queued , in_progress or completed for check runs , empty for statuses
+	Status string `json:"status"`
+
+	// Conclusion is the result of check , for ex. success , failure , timed_out for check runs
+	// and success , failure , error , pending for statuses
+	Conclusion string `json:"conclusion"`
+
+	// CreatedAt represents at what time the check is completed or status is reported
+	CreatedAt time.Time `json:"created_at"`
+
+	// URL is html url to check
+	URL string `json:"url"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// ProcessPullRequestReviews prepares review output documents for a pull request
+func (g GithubProcessor) ProcessPullRequestReviews(pullRequestNo string, data []byte, tags map[string]string) ([]interface{}, error) {
+	var reviews []github.PullRequestReview
+	reviewDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &reviews)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling reviews of pull request %v for repository %v", err, pullRequestNo, g.RepoName)
+		return reviewDocuments, err
+	}
+	for _, r := range reviews {
+		var review PullRequestReview
+		review.DocumentType = PULLREQUESTREVIEW
+		review.RepoType = GITHUB
+		review.RepoName = g.RepoName
+		review.RepoURL = g.RepoURL
+		review.PullRequestNo = pullRequestNo
+		review.ReviewID = strconv.FormatInt(r.GetID(), 10)
+		review.State = r.GetState()
+		review.CommitSha = r.GetCommitID()
+		review.CreatedAt = r.GetSubmittedAt().Local()
+		review.URL = r.GetHTMLURL()
+		review.CreatedBy.ID = strconv.FormatInt(r.User.GetID(), 10)
+		review.CreatedBy.User = r.User.GetLogin()
+		review.Time = g.CurrentTimeInMS
+		reviewDocuments = append(reviewDocuments, review)
+	}
+	b, _ := json.Marshal(reviewDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
+
+// ProcessCommitChecks prepares check output documents for a commit
+func (g GithubProcessor) ProcessCommitChecks(sha string, data []byte, tags map[string]string) ([]interface{}, error) {
+	var checks gitprovider.CommitChecks
+	checkDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &checks)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling checks of commit %v for repository %v", err, sha, g.RepoName)
+		return checkDocuments, err
+	}
+	for _, c := range checks.CheckRuns {
+		check := g.newCommitCheck(sha, CheckSourceCheckRun)
+		check.Name = c.GetName()
+		check.Status = c.GetStatus()
+		check.Conclusion = c.GetConclusion()
+		check.CreatedAt = c.GetCompletedAt().Local()
+		check.URL = c.GetHTMLURL()
+		checkDocuments = append(checkDocuments, check)
+	}
+	for _, s := range checks.Statuses {
+		check := g.newCommitCheck(sha, CheckSourceStatus)
+		check.Name = s.GetContext()
+		check.Conclusion = s.GetState()
+		check.CreatedAt = s.GetUpdatedAt().Local()
+		check.URL = s.GetTargetURL()
+		checkDocuments = append(checkDocuments, check)
+	}
+	b, _ := json.Marshal(checkDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
+
+// newCommitCheck returns commit check of a source without check specific fields
+func (g GithubProcessor) newCommitCheck(sha string, source string) CommitCheck {
+	var check CommitCheck
+	check.DocumentType = COMMITCHECK
+	check.RepoType = GITHUB
+	check.RepoName = g.RepoName
+	check.RepoURL = g.RepoURL
+	check.Sha = sha
+	check.Source = source
+	check.Time = g.CurrentTimeInMS
+	return check
+}
+
+// FileChange represents the change of a file in a commit
+type FileChange struct {
+	// Path of the file after the change
+	Path string
+
+	// PreviousPath of renamed file , empty for others
+	PreviousPath string
+
+	// Status is added , removed , modified , renamed , copied , changed or unchanged
+	Status string
+
+	// Additions is the number of lines added
+	Additions int
+
+	// Deletions is the number of lines removed
+	Deletions int
+
+	// Patch is the unified diff of the change , empty for binary files and large diffs
+	Patch string
+}
+
+// ProcessFileChanges returns changes of files in a commit along with line counts and patches
+func (g GithubProcessor) ProcessFileChanges(data []byte) ([]FileChange, error) {
+	var files []github.CommitFile
+	changes := make([]FileChange, 0)
+	err := json.Unmarshal(data, &files)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling file changes for repository %v", err, g.RepoName)
+		return changes, err
+	}
+	for _, f := range files {
+		var change FileChange
+		change.Path = f.GetFilename()
+		change.PreviousPath = f.GetPreviousFilename()
+		change.Status = f.GetStatus()
+		change.Additions = f.GetAdditions()
+		change.Deletions = f.GetDeletions()
+		change.Patch = f.GetPatch()
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// ProcessChangedFiles returns paths of changed files along with previous paths of renamed files
+func (g GithubProcessor) ProcessChangedFiles(data []byte) ([]string, error) {
+	var files []github.CommitFile
+	paths := make([]string, 0)
+	err := json.Unmarshal(data, &files)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling changed files for repository %v", err, g.RepoName)
+		return paths, err
+	}
+	for _, f := range files {
+		paths = append(paths, f.GetFilename())
+		if f.GetPreviousFilename() != "" {
+			paths = append(paths, f.GetPreviousFilename())
+		}
+	}
+	return paths, nil
+}
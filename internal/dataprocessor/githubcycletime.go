@@ -0,0 +1,264 @@
+package dataprocessor
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+const (
+	PULLREQUESTCYCLETIME = "pull_request_cycle_time"
+
+	// review states as reported by github
+	reviewApproved = "APPROVED"
+	reviewPending  = "PENDING"
+
+	// timeline events as reported by github
+	timelineCommented      = "commented"
+	timelineReadyForReview = "ready_for_review"
+)
+
+// PullRequestActivity holds data of a single pull request as fetched from gitprovider
+type PullRequestActivity struct {
+	// PullRequest is the processed pull request document
+	PullRequest PullRequest
+
+	// Reviews are the reviews of pull request
+	Reviews []byte
+
+	// Commits are the commits of pull request
+	Commits []byte
+
+	// ReviewComments are the comments made on the diff of pull request
+	ReviewComments []byte
+
+	// Timeline are the events of the conversation of pull request , like comments and changes of draft state
+	Timeline []byte
+}
+
+// PullRequestCycleTime represents the lifetime of a merged pull request split in phases
+type PullRequestCycleTime struct {
+	// DocumentType is "pull_request_cycle_time"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// PullRequestNo represents pull request number
+	PullRequestNo string `json:"pull_request_no"`
+
+	// Title represents pull request title
+	Title string `json:"title"`
+
+	// URL is the api url for pull request
+	URL string `json:"url"`
+
+	// CreatedBy shows the user who opened the pull request
+	CreatedBy User `json:"created_by"`
+
+	// Branch represents the branch pull request is merged to
+	Branch string `json:"branch"`
+
+	// CreatedAt represents at what time this pull request is opened
+	CreatedAt time.Time `json:"created_at"`
+
+	// FirstCommitAt represents at what time the first commit of pull request is authored
+	FirstCommitAt time.Time `json:"first_commit_at"`
+
+	// ReadyForReviewAt represents at what time a draft pull request is made ready for review , same as created at for others
+	ReadyForReviewAt time.Time `json:"ready_for_review_at"`
+
+	// FirstReviewAt represents at what time the first review is submitted
+	FirstReviewAt time.Time `json:"first_review_at"`
+
+	// ApprovedAt represents at what time the last approval before merge is submitted
+	ApprovedAt time.Time `json:"approved_at"`
+
+	// MergedAt represents at what time this pull request is merged
+	MergedAt time.Time `json:"merged_at"`
+
+	// CodingTimeSeconds is time from first commit to pull request ready for review
+	CodingTimeSeconds int64 `json:"coding_time_seconds"`
+
+	// PickupTimeSeconds is time from pull request ready for review to first review
+	PickupTimeSeconds int64 `json:"pickup_time_seconds"`
+
+	// ReviewTimeSeconds is time from first review to approval
+	ReviewTimeSeconds int64 `json:"review_time_seconds"`
+
+	// MergeTimeSeconds is time from approval to merge
+	MergeTimeSeconds int64 `json:"merge_time_seconds"`
+
+	// CycleTimeSeconds is time from first commit , or pull request open if earlier , to merge
+	CycleTimeSeconds int64 `json:"cycle_time_seconds"`
+
+	// ReviewRounds represents the number of times pull request was reviewed after new commits
+	ReviewRounds int `json:"review_rounds"`
+
+	// ReviewCount represents the number of submitted reviews
+	ReviewCount int `json:"review_count"`
+
+	// CommitCount represents the number of commits
+	CommitCount int `json:"commit_count"`
+
+	// CommentCount represents the number of review and conversation comments
+	CommentCount int `json:"comment_count"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// ProcessPullRequestCycleTime prepares cycle time output document for a merged pull request
+func (g GithubProcessor) ProcessPullRequestCycleTime(activity PullRequestActivity, tags map[string]string) ([]interface{}, error) {
+	var (
+		reviews        []github.PullRequestReview
+		commits        []github.RepositoryCommit
+		reviewComments []github.PullRequestComment
+		timeline       []github.Timeline
+	)
+	cycleTimeDocuments := make([]interface{}, 0)
+	for _, raw := range []struct {
+		data []byte
+		out  interface{}
+	}{
+		{activity.Reviews, &reviews},
+		{activity.Commits, &commits},
+		{activity.ReviewComments, &reviewComments},
+		{activity.Timeline, &timeline},
+	} {
+		if len(raw.data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(raw.data, raw.out); err != nil {
+			log.Errorf("error[%v] in unmarshalling pull request activity for repository %v", err, g.RepoName)
+			return cycleTimeDocuments, err
+		}
+	}
+	pr := activity.PullRequest
+	if pr.MergedAt.IsZero() {
+		return cycleTimeDocuments, nil
+	}
+	var ct PullRequestCycleTime
+	ct.DocumentType = PULLREQUESTCYCLETIME
+	ct.RepoType = GITHUB
+	ct.RepoName = g.RepoName
+	ct.RepoURL = g.RepoURL
+	ct.PullRequestNo = pr.PullRequestNo
+	ct.Title = pr.Title
+	ct.URL = pr.URL
+	ct.CreatedBy = pr.CreatedBy
+	ct.Branch = pr.MergeToRepo.Branch
+	ct.CreatedAt = pr.CreatedAt
+	ct.MergedAt = pr.MergedAt
+	ct.Time = g.CurrentTimeInMS
+
+	commitTimes := authoredTimes(commits)
+	if len(commitTimes) > 0 {
+		ct.FirstCommitAt = commitTimes[0].Local()
+	}
+
+	// reviews are listed in chronological order
+	commitsSeen := -1
+	for _, r := range reviews {
+		submittedAt := r.GetSubmittedAt()
+		// pending reviews are not submitted and self reviews are only comments
+		if strings.ToUpper(r.GetState()) == reviewPending || strconv.FormatInt(r.User.GetID(), 10) == pr.CreatedBy.ID || submittedAt.After(ct.MergedAt) {
+			continue
+		}
+		ct.ReviewCount++
+		if ct.FirstReviewAt.IsZero() {
+			ct.FirstReviewAt = submittedAt.Local()
+		}
+		if strings.ToUpper(r.GetState()) == reviewApproved {
+			ct.ApprovedAt = submittedAt.Local()
+		}
+		// a new review round starts with the first review after new commits are added
+		commitsBefore := sort.Search(len(commitTimes), func(i int) bool { return commitTimes[i].After(submittedAt) })
+		if commitsBefore != commitsSeen {
+			ct.ReviewRounds++
+			commitsSeen = commitsBefore
+		}
+	}
+	ct.CommitCount = len(commits)
+	ct.CommentCount = len(reviewComments)
+
+	// a pull request opened as draft waits for review only after the last time it is made ready before the first review
+	ct.ReadyForReviewAt = ct.CreatedAt
+	readyBefore := ct.MergedAt
+	if !ct.FirstReviewAt.IsZero() {
+		readyBefore = ct.FirstReviewAt
+	}
+	for _, e := range timeline {
+		switch e.GetEvent() {
+		case timelineCommented:
+			ct.CommentCount++
+		case timelineReadyForReview:
+			at := e.GetCreatedAt()
+			if at.After(ct.ReadyForReviewAt) && !at.After(readyBefore) {
+				ct.ReadyForReviewAt = at.Local()
+			}
+		}
+	}
+
+	ct.CodingTimeSeconds = phaseSeconds(ct.FirstCommitAt, ct.ReadyForReviewAt)
+	ct.PickupTimeSeconds = phaseSeconds(ct.ReadyForReviewAt, ct.FirstReviewAt)
+	ct.ReviewTimeSeconds = phaseSeconds(ct.FirstReviewAt, ct.ApprovedAt)
+	ct.MergeTimeSeconds = phaseSeconds(ct.ApprovedAt, ct.MergedAt)
+	// commits may be authored after the pull request is opened , for ex. when they are amended after a review
+	if ct.FirstCommitAt.IsZero() || ct.CreatedAt.Before(ct.FirstCommitAt) {
+		ct.CycleTimeSeconds = phaseSeconds(ct.CreatedAt, ct.MergedAt)
+	} else {
+		ct.CycleTimeSeconds = phaseSeconds(ct.FirstCommitAt, ct.MergedAt)
+	}
+	cycleTimeDocuments = append(cycleTimeDocuments, ct)
+
+	b, _ := json.Marshal(cycleTimeDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
+
+// phaseSeconds returns the seconds spent between start and end of a phase , 0 if phase is not reached
+// or if start is after end , for ex. when commits are authored after the pull request is opened.
+func phaseSeconds(start time.Time, end time.Time) int64 {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return int64(end.Sub(start).Seconds())
+}
+
+// ProcessFirstCommitTime returns the time the earliest commit of a pull request was authored , zero time if there are no commits
+func (g GithubProcessor) ProcessFirstCommitTime(data []byte) (time.Time, error) {
+	var commits []github.RepositoryCommit
+	err := json.Unmarshal(data, &commits)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling commits of pull request for repository %v", err, g.RepoName)
+		return time.Time{}, err
+	}
+	commitTimes := authoredTimes(commits)
+	if len(commitTimes) == 0 {
+		return time.Time{}, nil
+	}
+	return commitTimes[0].Local(), nil
+}
+
+// authoredTimes returns the author dates of commits in chronological order. Author date is used instead of committer date
+// as rebasing or amending a pull request changes the committer date of all its commits to the time of rebase.
+func authoredTimes(commits []github.RepositoryCommit) []time.Time {
+	commitTimes := make([]time.Time, 0, len(commits))
+	for _, c := range commits {
+		commitTimes = append(commitTimes, c.Commit.Author.GetDate())
+	}
+	sort.Slice(commitTimes, func(i, j int) bool { return commitTimes[i].Before(commitTimes[j]) })
+	return commitTimes
+}
@@ -0,0 +1,101 @@
+package dataprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestGithubProcessor_ProcessPullRequestCycleTime(t *testing.T) {
+	g := GithubProcessor{RepoName: "testRepo", MetricFormator: &metricformator.MetricFormator{}}
+	at := func(day int, hour int) time.Time { return time.Date(2022, 10, day, hour, 0, 0, 0, time.UTC) }
+	pr := PullRequest{PullRequestNo: "1", CreatedAt: at(10, 10), MergedAt: at(11, 10)}
+	pr.CreatedBy.ID = "1"
+	tests := []struct {
+		name      string
+		activity  PullRequestActivity
+		wantReady time.Time
+		want      map[string]float64
+	}{
+		{
+			name: "no review",
+			activity: PullRequestActivity{
+				PullRequest: pr,
+				Commits:     []byte(`[{"sha":"a","commit":{"author":{"date":"2022-10-10T08:00:00Z"}}}]`),
+				Timeline:    []byte(`[{"event":"commented","created_at":"2022-10-10T12:00:00Z"},{"event":"labeled","created_at":"2022-10-10T12:00:00Z"}]`),
+			},
+			wantReady: at(10, 10),
+			want: map[string]float64{"coding_time_seconds": 7200, "pickup_time_seconds": 0, "review_time_seconds": 0, "merge_time_seconds": 0,
+				"cycle_time_seconds": 93600, "review_rounds": 0, "review_count": 0, "commit_count": 1, "comment_count": 1},
+		},
+		{
+			// commits amended after the first review are authored after the pull request is opened
+			name: "review before the first commit",
+			activity: PullRequestActivity{
+				PullRequest: pr,
+				Reviews: []byte(`[{"id":1,"user":{"id":2},"state":"APPROVED","submitted_at":"2022-10-10T11:00:00Z"},
+					{"id":2,"user":{"id":1},"state":"COMMENTED","submitted_at":"2022-10-10T12:30:00Z"},
+					{"id":3,"user":{"id":2},"state":"COMMENTED","submitted_at":"2022-10-10T13:00:00Z"}]`),
+				Commits: []byte(`[{"sha":"a","commit":{"author":{"date":"2022-10-10T12:00:00Z"}}}]`),
+			},
+			wantReady: at(10, 10),
+			want: map[string]float64{"coding_time_seconds": 0, "pickup_time_seconds": 3600, "review_time_seconds": 0, "merge_time_seconds": 82800,
+				"cycle_time_seconds": 86400, "review_rounds": 2, "review_count": 2, "commit_count": 1, "comment_count": 0},
+		},
+		{
+			// made ready again after the first review , pickup time is until the first review
+			name: "draft made ready for review",
+			activity: PullRequestActivity{
+				PullRequest:    pr,
+				Reviews:        []byte(`[{"id":1,"user":{"id":2},"state":"APPROVED","submitted_at":"2022-10-10T16:00:00Z"}]`),
+				Commits:        []byte(`[{"sha":"a","commit":{"author":{"date":"2022-10-10T09:00:00Z"}}}]`),
+				ReviewComments: []byte(`[{"id":1}]`),
+				Timeline: []byte(`[{"event":"ready_for_review","created_at":"2022-10-10T14:00:00Z"},
+					{"event":"convert_to_draft","created_at":"2022-10-10T18:00:00Z"},{"event":"ready_for_review","created_at":"2022-10-10T20:00:00Z"}]`),
+			},
+			wantReady: at(10, 14),
+			want: map[string]float64{"coding_time_seconds": 18000, "pickup_time_seconds": 7200, "review_time_seconds": 0, "merge_time_seconds": 64800,
+				"cycle_time_seconds": 90000, "review_rounds": 1, "review_count": 1, "commit_count": 1, "comment_count": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := g.ProcessPullRequestCycleTime(tt.activity, nil)
+			if err != nil || len(docs) != 1 {
+				t.Fatalf("ProcessPullRequestCycleTime() = %v, error = %v", docs, err)
+			}
+			doc := docs[0].(map[string]interface{})
+			for k, want := range tt.want {
+				if doc[k] != want {
+					t.Errorf("ProcessPullRequestCycleTime() %v = %v, want %v", k, doc[k], want)
+				}
+			}
+			ready, _ := time.Parse(time.RFC3339, doc["ready_for_review_at"].(string))
+			if !ready.Equal(tt.wantReady) {
+				t.Errorf("ProcessPullRequestCycleTime() ready_for_review_at = %v, want %v", ready, tt.wantReady)
+			}
+		})
+	}
+
+	// pull requests closed without merge have no cycle time
+	closed := pr
+	closed.MergedAt = time.Time{}
+	if docs, err := g.ProcessPullRequestCycleTime(PullRequestActivity{PullRequest: closed}, nil); err != nil || len(docs) != 0 {
+		t.Errorf("ProcessPullRequestCycleTime() = %v, error = %v, want no documents", docs, err)
+	}
+}
+
+func TestGithubProcessor_ProcessFirstCommitTime(t *testing.T) {
+	g := GithubProcessor{RepoName: "testRepo"}
+	// rebased commits keep their author date , committer date is the time of rebase
+	commits := []byte(`[{"sha":"b","commit":{"author":{"date":"2022-10-10T09:00:00Z"},"committer":{"date":"2022-10-11T08:00:00Z"}}},
+		{"sha":"a","commit":{"author":{"date":"2022-10-10T08:00:00Z"},"committer":{"date":"2022-10-11T08:00:00Z"}}}]`)
+	got, err := g.ProcessFirstCommitTime(commits)
+	if want := time.Date(2022, 10, 10, 8, 0, 0, 0, time.UTC); err != nil || !got.Equal(want) {
+		t.Errorf("ProcessFirstCommitTime() = %v, error = %v, want %v", got, err, want)
+	}
+	if got, err = g.ProcessFirstCommitTime([]byte(`[]`)); err != nil || !got.IsZero() {
+		t.Errorf("ProcessFirstCommitTime() = %v, error = %v, want zero time", got, err)
+	}
+}
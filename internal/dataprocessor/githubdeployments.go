@@ -0,0 +1,186 @@
+package dataprocessor
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+const (
+	DEPLOYMENT = "deployment"
+	RELEASE    = "release"
+)
+
+// githubDeployment is a github deployment along with its statuses as fetched by gitprovider
+type githubDeployment struct {
+	github.Deployment
+
+	// Statuses of the deployment , latest status first
+	Statuses []github.DeploymentStatus `json:"statuses"`
+}
+
+// Deployment represents deployment document
+type Deployment struct {
+	// DocumentType is "deployment"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// DeploymentID is the deployment id
+	DeploymentID string `json:"deployment_id"`
+
+	// Sha represents deployed commit sha
+	Sha string `json:"sha"`
+
+	// Ref represents deployed branch , tag or sha
+	Ref string `json:"ref"`
+
+	// Environment represents the environment of deployment
+	Environment string `json:"environment"`
+
+	// CreatedAt represents at what time this deployment is created
+	CreatedAt time.Time `json:"created_at"`
+
+	// State represents the latest status of deployment
+	State string `json:"state"`
+
+	// StateAt represents at what time the latest status was reported
+	StateAt time.Time `json:"state_at"`
+
+	// CreatedBy shows the user who created it
+	CreatedBy User `json:"created_by"`
+
+	// URL is api url to deployment
+	URL string `json:"url"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// Release represents release document
+type Release struct {
+	// DocumentType is "release"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// ReleaseID is the release id
+	ReleaseID string `json:"release_id"`
+
+	// TagName represents the tag of release
+	TagName string `json:"tag_name"`
+
+	// TargetCommitish represents the branch or sha the tag is created from
+	TargetCommitish string `json:"target_commitish"`
+
+	// Name represents the release name
+	Name string `json:"name"`
+
+	// Draft is whether this release is a draft
+	Draft bool `json:"draft"`
+
+	// Prerelease is whether this release is a prerelease
+	Prerelease bool `json:"prerelease"`
+
+	// CreatedAt represents at what time this release is created
+	CreatedAt time.Time `json:"created_at"`
+
+	// PublishedAt represents at what time this release is published
+	PublishedAt time.Time `json:"published_at"`
+
+	// CreatedBy shows the user who created it
+	CreatedBy User `json:"created_by"`
+
+	// URL is api url to release
+	URL string `json:"url"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// ProcessDeployments prepares deployment output documents
+func (g GithubProcessor) ProcessDeployments(data []byte, tags map[string]string) ([]interface{}, error) {
+	var deployments []githubDeployment
+	deploymentDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &deployments)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling deployments for repository %v", err, g.RepoName)
+		return deploymentDocuments, err
+	}
+	for _, d := range deployments {
+		var deployment Deployment
+		deployment.DocumentType = DEPLOYMENT
+		deployment.RepoType = GITHUB
+		deployment.RepoName = g.RepoName
+		deployment.RepoURL = g.RepoURL
+		deployment.DeploymentID = strconv.FormatInt(d.GetID(), 10)
+		deployment.Sha = d.GetSHA()
+		deployment.Ref = d.GetRef()
+		deployment.Environment = d.GetEnvironment()
+		deployment.CreatedAt = d.GetCreatedAt().Local()
+		deployment.CreatedBy.ID = strconv.FormatInt(d.Creator.GetID(), 10)
+		deployment.CreatedBy.User = d.Creator.GetLogin()
+		deployment.URL = d.GetURL()
+		if len(d.Statuses) > 0 {
+			deployment.State = d.Statuses[0].GetState()
+			deployment.StateAt = d.Statuses[0].GetCreatedAt().Local()
+		}
+		deployment.Time = g.CurrentTimeInMS
+		deploymentDocuments = append(deploymentDocuments, deployment)
+	}
+	b, _ := json.Marshal(deploymentDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
+
+// ProcessReleases prepares release output documents
+func (g GithubProcessor) ProcessReleases(data []byte, tags map[string]string) ([]interface{}, error) {
+	var releases []github.RepositoryRelease
+	releaseDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &releases)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling releases for repository %v", err, g.RepoName)
+		return releaseDocuments, err
+	}
+	for _, r := range releases {
+		var release Release
+		release.DocumentType = RELEASE
+		release.RepoType = GITHUB
+		release.RepoName = g.RepoName
+		release.RepoURL = g.RepoURL
+		release.ReleaseID = strconv.FormatInt(r.GetID(), 10)
+		release.TagName = r.GetTagName()
+		release.TargetCommitish = r.GetTargetCommitish()
+		release.Name = r.GetName()
+		release.Draft = r.GetDraft()
+		release.Prerelease = r.GetPrerelease()
+		release.CreatedAt = r.GetCreatedAt().Local()
+		release.PublishedAt = r.GetPublishedAt().Local()
+		release.CreatedBy.ID = strconv.FormatInt(r.Author.GetID(), 10)
+		release.CreatedBy.User = r.Author.GetLogin()
+		release.URL = r.GetURL()
+		release.Time = g.CurrentTimeInMS
+		releaseDocuments = append(releaseDocuments, release)
+	}
+	b, _ := json.Marshal(releaseDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
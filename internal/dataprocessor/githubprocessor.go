@@ -2,18 +2,21 @@ package dataprocessor
 
 import (
 	"encoding/json"
+	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/google/go-github/v48/github"
+	"github.com/maplelabs/github-audit/internal/signature"
 	"github.com/maplelabs/github-audit/metricformator"
 )
 
 const (
-	COMMIT      = "commit"
-	PULLREQUEST = "pull_request"
-	ISSUE       = "issue"
-	GITHUB      = "github"
+	COMMIT       = "commit"
+	PULLREQUEST  = "pull_request"
+	ISSUE        = "issue"
+	ISSUECOMMENT = "issue_comment"
+	GITHUB       = "github"
 )
 
 // GithubProcessor process data from github APIs
@@ -29,13 +32,21 @@ type GithubProcessor struct {
 
 	// Metricformator instance to customise processed data
 	MetricFormator *metricformator.MetricFormator
+
+	// TicketPatterns are used to find ticket keys in commit messages and pull requests
+	TicketPatterns []*regexp.Regexp
+
+	// Verifier verifies commit signatures locally , nil to use the verification reported by github
+	Verifier *signature.Verifier
 }
 
 // NewGithubProcessor provides new instance of github api processor
-func NewGithubProcessor(repoName string, repoURL string) GithubProcessor {
+func NewGithubProcessor(repoName string, repoURL string, ticketPatterns []string, verifier *signature.Verifier) GithubProcessor {
 	var gp GithubProcessor
 	gp.RepoName = repoName
 	gp.RepoURL = repoURL
+	gp.TicketPatterns = CompileTicketPatterns(ticketPatterns)
+	gp.Verifier = verifier
 	gp.CurrentTimeInMS = time.Now().UnixNano() / 1000000
 	gp.MetricFormator = metricformator.NewMetricFormator()
 	return gp
@@ -58,22 +69,64 @@ type Commit struct {
 	// CommitURL is the github api url to commit
 	CommitURL string `json:"commit_url"`
 
-	// CreatedAt represents at what time this commit was created
+	// CreatedAt represents at what time this commit was created by committer
 	CreatedAt time.Time `json:"created_at"`
 
+	// AuthorLocalTime represents at what time the commit was authored , in the timezone of author as recorded in git ,
+	// zero if not known
+	AuthorLocalTime time.Time `json:"author_local_time"`
+
+	// UTCOffset is the timezone offset of author as recorded in git , for ex. +05:30 , empty if not known
+	UTCOffset string `json:"utc_offset"`
+
 	// Message represents commit message
 	Message string `json:"message"`
 
+	// ParsedMessage represents the structured information parsed from commit message
+	ParsedMessage CommitMessage `json:"parsed_message"`
+
+	// Author provides info related to user who authored changes
+	Author User `json:"author"`
+
 	// Committer provides info related to user who commited changes
 	Committer User `json:"committer"`
 
 	// Sha represents commit sha
 	Sha string `json:"sha"`
 
+	// Parents are the shas of parent commits , more than one for merge commits
+	Parents []string `json:"parents"`
+
+	// Verification represents the signature verification of commit
+	Verification CommitVerification `json:"verification"`
+
 	// time in milliseconds
 	Time int64 `json:"time"`
 }
 
+// CommitVerification represents the signature verification of a commit as reported by git provider
+// or as verified locally against configured keys
+type CommitVerification struct {
+	// Verified is whether the commit is signed with a valid signature
+	Verified bool `json:"verified"`
+
+	// Reason is why the signature is valid or not , for ex. valid , unsigned , unknown_key
+	Reason string `json:"reason"`
+
+	// Signer is the github login of committer for signatures verified by github
+	// and the email of the key identity for signatures verified locally , empty if not verified
+	Signer string `json:"signer"`
+
+	// SignatureType is gpg , ssh or x509 , empty if unsigned
+	SignatureType string `json:"signature_type"`
+
+	// KeyID is the gpg key id or sha256 fingerprint of the ssh key that made the signature
+	KeyID string `json:"key_id"`
+
+	// VerifiedBy is github or local
+	VerifiedBy string `json:"verified_by"`
+}
+
 // User represents a git user
 type User struct {
 	// ID of the user
@@ -81,6 +134,9 @@ type User struct {
 
 	// User contains user name
 	User string `json:"user"`
+
+	// Email of the user , only known for commit author and committer
+	Email string `json:"email,omitempty"`
 }
 
 // PullRequest represents pull reequest document
@@ -115,6 +171,9 @@ type PullRequest struct {
 	// Title represents pull request title
 	Title string `json:"title"`
 
+	// ParsedMessage represents the structured information parsed from pull request title and body
+	ParsedMessage CommitMessage `json:"parsed_message"`
+
 	// URL is the api url for pull request
 	URL string `json:"url"`
 
@@ -124,6 +183,9 @@ type PullRequest struct {
 	// MergeCommitSha represents pull request sha
 	MergeCommitSha string `json:"merge_commit_sha"`
 
+	// CreatedBy shows the user who opened the pull request
+	CreatedBy User `json:"created_by"`
+
 	// Reviewers holds list of reviewrs
 	Reviewers []User `json:"reviewers"`
 
@@ -217,10 +279,73 @@ type Issue struct {
 	// Assignees represents issue assignees
 	Assignees []User `json:"assignees"`
 
+	// Labels represents issue labels
+	Labels []string `json:"labels"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// IssueComment represents comment on an issue
+type IssueComment struct {
+	// DocumentType is "issue_comment"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// IssueNo is issue number
+	IssueNo string `json:"issue_no"`
+
+	// CommentID is comment id
+	CommentID string `json:"comment_id"`
+
+	// CreatedAt represents at what time this comment is created
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt represents at what time this comment is updated
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// URL is api url to comment
+	URL string `json:"url"`
+
+	// CreatedBy shows the user who created it
+	CreatedBy User `json:"created_by"`
+
 	// time in milliseconds
 	Time int64 `json:"time"`
 }
 
+// verifyCommit returns the signature verification of commit , verified locally if a verifier is set
+func (g GithubProcessor) verifyCommit(c github.RepositoryCommit) CommitVerification {
+	var verification CommitVerification
+	sig := c.Commit.Verification.GetSignature()
+	if g.Verifier != nil {
+		result := g.Verifier.Verify(sig, c.Commit.Verification.GetPayload(), c.Commit.Committer.GetEmail())
+		verification.Verified = result.Verified
+		verification.Reason = result.Reason
+		verification.Signer = result.Signer
+		verification.SignatureType = result.Type
+		verification.KeyID = result.KeyID
+		verification.VerifiedBy = signature.VerifiedByLocal
+		return verification
+	}
+	verification.Verified = c.Commit.Verification.GetVerified()
+	verification.Reason = c.Commit.Verification.GetReason()
+	verification.SignatureType, verification.KeyID = signature.Inspect(sig)
+	if verification.Verified {
+		verification.Signer = c.Committer.GetLogin()
+	}
+	verification.VerifiedBy = signature.VerifiedByGithub
+	return verification
+}
+
 // ProcessCommits prepares commit output documents
 func (g GithubProcessor) ProcessCommits(data []byte, tags map[string]string) ([]interface{}, error) {
 	var commits []github.RepositoryCommit
@@ -236,12 +361,28 @@ func (g GithubProcessor) ProcessCommits(data []byte, tags map[string]string) ([]
 		commit.RepoURL = g.RepoURL
 		commit.DocumentType = COMMIT
 		commit.Message = c.Commit.GetMessage()
+		commit.ParsedMessage = ParseCommitMessage(commit.Message, g.TicketPatterns)
 		commit.RepoType = GITHUB
 		commit.CommitURL = c.GetURL()
 		commit.Sha = c.GetSHA()
+		commit.Parents = make([]string, 0, len(c.Parents))
+		for _, p := range c.Parents {
+			commit.Parents = append(commit.Parents, p.GetSHA())
+		}
 		commit.CreatedAt = c.Commit.Committer.GetDate().Local()
+		// raw commit object is only returned for signed commits , its times keep the timezone of author and committer
+		if at, ok := gitTime(c.Commit.Verification.GetPayload(), "author"); ok {
+			commit.AuthorLocalTime = at
+			commit.UTCOffset = at.Format("-07:00")
+		}
+		// id is of the github account linked to the git email , user and email are as recorded in git
+		commit.Author.ID = strconv.FormatInt(c.Author.GetID(), 10)
+		commit.Author.User = c.Commit.Author.GetName()
+		commit.Author.Email = c.Commit.Author.GetEmail()
 		commit.Committer.ID = strconv.FormatInt(c.Committer.GetID(), 10)
-		commit.Committer.User = c.Commit.Author.GetName()
+		commit.Committer.User = c.Commit.Committer.GetName()
+		commit.Committer.Email = c.Commit.Committer.GetEmail()
+		commit.Verification = g.verifyCommit(c)
 		commit.Time = g.CurrentTimeInMS
 		commitDocuments = append(commitDocuments, commit)
 	}
@@ -273,8 +414,11 @@ func (g GithubProcessor) ProcessPullRequests(data []byte, tags map[string]string
 		pr.State = p.GetState()
 		pr.URL = p.GetURL()
 		pr.Title = p.GetTitle()
+		pr.ParsedMessage = ParseCommitMessage(p.GetTitle()+"\n\n"+p.GetBody(), g.TicketPatterns)
 		pr.MergedAt = p.GetMergedAt().Local()
 		pr.MergeCommitSha = p.GetMergeCommitSHA()
+		pr.CreatedBy.ID = strconv.FormatInt(p.User.GetID(), 10)
+		pr.CreatedBy.User = p.User.GetLogin()
 		pr.Time = g.CurrentTimeInMS
 		var reqFromRepo RequestFromRepository
 		reqFromRepo.Branch = p.Head.GetRef()
@@ -333,6 +477,9 @@ func (g GithubProcessor) ProcessIssues(data []byte, tags map[string]string) ([]i
 			issue.Time = g.CurrentTimeInMS
 			issue.CreatedAt = i.GetCreatedAt().Local()
 			issue.UpdatedAt = i.GetUpdatedAt().Local()
+			if i.ClosedAt != nil {
+				issue.ClosedAt = i.GetClosedAt().Local().Format(time.RFC3339)
+			}
 			issue.CreatedBy.ID = strconv.FormatInt(i.User.GetID(), 10)
 			issue.CreatedBy.User = i.User.GetLogin()
 			var assignees []User
@@ -343,6 +490,9 @@ func (g GithubProcessor) ProcessIssues(data []byte, tags map[string]string) ([]i
 				assignees = append(assignees, u)
 			}
 			issue.Assignees = assignees
+			for _, l := range i.Labels {
+				issue.Labels = append(issue.Labels, l.GetName())
+			}
 			issueDocuments = append(issueDocuments, issue)
 		}
 	}
@@ -351,3 +501,34 @@ func (g GithubProcessor) ProcessIssues(data []byte, tags map[string]string) ([]i
 	finalDocs := AddTags(b, tags)
 	return finalDocs, err
 }
+
+// ProcessIssueComments prepares issue comment output documents for an issue
+func (g GithubProcessor) ProcessIssueComments(issueNo string, data []byte, tags map[string]string) ([]interface{}, error) {
+	var comments []github.IssueComment
+	commentDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &comments)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling comments of issue %v for repository %v", err, issueNo, g.RepoName)
+		return commentDocuments, err
+	}
+	for _, c := range comments {
+		var comment IssueComment
+		comment.DocumentType = ISSUECOMMENT
+		comment.RepoType = GITHUB
+		comment.RepoName = g.RepoName
+		comment.RepoURL = g.RepoURL
+		comment.IssueNo = issueNo
+		comment.CommentID = strconv.FormatInt(c.GetID(), 10)
+		comment.CreatedAt = c.GetCreatedAt().Local()
+		comment.UpdatedAt = c.GetUpdatedAt().Local()
+		comment.URL = c.GetURL()
+		comment.CreatedBy.ID = strconv.FormatInt(c.User.GetID(), 10)
+		comment.CreatedBy.User = c.User.GetLogin()
+		comment.Time = g.CurrentTimeInMS
+		commentDocuments = append(commentDocuments, comment)
+	}
+	b, _ := json.Marshal(commentDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
@@ -0,0 +1,58 @@
+package dataprocessor
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// Push represents a push to a branch as reported by push events
+type Push struct {
+	// Ref is the full ref pushed to , for ex. refs/heads/main
+	Ref string
+
+	// Before is the sha of head before the push
+	Before string
+
+	// Head is the sha of head after the push
+	Head string
+
+	// Pusher is the user who pushed
+	Pusher User
+
+	// CreatedAt represents at what time the push is made
+	CreatedAt time.Time
+}
+
+// ProcessPushEvents returns pushes of push events , events of other types are skipped
+func (g GithubProcessor) ProcessPushEvents(data []byte) ([]Push, error) {
+	var events []github.Event
+	pushes := make([]Push, 0)
+	err := json.Unmarshal(data, &events)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling push events for repository %v", err, g.RepoName)
+		return pushes, err
+	}
+	for _, e := range events {
+		payload, err := e.ParsePayload()
+		if err != nil {
+			log.Errorf("error[%v] in parsing payload of event %v for repository %v", err, e.GetID(), g.RepoName)
+			continue
+		}
+		pe, ok := payload.(*github.PushEvent)
+		if !ok {
+			continue
+		}
+		var push Push
+		push.Ref = pe.GetRef()
+		push.Before = pe.GetBefore()
+		push.Head = pe.GetHead()
+		push.Pusher.ID = strconv.FormatInt(e.Actor.GetID(), 10)
+		push.Pusher.User = e.Actor.GetLogin()
+		push.CreatedAt = e.GetCreatedAt().Local()
+		pushes = append(pushes, push)
+	}
+	return pushes, nil
+}
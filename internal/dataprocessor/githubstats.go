@@ -0,0 +1,214 @@
+package dataprocessor
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+const (
+	CONTRIBUTORSTATS = "contributor_stats"
+	CODEFREQUENCY    = "code_frequency"
+	COMMITACTIVITY   = "commit_activity"
+)
+
+// ContributorStats represents weekly contribution document of a single contributor
+type ContributorStats struct {
+	// DocumentType is "contributor_stats"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents the start of the week
+	CreatedAt time.Time `json:"created_at"`
+
+	// Contributor provides info related to the contributor
+	Contributor User `json:"contributor"`
+
+	// Additions represents lines added by contributor in the week
+	Additions int `json:"additions"`
+
+	// Deletions represents lines deleted by contributor in the week
+	Deletions int `json:"deletions"`
+
+	// Commits represents commits made by contributor in the week
+	Commits int `json:"commits"`
+
+	// TotalCommits represents all commits made by contributor to the repository
+	TotalCommits int `json:"total_commits"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// CodeFrequency represents weekly additions and deletions document for repository
+type CodeFrequency struct {
+	// DocumentType is "code_frequency"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents the start of the week
+	CreatedAt time.Time `json:"created_at"`
+
+	// Additions represents lines added in the week
+	Additions int `json:"additions"`
+
+	// Deletions represents lines deleted in the week
+	Deletions int `json:"deletions"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// CommitActivity represents weekly commit count document for repository
+type CommitActivity struct {
+	// DocumentType is "commit_activity"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents the start of the week
+	CreatedAt time.Time `json:"created_at"`
+
+	// Total represents commits made in the week
+	Total int `json:"total"`
+
+	// Days represents commits made on each day of the week starting on sunday
+	Days []int `json:"days"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// ProcessContributorStats prepares contributor stats output documents for weeks starting after from and upto to.
+// Weeks without any contribution are skipped.
+func (g GithubProcessor) ProcessContributorStats(data []byte, from time.Time, to time.Time, tags map[string]string) ([]interface{}, error) {
+	var stats []github.ContributorStats
+	statsDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &stats)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling contributor stats for repository %v", err, g.RepoName)
+		return statsDocuments, err
+	}
+	for _, s := range stats {
+		for _, w := range s.Weeks {
+			week := w.GetWeek().UTC()
+			if !inWeekRange(week, from, to) || (w.GetAdditions() == 0 && w.GetDeletions() == 0 && w.GetCommits() == 0) {
+				continue
+			}
+			var cs ContributorStats
+			cs.DocumentType = CONTRIBUTORSTATS
+			cs.RepoType = GITHUB
+			cs.RepoName = g.RepoName
+			cs.RepoURL = g.RepoURL
+			cs.CreatedAt = week
+			cs.Contributor.ID = strconv.FormatInt(s.Author.GetID(), 10)
+			cs.Contributor.User = s.Author.GetLogin()
+			cs.Additions = w.GetAdditions()
+			cs.Deletions = w.GetDeletions()
+			cs.Commits = w.GetCommits()
+			cs.TotalCommits = s.GetTotal()
+			cs.Time = g.CurrentTimeInMS
+			statsDocuments = append(statsDocuments, cs)
+		}
+	}
+	b, _ := json.Marshal(statsDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
+
+// ProcessCodeFrequency prepares code frequency output documents for weeks starting after from and upto to
+func (g GithubProcessor) ProcessCodeFrequency(data []byte, from time.Time, to time.Time, tags map[string]string) ([]interface{}, error) {
+	var stats []github.WeeklyStats
+	statsDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &stats)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling code frequency for repository %v", err, g.RepoName)
+		return statsDocuments, err
+	}
+	for _, w := range stats {
+		week := w.GetWeek().UTC()
+		if !inWeekRange(week, from, to) {
+			continue
+		}
+		var cf CodeFrequency
+		cf.DocumentType = CODEFREQUENCY
+		cf.RepoType = GITHUB
+		cf.RepoName = g.RepoName
+		cf.RepoURL = g.RepoURL
+		cf.CreatedAt = week
+		cf.Additions = w.GetAdditions()
+		// github reports deletions as negative numbers for code frequency
+		cf.Deletions = w.GetDeletions()
+		if cf.Deletions < 0 {
+			cf.Deletions = -cf.Deletions
+		}
+		cf.Time = g.CurrentTimeInMS
+		statsDocuments = append(statsDocuments, cf)
+	}
+	b, _ := json.Marshal(statsDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
+
+// ProcessCommitActivity prepares commit activity output documents for weeks starting after from and upto to
+func (g GithubProcessor) ProcessCommitActivity(data []byte, from time.Time, to time.Time, tags map[string]string) ([]interface{}, error) {
+	var stats []github.WeeklyCommitActivity
+	statsDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &stats)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling commit activity for repository %v", err, g.RepoName)
+		return statsDocuments, err
+	}
+	for _, w := range stats {
+		week := w.GetWeek().UTC()
+		if !inWeekRange(week, from, to) {
+			continue
+		}
+		var ca CommitActivity
+		ca.DocumentType = COMMITACTIVITY
+		ca.RepoType = GITHUB
+		ca.RepoName = g.RepoName
+		ca.RepoURL = g.RepoURL
+		ca.CreatedAt = week
+		ca.Total = w.GetTotal()
+		ca.Days = w.Days
+		ca.Time = g.CurrentTimeInMS
+		statsDocuments = append(statsDocuments, ca)
+	}
+	b, _ := json.Marshal(statsDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
+
+// inWeekRange checks if week starts after from and not later than to
+func inWeekRange(week time.Time, from time.Time, to time.Time) bool {
+	return week.After(from) && !week.After(to)
+}
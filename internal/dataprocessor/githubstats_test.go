@@ -0,0 +1,80 @@
+package dataprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestGithubProcessor_ProcessStats(t *testing.T) {
+	g := GithubProcessor{RepoName: "testRepo", MetricFormator: &metricformator.MetricFormator{}}
+	from := time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 10, 16, 0, 0, 0, 0, time.UTC)
+	// weeks of 2022-10-02 , 2022-10-09 , 2022-10-16 and 2022-10-23 as unix seconds
+	contributors := `[{"author":{"id":1,"login":"dev1"},"total":12,"weeks":[
+		{"w":1664668800,"a":100,"d":1,"c":5},{"w":1665273600,"a":10,"d":2,"c":3},
+		{"w":1665878400,"a":0,"d":0,"c":0},{"w":1666483200,"a":7,"d":7,"c":1}]}]`
+	// code frequency as marshalled by gitprovider , github reports deletions as negative numbers
+	codeFrequency := `[{"w":1664668800,"a":100,"d":-1},{"w":1665273600,"a":10,"d":-2},{"w":1665878400,"a":5,"d":-5},{"w":1666483200,"a":7,"d":-7}]`
+	commitActivity := `[{"days":[0,1,2,0,0,0,0],"total":3,"week":1665273600},{"days":[1,0,0,0,0,0,0],"total":1,"week":1665878400},
+		{"days":[0,0,0,0,0,0,4],"total":4,"week":1666483200}]`
+	tests := []struct {
+		name    string
+		process func([]byte, time.Time, time.Time, map[string]string) ([]interface{}, error)
+		data    string
+		want    []map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:    "contributor stats of weeks with contributions",
+			process: g.ProcessContributorStats,
+			data:    contributors,
+			want: []map[string]interface{}{{"document_type": CONTRIBUTORSTATS, "created_at": "2022-10-09T00:00:00Z", "additions": 10.0,
+				"deletions": 2.0, "commits": 3.0, "total_commits": 12.0, "team": "core"}},
+		},
+		{
+			name:    "code frequency with positive deletions",
+			process: g.ProcessCodeFrequency,
+			data:    codeFrequency,
+			want: []map[string]interface{}{
+				{"document_type": CODEFREQUENCY, "created_at": "2022-10-09T00:00:00Z", "additions": 10.0, "deletions": 2.0, "team": "core"},
+				{"document_type": CODEFREQUENCY, "created_at": "2022-10-16T00:00:00Z", "additions": 5.0, "deletions": 5.0, "team": "core"},
+			},
+		},
+		{
+			name:    "commit activity",
+			process: g.ProcessCommitActivity,
+			data:    commitActivity,
+			want: []map[string]interface{}{
+				{"document_type": COMMITACTIVITY, "created_at": "2022-10-09T00:00:00Z", "total": 3.0, "team": "core"},
+				{"document_type": COMMITACTIVITY, "created_at": "2022-10-16T00:00:00Z", "total": 1.0, "team": "core"},
+			},
+		},
+		{
+			name:    "incorrect data",
+			process: g.ProcessCommitActivity,
+			data:    `{"message":"Bad credentials"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.process([]byte(tt.data), from, to, map[string]string{"team": "core"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("process() = %v, want %v", got, tt.want)
+			}
+			for i, want := range tt.want {
+				doc := got[i].(map[string]interface{})
+				for k, v := range want {
+					if doc[k] != v {
+						t.Errorf("process() document %v has %v = %v, want %v", i, k, doc[k], v)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package dataprocessor
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+const (
+	WORKFLOWRUN = "workflow_run"
+	ITEMCOUNT   = "item_count"
+
+	// kinds of counted items
+	ItemPullRequest = "pull_request"
+	ItemIssue       = "issue"
+)
+
+// WorkflowRun represents a completed workflow run of github actions
+type WorkflowRun struct {
+	// DocumentType is "workflow_run"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// RunID is the workflow run id , same for all attempts of the run
+	RunID string `json:"run_id"`
+
+	// RunAttempt is the attempt of the run , starting at 1
+	RunAttempt int `json:"run_attempt"`
+
+	// WorkflowID is the id of the workflow
+	WorkflowID string `json:"workflow_id"`
+
+	// Name represents the name of the workflow
+	Name string `json:"name"`
+
+	// Event represents the event which triggered the run , for ex. push or pull_request
+	Event string `json:"event"`
+
+	// Branch represents the head branch of the run
+	Branch string `json:"branch"`
+
+	// HeadSha represents the commit sha the run is for
+	HeadSha string `json:"head_sha"`
+
+	// Conclusion is success , failure , cancelled , skipped , timed_out or action_required
+	Conclusion string `json:"conclusion"`
+
+	// CreatedAt represents at what time this run is created
+	CreatedAt time.Time `json:"created_at"`
+
+	// StartedAt represents at what time this attempt of the run is started
+	StartedAt time.Time `json:"started_at"`
+
+	// CompletedAt represents at what time this run is completed
+	CompletedAt time.Time `json:"completed_at"`
+
+	// DurationSeconds is the time from start to completion of the attempt
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// TriggeredBy shows the user who triggered the run
+	TriggeredBy User `json:"triggered_by"`
+
+	// URL is api url to workflow run
+	URL string `json:"url"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// ItemCount represents the number of pull requests or issues of a repository in a state at a time
+type ItemCount struct {
+	// DocumentType is "item_count"
+	DocumentType string `json:"document_type"`
+
+	// RepoType is "github" , represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the items are counted
+	CreatedAt time.Time `json:"created_at"`
+
+	// ItemType is pull_request or issue
+	ItemType string `json:"item_type"`
+
+	// State is open or closed
+	State string `json:"state"`
+
+	// Branch is the base branch of pull requests , empty for issues
+	Branch string `json:"branch"`
+
+	// Count is the number of items
+	Count int `json:"count"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// ProcessWorkflowRuns prepares workflow run output documents of runs completed after from and until to
+func (g GithubProcessor) ProcessWorkflowRuns(data []byte, from time.Time, to time.Time, tags map[string]string) ([]interface{}, error) {
+	var runs []github.WorkflowRun
+	runDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(data, &runs)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling workflow runs for repository %v", err, g.RepoName)
+		return runDocuments, err
+	}
+	for _, r := range runs {
+		// updated time of a completed run is its completion time
+		completedAt := r.GetUpdatedAt().Time
+		if !completedAt.After(from) || completedAt.After(to) {
+			continue
+		}
+		var run WorkflowRun
+		run.DocumentType = WORKFLOWRUN
+		run.RepoType = GITHUB
+		run.RepoName = g.RepoName
+		run.RepoURL = g.RepoURL
+		run.RunID = strconv.FormatInt(r.GetID(), 10)
+		run.RunAttempt = r.GetRunAttempt()
+		run.WorkflowID = strconv.FormatInt(r.GetWorkflowID(), 10)
+		run.Name = r.GetName()
+		run.Event = r.GetEvent()
+		run.Branch = r.GetHeadBranch()
+		run.HeadSha = r.GetHeadSHA()
+		run.Conclusion = r.GetConclusion()
+		run.CreatedAt = r.GetCreatedAt().Local()
+		run.StartedAt = r.GetRunStartedAt().Local()
+		run.CompletedAt = completedAt.Local()
+		if !run.StartedAt.IsZero() && run.CompletedAt.After(run.StartedAt) {
+			run.DurationSeconds = run.CompletedAt.Sub(run.StartedAt).Seconds()
+		}
+		run.TriggeredBy.ID = strconv.FormatInt(r.Actor.GetID(), 10)
+		run.TriggeredBy.User = r.Actor.GetLogin()
+		run.URL = r.GetURL()
+		run.Time = g.CurrentTimeInMS
+		runDocuments = append(runDocuments, run)
+	}
+	b, _ := json.Marshal(runDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
+
+// ProcessItemCounts prepares item count output documents of open pull requests by base branch and of issues by state ,
+// takes all open pull requests and the number of issues with state as key
+func (g GithubProcessor) ProcessItemCounts(openPullRequests []byte, issueCounts map[string]int, at time.Time, tags map[string]string) ([]interface{}, error) {
+	var pullRequests []github.PullRequest
+	countDocuments := make([]interface{}, 0)
+	err := json.Unmarshal(openPullRequests, &pullRequests)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling open pull requests for repository %v", err, g.RepoName)
+		return countDocuments, err
+	}
+	count := func(itemType string, state string, branch string, n int) ItemCount {
+		return ItemCount{DocumentType: ITEMCOUNT, RepoType: GITHUB, RepoName: g.RepoName, RepoURL: g.RepoURL, CreatedAt: at.Local(),
+			ItemType: itemType, State: state, Branch: branch, Count: n, Time: g.CurrentTimeInMS}
+	}
+	byBranch := make(map[string]int)
+	for _, pr := range pullRequests {
+		byBranch[pr.GetBase().GetRef()]++
+	}
+	branches := make([]string, 0, len(byBranch))
+	for branch := range byBranch {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	for _, branch := range branches {
+		countDocuments = append(countDocuments, count(ItemPullRequest, "open", branch, byBranch[branch]))
+	}
+	states := make([]string, 0, len(issueCounts))
+	for state := range issueCounts {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		countDocuments = append(countDocuments, count(ItemIssue, state, "", issueCounts[state]))
+	}
+	b, _ := json.Marshal(countDocuments)
+	b = g.MetricFormator.CustomizeMetrics(b)
+	finalDocs := AddTags(b, tags)
+	return finalDocs, nil
+}
@@ -0,0 +1,55 @@
+package dataprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestGithubProcessor_ProcessWorkflowRunsAndItemCounts(t *testing.T) {
+	g := GithubProcessor{RepoName: "testRepo", MetricFormator: &metricformator.MetricFormator{}}
+	from := time.Date(2022, 10, 10, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 10, 10, 11, 0, 0, 0, time.UTC)
+	// runs completed before , within and after the range
+	runs := `[{"id":1,"conclusion":"success","run_started_at":"2022-10-10T09:00:00Z","updated_at":"2022-10-10T09:30:00Z"},
+		{"id":2,"conclusion":"failure","run_started_at":"2022-10-10T10:00:00Z","updated_at":"2022-10-10T10:01:35Z"},
+		{"id":3,"conclusion":"success","run_started_at":"2022-10-10T10:50:00Z","updated_at":"2022-10-10T11:10:00Z"}]`
+	gotRuns, err := g.ProcessWorkflowRuns([]byte(runs), from, to, map[string]string{"team": "core"})
+	if err != nil {
+		t.Fatalf("ProcessWorkflowRuns() error = %v", err)
+	}
+	if len(gotRuns) != 1 {
+		t.Fatalf("ProcessWorkflowRuns() = %v, want only run completed within range", gotRuns)
+	}
+	run := gotRuns[0].(map[string]interface{})
+	if run["run_id"] != "2" || run["duration_seconds"] != 95.0 || run["team"] != "core" {
+		t.Errorf("ProcessWorkflowRuns() = %v, want run 2 of 95 seconds", run)
+	}
+
+	pullRequests := `[{"number":1,"base":{"ref":"main"}},{"number":2,"base":{"ref":"dev"}},{"number":3,"base":{"ref":"main"}}]`
+	gotCounts, err := g.ProcessItemCounts([]byte(pullRequests), map[string]int{"open": 4, "closed": 9}, to, nil)
+	if err != nil {
+		t.Fatalf("ProcessItemCounts() error = %v", err)
+	}
+	want := []map[string]interface{}{
+		{"item_type": ItemPullRequest, "state": "open", "branch": "dev", "count": 1.0},
+		{"item_type": ItemPullRequest, "state": "open", "branch": "main", "count": 2.0},
+		{"item_type": ItemIssue, "state": "closed", "branch": "", "count": 9.0},
+		{"item_type": ItemIssue, "state": "open", "branch": "", "count": 4.0},
+	}
+	if len(gotCounts) != len(want) {
+		t.Fatalf("ProcessItemCounts() = %v, want %v", gotCounts, want)
+	}
+	for i, w := range want {
+		doc := gotCounts[i].(map[string]interface{})
+		for k, v := range w {
+			if doc[k] != v {
+				t.Errorf("ProcessItemCounts() document %v has %v = %v, want %v", i, k, doc[k], v)
+			}
+		}
+	}
+	if _, err = g.ProcessItemCounts([]byte(`{"message":"Bad credentials"}`), nil, to, nil); err == nil {
+		t.Errorf("ProcessItemCounts() error = nil, want error for incorrect data")
+	}
+}
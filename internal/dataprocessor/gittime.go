@@ -0,0 +1,44 @@
+package dataprocessor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitTime returns the time of the author or committer header of a raw commit object , in the timezone recorded by git.
+// Header lines are of the form "committer Name <email> 1665400000 +0530".
+func gitTime(payload string, header string) (time.Time, bool) {
+	for _, line := range strings.Split(payload, "\n") {
+		// headers end at the first empty line
+		if line == "" {
+			break
+		}
+		if !strings.HasPrefix(line, header+" ") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return time.Time{}, false
+		}
+		seconds, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		zone := fields[len(fields)-1]
+		if len(zone) != 5 || (zone[0] != '+' && zone[0] != '-') {
+			return time.Time{}, false
+		}
+		hours, errHours := strconv.Atoi(zone[1:3])
+		minutes, errMinutes := strconv.Atoi(zone[3:])
+		if errHours != nil || errMinutes != nil {
+			return time.Time{}, false
+		}
+		offset := hours*3600 + minutes*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		return time.Unix(seconds, 0).In(time.FixedZone("", offset)), true
+	}
+	return time.Time{}, false
+}
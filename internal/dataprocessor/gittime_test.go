@@ -0,0 +1,34 @@
+package dataprocessor
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_gitTime(t *testing.T) {
+	payload := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\nauthor Dev One <dev@example.com> 1665400000 -0700\ncommitter Dev One <dev@example.com> 1665403600 +0530\n\ncommitter in message 1 +0000\n"
+	tests := []struct {
+		name       string
+		payload    string
+		header     string
+		wantOK     bool
+		wantUnix   int64
+		wantOffset string
+	}{
+		{"committer", payload, "committer", true, 1665403600, "+05:30"},
+		{"author", payload, "author", true, 1665400000, "-07:00"},
+		{"unsigned", "", "committer", false, 0, ""},
+		{"bad zone", "committer Dev <dev@example.com> 1665403600 IST\n", "committer", false, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := gitTime(tt.payload, tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("gitTime() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (got.Unix() != tt.wantUnix || got.Format("-07:00") != tt.wantOffset) {
+				t.Errorf("gitTime() = %v, want %v in %v", got.Format(time.RFC3339), tt.wantUnix, tt.wantOffset)
+			}
+		})
+	}
+}
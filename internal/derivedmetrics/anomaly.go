@@ -0,0 +1,400 @@
+package derivedmetrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+const (
+	ACTIVITYANOMALY = "activity_anomaly"
+
+	// kinds of anomaly
+	AnomalyCommitVolume      = "commit_volume_spike"
+	AnomalyLargeCommit       = "large_commit"
+	AnomalyOffHours          = "off_hours_activity"
+	AnomalyMassIssueClosure  = "mass_issue_closure"
+	AnomalyFirstPushToBranch = "first_push_to_protected_branch"
+
+	// minStddev is the least standard deviation of numeric baselines , so that flat baselines do not flag
+	// every small change. It is one commit or closure for counts and a doubling for commit sizes.
+	minStddev = 1.0
+)
+
+// Baseline represents the rolling mean and variance of a numeric observation. Observations are averaged
+// equally until the baseline has baseline samples , after which older observations fade out exponentially.
+type Baseline struct {
+	// Samples is the number of observations added
+	Samples int
+
+	// Mean of observations
+	Mean float64
+
+	// Variance of observations
+	Variance float64
+}
+
+// add adds an observation to the baseline
+func (b *Baseline) add(x float64, baselineSamples int) {
+	b.Samples++
+	alpha := 1 / math.Min(float64(b.Samples), float64(baselineSamples))
+	delta := x - b.Mean
+	b.Mean += alpha * delta
+	b.Variance = (1 - alpha) * (b.Variance + alpha*delta*delta)
+}
+
+// stddev returns the standard deviation of observations , at least minStddev
+func (b Baseline) stddev() float64 {
+	return math.Max(math.Sqrt(b.Variance), minStddev)
+}
+
+// score returns how unusually high x is for the baseline , as one minus the Cantelli bound of the probability
+// of an observation at least as high as x. It is 0 for observations not above the mean.
+func (b Baseline) score(x float64) float64 {
+	z := (x - b.Mean) / b.stddev()
+	if z <= 0 {
+		return 0
+	}
+	return z * z / (1 + z*z)
+}
+
+// AnomalyBaselines represents the baselines of repository activity , saved between runs
+type AnomalyBaselines struct {
+	// CommitVolume is the baseline of commits to monitored branches per run
+	CommitVolume Baseline
+
+	// CommitSize is the baseline of log2 of one more than the lines changed by a commit
+	CommitSize Baseline
+
+	// IssueClosures is the baseline of issues closed per run
+	IssueClosures Baseline
+
+	// AuthorHours is the weight of commits of each author by hour of day in UTC with canonical identity as key
+	AuthorHours map[string][]float64
+
+	// BranchPushers is the weight of pushes to each protected branch by pusher login with branch as key
+	BranchPushers map[string]map[string]float64
+
+	// LastPushAt represents the time of the latest push added to baselines
+	LastPushAt time.Time
+}
+
+// ActivityAnomaly represents an unusual activity in a repository compared to its baseline
+type ActivityAnomaly struct {
+	// DocumentType is "activity_anomaly"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the anomaly was detected
+	CreatedAt time.Time `json:"created_at"`
+
+	// Kind is the kind of anomaly , for ex. commit_volume_spike , large_commit
+	Kind string `json:"kind"`
+
+	// Score is how unusual the activity is , between 0 and 1
+	Score float64 `json:"score"`
+
+	// Explanation describes the activity and its baseline
+	Explanation string `json:"explanation"`
+
+	// Value is the observed value , commits , lines changed , closures or share of author commits at the hour
+	Value float64 `json:"value"`
+
+	// BaselineMean is the mean of the baseline
+	BaselineMean float64 `json:"baseline_mean"`
+
+	// BaselineStddev is the standard deviation of the baseline
+	BaselineStddev float64 `json:"baseline_stddev"`
+
+	// BaselineSamples is the number of observations in the baseline
+	BaselineSamples int `json:"baseline_samples"`
+
+	// Author is the canonical identity of author or login of pusher , empty for repository wide anomalies
+	Author string `json:"author"`
+
+	// Branch is the protected branch pushed to , only for first pushes
+	Branch string `json:"branch"`
+
+	// Sha is the commit sha for commit anomalies and the pushed head for first pushes
+	Sha string `json:"sha"`
+
+	// IssueNos are the closed issues for mass issue closures
+	IssueNos []string `json:"issue_nos"`
+
+	// OccurredAt represents at what time the activity happened , the start of run period for volumes
+	OccurredAt time.Time `json:"occurred_at"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// AnomalyDetector detects unusual activity in a repository against rolling baselines
+type AnomalyDetector struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+
+	cfg       input.AnomalyConfig
+	protected map[string]bool
+	resolver  *enrichment.IdentityResolver
+}
+
+// NewAnomalyDetector returns a new anomaly detector for a repository , resolver merges aliases of authors and detects bots
+func NewAnomalyDetector(repoName string, repoURL string, cfg input.AnomalyConfig, resolver *enrichment.IdentityResolver) *AnomalyDetector {
+	ad := new(AnomalyDetector)
+	ad.RepoName = repoName
+	ad.RepoURL = repoURL
+	ad.MetricFormator = metricformator.NewMetricFormator()
+	ad.cfg = cfg
+	ad.protected = make(map[string]bool)
+	for _, br := range cfg.ProtectedBranches {
+		ad.protected[br] = true
+	}
+	ad.resolver = resolver
+	return ad
+}
+
+// Detect prepares activity anomaly output documents for commits to monitored branches , issues closed and pushes
+// made since the last run at lastRun , and returns baselines updated with them. Each observation is scored against
+// the baselines before it is added , so anomalies are detected once baselines have min samples.
+func (ad *AnomalyDetector) Detect(commits []CommitFiles, closedIssues []dataprocessor.Issue, pushes []dataprocessor.Push, baselines AnomalyBaselines, lastRun time.Time, now time.Time, tags map[string]string) ([]interface{}, AnomalyBaselines) {
+	baselines = baselines.copy()
+	anomalies := make([]ActivityAnomaly, 0)
+
+	// repository wide volumes are observed once per run
+	volume := float64(len(commits))
+	if a, ok := ad.numeric(AnomalyCommitVolume, baselines.CommitVolume, volume); ok {
+		a.Explanation = fmt.Sprintf("%d commits to monitored branches since %v , usually %.1f ± %.1f", len(commits), lastRun.Format(time.RFC3339), a.BaselineMean, a.BaselineStddev)
+		a.OccurredAt = lastRun
+		anomalies = append(anomalies, a)
+	}
+	baselines.CommitVolume.add(volume, ad.cfg.BaselineSamples)
+
+	issueNos := make([]string, 0, len(closedIssues))
+	for _, issue := range closedIssues {
+		issueNos = append(issueNos, issue.IssueNo)
+	}
+	closures := float64(len(closedIssues))
+	if a, ok := ad.numeric(AnomalyMassIssueClosure, baselines.IssueClosures, closures); ok {
+		a.Explanation = fmt.Sprintf("%d issues closed since %v , usually %.1f ± %.1f", len(closedIssues), lastRun.Format(time.RFC3339), a.BaselineMean, a.BaselineStddev)
+		a.IssueNos = issueNos
+		a.OccurredAt = lastRun
+		anomalies = append(anomalies, a)
+	}
+	baselines.IssueClosures.add(closures, ad.cfg.BaselineSamples)
+
+	// commits are observed in order , merge commits and commits by bots are not of any author
+	sorted := append([]CommitFiles(nil), commits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Commit.CreatedAt.Before(sorted[j].Commit.CreatedAt) })
+	for _, c := range sorted {
+		id := ad.resolver.Resolve(c.Commit.Author)
+		if len(c.Commit.Parents) > 1 || ad.resolver.IsBot(c.Commit.Author, id) {
+			continue
+		}
+		lines := 0
+		for _, f := range c.Files {
+			lines += f.Additions + f.Deletions
+		}
+		size := math.Log2(float64(lines) + 1)
+		if a, ok := ad.numeric(AnomalyLargeCommit, baselines.CommitSize, size); ok {
+			a.Value = float64(lines)
+			a.BaselineMean = math.Exp2(a.BaselineMean) - 1
+			a.Explanation = fmt.Sprintf("commit %v by %v changed %d lines , usually about %.0f lines", shortSha(c.Commit.Sha), id.Canonical, lines, a.BaselineMean)
+			a.Author = id.Canonical
+			a.Sha = c.Commit.Sha
+			a.OccurredAt = c.Commit.CreatedAt
+			anomalies = append(anomalies, a)
+		}
+		baselines.CommitSize.add(size, ad.cfg.BaselineSamples)
+
+		hour := c.Commit.CreatedAt.UTC().Hour()
+		hours := baselines.AuthorHours[id.Canonical]
+		if hours == nil {
+			hours = make([]float64, 24)
+		}
+		if a, ok := ad.offHours(hours, hour); ok {
+			a.Explanation = fmt.Sprintf("commit %v by %v at %02d:00 UTC , %.1f%% of their %.0f recent commits are within an hour of it", shortSha(c.Commit.Sha), id.Canonical, hour, a.Value*100, a.BaselineMean)
+			a.Author = id.Canonical
+			a.Sha = c.Commit.Sha
+			a.OccurredAt = c.Commit.CreatedAt
+			anomalies = append(anomalies, a)
+		}
+		baselines.AuthorHours[id.Canonical] = addWeight(hours, hour, ad.cfg.BaselineSamples)
+	}
+
+	sortedPushes := append([]dataprocessor.Push(nil), pushes...)
+	sort.SliceStable(sortedPushes, func(i, j int) bool { return sortedPushes[i].CreatedAt.Before(sortedPushes[j].CreatedAt) })
+	for _, p := range sortedPushes {
+		if !p.CreatedAt.After(baselines.LastPushAt) {
+			continue
+		}
+		baselines.LastPushAt = p.CreatedAt
+		branch := strings.TrimPrefix(p.Ref, branchRefPrefix)
+		if !ad.protected[branch] || p.Pusher.User == "" {
+			continue
+		}
+		pushers := baselines.BranchPushers[branch]
+		if a, ok := ad.firstPush(pushers, p.Pusher.User); ok {
+			a.Explanation = fmt.Sprintf("first push to %v by %v , %d users made its %.0f recent pushes", branch, p.Pusher.User, len(pushers), a.BaselineMean)
+			a.Author = p.Pusher.User
+			a.Branch = branch
+			a.Sha = p.Head
+			a.OccurredAt = p.CreatedAt
+			anomalies = append(anomalies, a)
+		}
+		baselines.BranchPushers[branch] = addPusher(pushers, p.Pusher.User, ad.cfg.BaselineSamples)
+	}
+	return formatDocuments(ad.MetricFormator, ad.documents(anomalies, now), tags), baselines
+}
+
+// numeric returns an anomaly of kind if value scores at least min score against baseline
+func (ad *AnomalyDetector) numeric(kind string, b Baseline, value float64) (ActivityAnomaly, bool) {
+	var a ActivityAnomaly
+	if b.Samples < ad.cfg.MinSamples {
+		return a, false
+	}
+	a.Kind = kind
+	a.Score = b.score(value)
+	a.Value = value
+	a.BaselineMean = b.Mean
+	a.BaselineStddev = b.stddev()
+	a.BaselineSamples = b.Samples
+	return a, a.Score >= ad.cfg.MinScore
+}
+
+// offHours returns an anomaly if few commits of author are within an hour of hour. Value is that share of commits ,
+// baseline mean is the weight of recent commits of author and score is one minus the share.
+func (ad *AnomalyDetector) offHours(hours []float64, hour int) (ActivityAnomaly, bool) {
+	var a ActivityAnomaly
+	total := 0.0
+	for _, w := range hours {
+		total += w
+	}
+	if total < float64(ad.cfg.MinSamples) {
+		return a, false
+	}
+	near := hours[(hour+23)%24] + hours[hour] + hours[(hour+1)%24]
+	a.Kind = AnomalyOffHours
+	a.Value = near / total
+	a.Score = 1 - a.Value
+	a.BaselineMean = total
+	a.BaselineSamples = int(math.Round(total))
+	return a, a.Score >= ad.cfg.MinScore
+}
+
+// firstPush returns an anomaly if pusher has not pushed to the branch before. Score is one minus the chance of a
+// new pusher estimated as the number of pushers per push , so first pushes to branches pushed by few users score high.
+func (ad *AnomalyDetector) firstPush(pushers map[string]float64, pusher string) (ActivityAnomaly, bool) {
+	var a ActivityAnomaly
+	if _, ok := pushers[pusher]; ok {
+		return a, false
+	}
+	total := 0.0
+	for _, w := range pushers {
+		total += w
+	}
+	if total < float64(ad.cfg.MinSamples) {
+		return a, false
+	}
+	a.Kind = AnomalyFirstPushToBranch
+	a.Value = 1
+	a.Score = 1 - float64(len(pushers))/(total+1)
+	a.BaselineMean = total
+	a.BaselineSamples = int(math.Round(total))
+	return a, a.Score >= ad.cfg.MinScore
+}
+
+// documents fills the common fields of anomalies
+func (ad *AnomalyDetector) documents(anomalies []ActivityAnomaly, now time.Time) []ActivityAnomaly {
+	for i := range anomalies {
+		anomalies[i].DocumentType = ACTIVITYANOMALY
+		anomalies[i].RepoType = dataprocessor.GITHUB
+		anomalies[i].RepoName = ad.RepoName
+		anomalies[i].RepoURL = ad.RepoURL
+		anomalies[i].CreatedAt = now
+		anomalies[i].Score = math.Round(anomalies[i].Score*1000) / 1000
+		anomalies[i].Time = now.UnixNano() / 1000000
+	}
+	return anomalies
+}
+
+// copy returns a copy of baselines whose maps can be modified without changing the saved baselines
+func (b AnomalyBaselines) copy() AnomalyBaselines {
+	authorHours := make(map[string][]float64, len(b.AuthorHours))
+	for k, v := range b.AuthorHours {
+		authorHours[k] = v
+	}
+	branchPushers := make(map[string]map[string]float64, len(b.BranchPushers))
+	for k, v := range b.BranchPushers {
+		branchPushers[k] = v
+	}
+	b.AuthorHours = authorHours
+	b.BranchPushers = branchPushers
+	return b
+}
+
+// addWeight returns a copy of hours with a commit added at hour , fading out older commits once there are baseline samples
+func addWeight(hours []float64, hour int, baselineSamples int) []float64 {
+	total := 0.0
+	for _, w := range hours {
+		total += w
+	}
+	fade := fadeFactor(total, baselineSamples)
+	added := make([]float64, len(hours))
+	for i, w := range hours {
+		added[i] = w * fade
+	}
+	added[hour]++
+	return added
+}
+
+// addPusher returns a copy of pushers with a push added by pusher , fading out older pushes once there are baseline samples
+func addPusher(pushers map[string]float64, pusher string, baselineSamples int) map[string]float64 {
+	total := 0.0
+	for _, w := range pushers {
+		total += w
+	}
+	fade := fadeFactor(total, baselineSamples)
+	added := make(map[string]float64, len(pushers)+1)
+	for k, w := range pushers {
+		added[k] = w * fade
+	}
+	added[pusher]++
+	return added
+}
+
+// fadeFactor returns the factor by which weights are faded out before adding one , so that the total stays at baseline samples
+func fadeFactor(total float64, baselineSamples int) float64 {
+	if total < float64(baselineSamples) {
+		return 1
+	}
+	return (float64(baselineSamples) - 1) / total
+}
+
+// shortSha returns the first seven characters of sha
+func shortSha(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
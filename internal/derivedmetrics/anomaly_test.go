@@ -0,0 +1,106 @@
+package derivedmetrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestBaseline_add(t *testing.T) {
+	tests := []struct {
+		name            string
+		observations    []float64
+		baselineSamples int
+		wantMean        float64
+		wantVariance    float64
+	}{
+		{"single", []float64{4}, 10, 4, 0},
+		{"cumulative", []float64{2, 4, 6, 8}, 10, 5, 5},
+		{"fading", []float64{0, 0, 4}, 2, 2, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Baseline
+			for _, x := range tt.observations {
+				b.add(x, tt.baselineSamples)
+			}
+			if math.Abs(b.Mean-tt.wantMean) > 1e-9 || math.Abs(b.Variance-tt.wantVariance) > 1e-9 {
+				t.Errorf("add() mean = %v variance = %v, want %v , %v", b.Mean, b.Variance, tt.wantMean, tt.wantVariance)
+			}
+		})
+	}
+}
+
+func TestAnomalyDetector_Detect(t *testing.T) {
+	cfg := input.AnomalyConfig{MinScore: 0.9, MinSamples: 10, BaselineSamples: 100, ProtectedBranches: []string{"main"}}
+	ad := NewAnomalyDetector("testRepo", "", cfg, enrichment.NewIdentityResolver(input.IdentityConfig{}))
+	ad.MetricFormator = &metricformator.MetricFormator{}
+	commit := func(sha string, email string, at time.Time, lines int) CommitFiles {
+		c := dataprocessor.Commit{Sha: sha, Author: dataprocessor.User{User: email, Email: email}, CreatedAt: at}
+		return CommitFiles{Commit: c, Files: []dataprocessor.FileChange{{Path: "main.go", Additions: lines}}}
+	}
+	push := func(login string, at time.Time) dataprocessor.Push {
+		return dataprocessor.Push{Ref: "refs/heads/main", Head: "h" + login, Pusher: dataprocessor.User{User: login}, CreatedAt: at}
+	}
+
+	// twelve days of usual activity during office hours
+	var baselines AnomalyBaselines
+	start := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day < 12; day++ {
+		runStart := start.AddDate(0, 0, day)
+		var commits []CommitFiles
+		for i := 0; i < 2+day%3; i++ {
+			commits = append(commits, commit(fmt.Sprintf("%d-%d", day, i), "dev@example.com", runStart.Add(time.Duration(10+i)*time.Hour), 20+10*i))
+		}
+		issues := make([]dataprocessor.Issue, day%2)
+		pushes := []dataprocessor.Push{push("dev", runStart.Add(12*time.Hour)), push("lead", runStart.Add(13*time.Hour))}
+		docs, updated := ad.Detect(commits, issues, pushes, baselines, runStart, runStart.AddDate(0, 0, 1), nil)
+		if len(docs) != 0 {
+			t.Fatalf("Detect() on day %v returned %v anomalies for usual activity", day, len(docs))
+		}
+		baselines = updated
+	}
+	if baselines.CommitVolume.Samples != 12 || len(baselines.AuthorHours) != 1 || len(baselines.BranchPushers["main"]) != 2 {
+		t.Fatalf("Detect() baselines = %+v", baselines)
+	}
+
+	runStart := start.AddDate(0, 0, 12)
+	var commits []CommitFiles
+	for i := 0; i < 30; i++ {
+		commits = append(commits, commit(fmt.Sprintf("spike-%d", i), "dev@example.com", runStart.Add(11*time.Hour), 20))
+	}
+	commits = append(commits, commit("large", "dev@example.com", runStart.Add(11*time.Hour), 5000))
+	commits = append(commits, commit("night", "dev@example.com", runStart.Add(3*time.Hour), 20))
+	issues := make([]dataprocessor.Issue, 40)
+	pushes := []dataprocessor.Push{push("dev", runStart.Add(12*time.Hour)), push("intruder", runStart.Add(14*time.Hour)), push("lead", runStart.AddDate(0, 0, -1))}
+	docs, _ := ad.Detect(commits, issues, pushes, baselines, runStart, runStart.AddDate(0, 0, 1), nil)
+	var anomalies []ActivityAnomaly
+	if err := dataprocessor.DecodeDocuments(docs, &anomalies); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	got := make([]string, 0, len(anomalies))
+	for _, a := range anomalies {
+		if a.Score < cfg.MinScore || a.Score > 1 || a.Explanation == "" {
+			t.Errorf("Detect() anomaly %v score = %v explanation = %q", a.Kind, a.Score, a.Explanation)
+		}
+		got = append(got, a.Kind+" "+a.Sha+a.Author)
+	}
+	sort.Strings(got)
+	want := []string{
+		AnomalyCommitVolume + " ",
+		AnomalyFirstPushToBranch + " hintruderintruder",
+		AnomalyLargeCommit + " largedev@example.com",
+		AnomalyMassIssueClosure + " ",
+		AnomalyOffHours + " nightdev@example.com",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Detect() = %v, want %v", got, want)
+	}
+}
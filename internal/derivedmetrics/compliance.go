@@ -0,0 +1,385 @@
+package derivedmetrics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/codeowners"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+const (
+	COMPLIANCEVIOLATION = "compliance_violation"
+
+	// kinds of change checked by compliance rules
+	ChangePullRequest  = "pull_request"
+	ChangeDirectCommit = "direct_commit"
+	ChangeBranchUpdate = "branch_update"
+
+	// review states as reported by github
+	reviewStateApproved         = "APPROVED"
+	reviewStateChangesRequested = "CHANGES_REQUESTED"
+	reviewStateDismissed        = "DISMISSED"
+)
+
+// failingConclusions are the check run conclusions and status states which fail a check
+var failingConclusions = map[string]bool{
+	"failure":         true,
+	"timed_out":       true,
+	"action_required": true,
+	"startup_failure": true,
+	"error":           true,
+}
+
+// ComplianceViolation represents a violation of a compliance rule by a change to a protected branch
+type ComplianceViolation struct {
+	// DocumentType is "compliance_violation"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the violation was detected
+	CreatedAt time.Time `json:"created_at"`
+
+	// RuleID is the id of violated rule
+	RuleID string `json:"rule_id"`
+
+	// ChangeType is pull_request , direct_commit or branch_update
+	ChangeType string `json:"change_type"`
+
+	// Branch is the protected branch which is changed
+	Branch string `json:"branch"`
+
+	// PullRequestNo represents pull request number , empty for other changes
+	PullRequestNo string `json:"pull_request_no"`
+
+	// Sha is the merge commit sha of pull request , sha of direct commit or new head of branch
+	Sha string `json:"sha"`
+
+	// URL is the api url to pull request or commit
+	URL string `json:"url"`
+
+	// CreatedBy shows the author of pull request or commit , empty for branch updates
+	CreatedBy dataprocessor.User `json:"created_by"`
+
+	// OccurredAt represents at what time the change was made , or detected for branch updates
+	OccurredAt time.Time `json:"occurred_at"`
+
+	// Evidence holds the rule specific details of violation
+	Evidence map[string]interface{} `json:"evidence"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// Change represents a merged pull request or a direct commit to a protected branch along with the data checked by rules
+type Change struct {
+	// PullRequest is the merged pull request , nil for direct commits
+	PullRequest *dataprocessor.PullRequest
+
+	// Commit is the direct commit , nil for pull requests
+	Commit *dataprocessor.Commit
+
+	// Branch is the protected branch which is changed
+	Branch string
+
+	// Reviews are the reviews of pull request
+	Reviews []dataprocessor.PullRequestReview
+
+	// Commits are the commits of pull request
+	Commits []dataprocessor.Commit
+
+	// Files are the paths of changed files
+	Files []string
+
+	// Checks are the checks of pull request head or direct commit
+	Checks []dataprocessor.CommitCheck
+}
+
+// checkEvidence represents a failing check in evidence
+type checkEvidence struct {
+	Name       string `json:"name"`
+	Source     string `json:"source"`
+	Conclusion string `json:"conclusion"`
+	URL        string `json:"url"`
+}
+
+// commitEvidence represents an unsigned commit in evidence
+type commitEvidence struct {
+	Sha    string `json:"sha"`
+	Reason string `json:"reason"`
+}
+
+// ComplianceEvaluator checks changes to protected branches of a repository against compliance rules
+type ComplianceEvaluator struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+
+	rules      map[string]bool
+	codeOwners *codeowners.CodeOwners
+	teams      *codeowners.Teams
+}
+
+// NewComplianceEvaluator returns a new compliance evaluator for a repository , codeOwners and teams are needed
+// for non_codeowner_approval rule which is skipped if the repository has no CODEOWNERS file
+func NewComplianceEvaluator(repoName string, repoURL string, cfg input.ComplianceConfig, codeOwners *codeowners.CodeOwners, teams *codeowners.Teams) *ComplianceEvaluator {
+	ce := new(ComplianceEvaluator)
+	ce.RepoName = repoName
+	ce.RepoURL = repoURL
+	ce.MetricFormator = metricformator.NewMetricFormator()
+	ce.rules = make(map[string]bool)
+	for _, r := range cfg.Rules {
+		ce.rules[r] = true
+	}
+	ce.codeOwners = codeOwners
+	ce.teams = teams
+	return ce
+}
+
+// Evaluate prepares compliance violation output documents for changes and branch updates detected at now
+func (ce *ComplianceEvaluator) Evaluate(changes []Change, updates []BranchUpdate, now time.Time, tags map[string]string) []interface{} {
+	violations := make([]ComplianceViolation, 0)
+	for _, c := range changes {
+		if c.PullRequest != nil {
+			violations = append(violations, ce.evaluatePullRequest(c, now)...)
+		} else if c.Commit != nil {
+			violations = append(violations, ce.evaluateDirectCommit(c, now)...)
+		}
+	}
+	for _, u := range updates {
+		if u.Rewritten && ce.rules[input.ComplianceForcePush] {
+			v := ce.newViolation(input.ComplianceForcePush, ChangeBranchUpdate, u.Branch, now)
+			v.Sha = u.Head
+			v.OccurredAt = now
+			v.Evidence = map[string]interface{}{
+				"previous_head": u.PreviousHead,
+				"head":          u.Head,
+			}
+			violations = append(violations, v)
+		}
+	}
+	return formatDocuments(ce.MetricFormator, violations, tags)
+}
+
+// evaluatePullRequest checks a merged pull request
+func (ce *ComplianceEvaluator) evaluatePullRequest(c Change, now time.Time) []ComplianceViolation {
+	var violations []ComplianceViolation
+	pr := c.PullRequest
+	newViolation := func(rule string) ComplianceViolation {
+		v := ce.newViolation(rule, ChangePullRequest, c.Branch, now)
+		v.PullRequestNo = pr.PullRequestNo
+		v.Sha = pr.MergeCommitSha
+		v.URL = pr.URL
+		v.CreatedBy = pr.CreatedBy
+		v.OccurredAt = pr.MergedAt
+		return v
+	}
+
+	approvers := approversAtMerge(c.Reviews, pr.MergedAt)
+	authoredBy := make(map[string][]string)
+	for _, commit := range c.Commits {
+		if commit.Author.ID != "" && commit.Author.ID != "0" {
+			authoredBy[commit.Author.ID] = append(authoredBy[commit.Author.ID], commit.Sha)
+		}
+	}
+	var independent []dataprocessor.User
+	for _, a := range approvers {
+		isAuthor := a.ID == pr.CreatedBy.ID
+		isSelf := isAuthor || len(authoredBy[a.ID]) > 0
+		if ce.rules[input.ComplianceSelfApproval] && isSelf {
+			v := newViolation(input.ComplianceSelfApproval)
+			v.Evidence = map[string]interface{}{
+				"approver":               a.User,
+				"is_pull_request_author": isAuthor,
+				"authored_commits":       append([]string{}, authoredBy[a.ID]...),
+			}
+			violations = append(violations, v)
+		}
+		// approvals by the author or by someone who pushed commits are not independent
+		if !isSelf {
+			independent = append(independent, a)
+		}
+	}
+	if ce.rules[input.ComplianceMissingApproval] && len(independent) == 0 {
+		v := newViolation(input.ComplianceMissingApproval)
+		v.Evidence = map[string]interface{}{
+			"review_count": len(c.Reviews),
+			"approvers":    logins(approvers),
+		}
+		violations = append(violations, v)
+	}
+	if ce.rules[input.ComplianceNonCodeOwnerApproval] && ce.codeOwners != nil && len(independent) > 0 {
+		if files := ce.filesWithoutOwnerApproval(c.Files, independent); len(files) > 0 {
+			v := newViolation(input.ComplianceNonCodeOwnerApproval)
+			v.Evidence = map[string]interface{}{
+				"files":     files,
+				"approvers": logins(independent),
+			}
+			violations = append(violations, v)
+		}
+	}
+	if v, ok := ce.unsignedCommits(c.Commits, newViolation); ok {
+		violations = append(violations, v)
+	}
+	if v, ok := ce.failingChecks(c.Checks, pr.RequestFromRepo.Sha, newViolation); ok {
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// evaluateDirectCommit checks a commit pushed to a protected branch without a pull request
+func (ce *ComplianceEvaluator) evaluateDirectCommit(c Change, now time.Time) []ComplianceViolation {
+	var violations []ComplianceViolation
+	commit := c.Commit
+	newViolation := func(rule string) ComplianceViolation {
+		v := ce.newViolation(rule, ChangeDirectCommit, c.Branch, now)
+		v.Sha = commit.Sha
+		v.URL = commit.CommitURL
+		v.CreatedBy = commit.Author
+		v.OccurredAt = commit.CreatedAt
+		return v
+	}
+	if ce.rules[input.ComplianceDirectCommit] {
+		v := newViolation(input.ComplianceDirectCommit)
+		v.Evidence = map[string]interface{}{
+			"subject":   commit.ParsedMessage.Subject,
+			"committer": commit.Committer.User,
+		}
+		violations = append(violations, v)
+	}
+	if v, ok := ce.unsignedCommits([]dataprocessor.Commit{*commit}, newViolation); ok {
+		violations = append(violations, v)
+	}
+	if v, ok := ce.failingChecks(c.Checks, commit.Sha, newViolation); ok {
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// unsignedCommits returns unsigned_commit violation if any commit is not verified
+func (ce *ComplianceEvaluator) unsignedCommits(commits []dataprocessor.Commit, newViolation func(string) ComplianceViolation) (ComplianceViolation, bool) {
+	var unsigned []commitEvidence
+	for _, commit := range commits {
+		if !commit.Verification.Verified {
+			unsigned = append(unsigned, commitEvidence{Sha: commit.Sha, Reason: commit.Verification.Reason})
+		}
+	}
+	if !ce.rules[input.ComplianceUnsignedCommit] || len(unsigned) == 0 {
+		return ComplianceViolation{}, false
+	}
+	v := newViolation(input.ComplianceUnsignedCommit)
+	v.Evidence = map[string]interface{}{
+		"commits": unsigned,
+	}
+	return v, true
+}
+
+// failingChecks returns failing_checks violation if any check of sha failed
+func (ce *ComplianceEvaluator) failingChecks(checks []dataprocessor.CommitCheck, sha string, newViolation func(string) ComplianceViolation) (ComplianceViolation, bool) {
+	var failing []checkEvidence
+	for _, check := range checks {
+		if failingConclusions[strings.ToLower(check.Conclusion)] {
+			failing = append(failing, checkEvidence{Name: check.Name, Source: check.Source, Conclusion: check.Conclusion, URL: check.URL})
+		}
+	}
+	if !ce.rules[input.ComplianceFailingChecks] || len(failing) == 0 {
+		return ComplianceViolation{}, false
+	}
+	v := newViolation(input.ComplianceFailingChecks)
+	v.Evidence = map[string]interface{}{
+		"checked_sha": sha,
+		"checks":      failing,
+	}
+	return v, true
+}
+
+// filesWithoutOwnerApproval returns sorted owned files for which none of the approvers is a code owner
+func (ce *ComplianceEvaluator) filesWithoutOwnerApproval(files []string, approvers []dataprocessor.User) []string {
+	uncovered := make([]string, 0)
+	for _, f := range files {
+		owners := ce.codeOwners.Owners(f)
+		if len(owners) == 0 {
+			continue
+		}
+		covered := false
+		for _, a := range approvers {
+			if ce.teams.IsOwner(owners, a.User) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			uncovered = append(uncovered, f)
+		}
+	}
+	sort.Strings(uncovered)
+	return uncovered
+}
+
+// newViolation returns violation of a rule without change specific fields
+func (ce *ComplianceEvaluator) newViolation(rule string, changeType string, branch string, now time.Time) ComplianceViolation {
+	var v ComplianceViolation
+	v.DocumentType = COMPLIANCEVIOLATION
+	v.RepoType = dataprocessor.GITHUB
+	v.RepoName = ce.RepoName
+	v.RepoURL = ce.RepoURL
+	v.CreatedAt = now
+	v.RuleID = rule
+	v.ChangeType = changeType
+	v.Branch = branch
+	v.Time = now.UnixNano() / 1000000
+	return v
+}
+
+// approversAtMerge returns reviewers whose last decisive review before merge is an approval , in order of approval.
+// Comments do not change the decision of a reviewer while requested changes and dismissals withdraw an approval.
+func approversAtMerge(reviews []dataprocessor.PullRequestReview, mergedAt time.Time) []dataprocessor.User {
+	sorted := append([]dataprocessor.PullRequestReview(nil), reviews...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+	decisions := make(map[string]dataprocessor.PullRequestReview)
+	var order []string
+	for _, r := range sorted {
+		if r.CreatedAt.IsZero() || r.CreatedAt.After(mergedAt) {
+			continue
+		}
+		switch strings.ToUpper(r.State) {
+		case reviewStateApproved, reviewStateChangesRequested, reviewStateDismissed:
+			if _, ok := decisions[r.CreatedBy.ID]; !ok {
+				order = append(order, r.CreatedBy.ID)
+			}
+			decisions[r.CreatedBy.ID] = r
+		}
+	}
+	var approvers []dataprocessor.User
+	for _, id := range order {
+		if strings.ToUpper(decisions[id].State) == reviewStateApproved {
+			approvers = append(approvers, decisions[id].CreatedBy)
+		}
+	}
+	return approvers
+}
+
+// logins returns the user names of users
+func logins(users []dataprocessor.User) []string {
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		names = append(names, u.User)
+	}
+	return names
+}
@@ -0,0 +1,106 @@
+package derivedmetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/codeowners"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestComplianceEvaluator_Evaluate(t *testing.T) {
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	co, err := codeowners.Parse(strings.NewReader("*.go @core-dev\n/docs/ @docs-dev\n"))
+	if err != nil {
+		t.Fatalf("codeowners.Parse() error = %v", err)
+	}
+	ce := &ComplianceEvaluator{
+		RepoName:       "testRepo",
+		MetricFormator: &metricformator.MetricFormator{},
+		codeOwners:     co,
+	}
+	ce.rules = make(map[string]bool)
+	for _, r := range input.ComplianceRules {
+		ce.rules[r] = true
+	}
+	author := dataprocessor.User{ID: "1", User: "author"}
+	coreDev := dataprocessor.User{ID: "2", User: "core-dev"}
+	other := dataprocessor.User{ID: "3", User: "other"}
+	review := func(u dataprocessor.User, state string, at time.Time) dataprocessor.PullRequestReview {
+		return dataprocessor.PullRequestReview{CreatedBy: u, State: state, CreatedAt: at}
+	}
+	signed := dataprocessor.CommitVerification{Verified: true, Reason: "valid"}
+
+	changes := []Change{
+		{
+			// approved by a code owner of go files only , approval of other was withdrawn
+			PullRequest: &dataprocessor.PullRequest{PullRequestNo: "1", CreatedBy: author, MergedAt: now.Add(-time.Hour)},
+			Branch:      "main",
+			Reviews: []dataprocessor.PullRequestReview{
+				review(other, "APPROVED", now.Add(-4*time.Hour)),
+				review(coreDev, "APPROVED", now.Add(-3*time.Hour)),
+				review(other, "CHANGES_REQUESTED", now.Add(-2*time.Hour)),
+			},
+			Commits: []dataprocessor.Commit{{Sha: "a1", Author: author, Verification: signed}},
+			Files:   []string{"main.go", "docs/guide.md", "LICENSE"},
+		},
+		{
+			// approved only by someone who pushed commits , with an unsigned commit and a failed check
+			PullRequest: &dataprocessor.PullRequest{PullRequestNo: "2", CreatedBy: author, MergedAt: now.Add(-time.Hour)},
+			Branch:      "main",
+			Reviews: []dataprocessor.PullRequestReview{
+				review(other, "APPROVED", now.Add(-2*time.Hour)),
+				review(coreDev, "APPROVED", now.Add(time.Hour)),
+			},
+			Commits: []dataprocessor.Commit{
+				{Sha: "b1", Author: author, Verification: signed},
+				{Sha: "b2", Author: other, Verification: dataprocessor.CommitVerification{Reason: "unsigned"}},
+			},
+			Files:  []string{"main.go"},
+			Checks: []dataprocessor.CommitCheck{{Name: "build", Conclusion: "failure"}, {Name: "lint", Conclusion: "success"}},
+		},
+		{
+			PullRequest: &dataprocessor.PullRequest{PullRequestNo: "3", CreatedBy: author, MergedAt: now.Add(-time.Hour)},
+			Branch:      "main",
+		},
+		{
+			Commit: &dataprocessor.Commit{Sha: "c1", Author: author, Verification: signed},
+			Branch: "main",
+		},
+	}
+	updates := []BranchUpdate{
+		{Branch: "main", PreviousHead: "old", Head: "new", Rewritten: true},
+		{Branch: "release", PreviousHead: "r1", Head: "r2"},
+	}
+	docs := ce.Evaluate(changes, updates, now, nil)
+	var violations []ComplianceViolation
+	if err := dataprocessor.DecodeDocuments(docs, &violations); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.PullRequestNo+":"+v.RuleID)
+	}
+	want := []string{
+		"1:" + input.ComplianceNonCodeOwnerApproval,
+		"2:" + input.ComplianceSelfApproval,
+		"2:" + input.ComplianceMissingApproval,
+		"2:" + input.ComplianceUnsignedCommit,
+		"2:" + input.ComplianceFailingChecks,
+		"3:" + input.ComplianceMissingApproval,
+		":" + input.ComplianceDirectCommit,
+		":" + input.ComplianceForcePush,
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Evaluate() violations = %v, want %v", got, want)
+	}
+	if files := violations[0].Evidence["files"].([]interface{}); len(files) != 1 || files[0] != "docs/guide.md" {
+		t.Errorf("non_codeowner_approval files = %v, want [docs/guide.md]", files)
+	}
+	if commits := violations[1].Evidence["authored_commits"].([]interface{}); len(commits) != 1 || commits[0] != "b2" {
+		t.Errorf("self_approval authored commits = %v, want [b2]", commits)
+	}
+}
@@ -0,0 +1,71 @@
+/* Package derivedmetrics computes metrics derived from documents prepared by dataprocessor , like DORA metrics */
+package derivedmetrics
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/logger"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+var (
+	log logger.Logger
+)
+
+func init() {
+	log = logger.GetLogger()
+}
+
+// CommitFiles represents a commit along with the changes of its files
+type CommitFiles struct {
+	// Commit is the analysed commit
+	Commit dataprocessor.Commit
+
+	// Files are the changes of files in commit
+	Files []dataprocessor.FileChange
+}
+
+// formatDocuments customises derived documents with metric formator and adds tags passed in config.yaml
+func formatDocuments(mf *metricformator.MetricFormator, docs interface{}, tags map[string]string) []interface{} {
+	b, err := json.Marshal(docs)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling derived documents", err)
+		return make([]interface{}, 0)
+	}
+	b = mf.CustomizeMetrics(b)
+	return dataprocessor.AddTags(b, tags)
+}
+
+// medianSeconds returns the median of durations in seconds , 0 for no durations
+func medianSeconds(durations []time.Duration) int64 {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return int64(((sorted[mid-1] + sorted[mid]) / 2).Seconds())
+	}
+	return int64(sorted[mid].Seconds())
+}
+
+// meanSeconds returns the mean of durations in seconds , 0 for no durations
+func meanSeconds(durations []time.Duration) int64 {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return int64((total / time.Duration(len(durations))).Seconds())
+}
+
+// inWindow checks if at lies in window [start , end]
+func inWindow(at time.Time, start time.Time, end time.Time) bool {
+	return !at.Before(start) && !at.After(end)
+}
@@ -0,0 +1,305 @@
+package derivedmetrics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/metricformator"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	DORAMETRIC = "dora_metric"
+)
+
+// DoraMetric represents the four DORA metrics of a repository for a time window
+type DoraMetric struct {
+	// DocumentType is "dora_metric"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents the end of the window
+	CreatedAt time.Time `json:"created_at"`
+
+	// Window represents the window length as configured , for ex. 7d
+	Window string `json:"window"`
+
+	// WindowStart represents the start of the window
+	WindowStart time.Time `json:"window_start"`
+
+	// DeploymentSource represents where deployments are read from
+	DeploymentSource string `json:"deployment_source"`
+
+	// Environment represents the production environment
+	Environment string `json:"environment"`
+
+	// DeploymentCount represents successful deployments in the window
+	DeploymentCount int `json:"deployment_count"`
+
+	// DeploymentFrequency represents successful deployments per day in the window
+	DeploymentFrequency float64 `json:"deployment_frequency_per_day"`
+
+	// LeadTimeMedianSeconds represents median time from first commit of a change to its deployment
+	LeadTimeMedianSeconds int64 `json:"lead_time_median_seconds"`
+
+	// LeadTimeMeanSeconds represents mean time from first commit of a change to its deployment
+	LeadTimeMeanSeconds int64 `json:"lead_time_mean_seconds"`
+
+	// LeadTimeSamples represents the number of deployed changes used for lead time
+	LeadTimeSamples int `json:"lead_time_samples"`
+
+	// FailedDeploymentCount represents failed deployments in the window
+	FailedDeploymentCount int `json:"failed_deployment_count"`
+
+	// IncidentCount represents incident issues opened in the window
+	IncidentCount int `json:"incident_count"`
+
+	// ChangeFailureRate represents failures per deployment in the window , between 0 and 1
+	ChangeFailureRate float64 `json:"change_failure_rate"`
+
+	// TimeToRestoreMedianSeconds represents median time to restore from a failure
+	TimeToRestoreMedianSeconds int64 `json:"time_to_restore_median_seconds"`
+
+	// TimeToRestoreSamples represents the number of restored failures used for time to restore
+	TimeToRestoreSamples int `json:"time_to_restore_samples"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// DoraInput holds the documents needed for computing DORA metrics
+type DoraInput struct {
+	// Deployments to the production environment , used when deployment source is deployments
+	Deployments []dataprocessor.Deployment
+
+	// Releases of the repository , used when deployment source is releases
+	Releases []dataprocessor.Release
+
+	// PullRequests closed or merged in the windows
+	PullRequests []dataprocessor.PullRequest
+
+	// FirstCommitAt holds the time of first commit for pull requests with pull request number as key
+	FirstCommitAt map[string]time.Time
+
+	// Incidents are the issues labelled as incidents
+	Incidents []dataprocessor.Issue
+}
+
+// DoraCalculator computes DORA metrics of a repository
+type DoraCalculator struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Config represents dora configuration of the audit job
+	Config input.DoraConfig
+
+	// Branches are the monitored branches , only changes merged to them are considered for lead time
+	Branches []string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+}
+
+// deployEvent is a deployment or a release normalised for computation
+type deployEvent struct {
+	at     time.Time
+	sha    string
+	failed bool
+}
+
+// NewDoraCalculator returns a new DORA metrics calculator for a repository
+func NewDoraCalculator(repoName string, repoURL string, branches []string, config input.DoraConfig) *DoraCalculator {
+	dc := new(DoraCalculator)
+	dc.RepoName = repoName
+	dc.RepoURL = repoURL
+	dc.Branches = branches
+	dc.Config = config
+	dc.MetricFormator = metricformator.NewMetricFormator()
+	return dc
+}
+
+// MaxWindow returns the longest configured window , data needs to be fetched for this duration
+func (dc *DoraCalculator) MaxWindow() time.Duration {
+	var max time.Duration
+	for _, w := range dc.Config.Windows {
+		d, err := utils.ParseDuration(w)
+		if err == nil && d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// Compute prepares dora metric output documents for each configured window ending at now
+func (dc *DoraCalculator) Compute(in DoraInput, now time.Time, tags map[string]string) []interface{} {
+	events := dc.deployEvents(in)
+	metrics := make([]DoraMetric, 0)
+	for _, w := range dc.Config.Windows {
+		length, err := utils.ParseDuration(w)
+		if err != nil {
+			log.Errorf("error[%v] in parsing dora window %v for repository %v", err, w, dc.RepoName)
+			continue
+		}
+		start := now.Add(-length)
+		var m DoraMetric
+		m.DocumentType = DORAMETRIC
+		m.RepoType = dataprocessor.GITHUB
+		m.RepoName = dc.RepoName
+		m.RepoURL = dc.RepoURL
+		m.CreatedAt = now
+		m.Window = w
+		m.WindowStart = start
+		m.DeploymentSource = dc.Config.DeploymentSource
+		m.Environment = dc.Config.Environment
+		m.Time = now.UnixNano() / 1000000
+
+		var restoreTimes []time.Duration
+		for i, ev := range events {
+			if !inWindow(ev.at, start, now) {
+				continue
+			}
+			if !ev.failed {
+				m.DeploymentCount++
+				continue
+			}
+			if !dc.countsFailure(input.DoraSourceDeployments) {
+				continue
+			}
+			m.FailedDeploymentCount++
+			// failed deployment is restored by the next successful deployment
+			for _, next := range events[i+1:] {
+				if !next.failed {
+					if !next.at.After(now) {
+						restoreTimes = append(restoreTimes, next.at.Sub(ev.at))
+					}
+					break
+				}
+			}
+		}
+		m.DeploymentFrequency = float64(m.DeploymentCount) / (length.Hours() / 24)
+
+		if dc.countsFailure(input.DoraSourceIssues) {
+			for _, incident := range in.Incidents {
+				if inWindow(incident.CreatedAt, start, now) {
+					m.IncidentCount++
+				}
+				closedAt, err := time.Parse(time.RFC3339, incident.ClosedAt)
+				if err == nil && inWindow(closedAt, start, now) {
+					restoreTimes = append(restoreTimes, closedAt.Sub(incident.CreatedAt))
+				}
+			}
+		}
+		if total := m.DeploymentCount + m.FailedDeploymentCount; total > 0 {
+			m.ChangeFailureRate = float64(m.FailedDeploymentCount+m.IncidentCount) / float64(total)
+			if m.ChangeFailureRate > 1 {
+				m.ChangeFailureRate = 1
+			}
+		}
+		m.TimeToRestoreMedianSeconds = medianSeconds(restoreTimes)
+		m.TimeToRestoreSamples = len(restoreTimes)
+
+		leadTimes := dc.leadTimes(in, events, start, now)
+		m.LeadTimeMedianSeconds = medianSeconds(leadTimes)
+		m.LeadTimeMeanSeconds = meanSeconds(leadTimes)
+		m.LeadTimeSamples = len(leadTimes)
+		metrics = append(metrics, m)
+	}
+	return formatDocuments(dc.MetricFormator, metrics, tags)
+}
+
+// deployEvents returns finished deployments or published releases sorted by time
+func (dc *DoraCalculator) deployEvents(in DoraInput) []deployEvent {
+	var events []deployEvent
+	if dc.Config.DeploymentSource == input.DoraSourceReleases {
+		for _, r := range in.Releases {
+			if r.Draft || r.Prerelease {
+				continue
+			}
+			at := r.PublishedAt
+			if at.IsZero() {
+				at = r.CreatedAt
+			}
+			events = append(events, deployEvent{at: at, sha: r.TargetCommitish})
+		}
+	} else {
+		for _, d := range in.Deployments {
+			at := d.StateAt
+			if at.IsZero() {
+				at = d.CreatedAt
+			}
+			switch strings.ToLower(d.State) {
+			// inactive deployments were successful before being replaced by a newer deployment
+			case "success", "inactive":
+				events = append(events, deployEvent{at: at, sha: d.Sha})
+			case "failure", "error":
+				events = append(events, deployEvent{at: at, sha: d.Sha, failed: true})
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+	return events
+}
+
+// leadTimes returns time from first commit to deployment for changes deployed in window [start , end].
+// A change is deployed by the deployment of its merge commit , or else by the first successful deployment after merge.
+func (dc *DoraCalculator) leadTimes(in DoraInput, events []deployEvent, start time.Time, end time.Time) []time.Duration {
+	var leadTimes []time.Duration
+	for _, pr := range in.PullRequests {
+		if pr.MergedAt.IsZero() || !dc.isMonitoredBranch(pr.MergeToRepo.Branch) {
+			continue
+		}
+		var deployedAt time.Time
+		for _, ev := range events {
+			if !ev.failed && ev.sha != "" && ev.sha == pr.MergeCommitSha && !ev.at.Before(pr.MergedAt) {
+				deployedAt = ev.at
+				break
+			}
+		}
+		if deployedAt.IsZero() {
+			for _, ev := range events {
+				if !ev.failed && !ev.at.Before(pr.MergedAt) {
+					deployedAt = ev.at
+					break
+				}
+			}
+		}
+		if deployedAt.IsZero() || !inWindow(deployedAt, start, end) {
+			continue
+		}
+		firstCommitAt, ok := in.FirstCommitAt[pr.PullRequestNo]
+		if !ok || firstCommitAt.IsZero() {
+			firstCommitAt = pr.CreatedAt
+		}
+		leadTimes = append(leadTimes, deployedAt.Sub(firstCommitAt))
+	}
+	return leadTimes
+}
+
+// countsFailure checks if source is configured to mark changes as failed
+func (dc *DoraCalculator) countsFailure(source string) bool {
+	return dc.Config.FailureSource == input.DoraSourceAll || dc.Config.FailureSource == source
+}
+
+// isMonitoredBranch checks if branch is one of the monitored branches
+func (dc *DoraCalculator) isMonitoredBranch(branch string) bool {
+	for _, br := range dc.Branches {
+		if br == branch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package derivedmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestDoraCalculator_Compute(t *testing.T) {
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	dc := &DoraCalculator{
+		RepoName: "testRepo",
+		Branches: []string{"main"},
+		Config: input.DoraConfig{
+			Enabled:          true,
+			DeploymentSource: input.DoraSourceDeployments,
+			FailureSource:    input.DoraSourceAll,
+			Windows:          []string{"7d"},
+		},
+		MetricFormator: &metricformator.MetricFormator{},
+	}
+	in := DoraInput{
+		Deployments: []dataprocessor.Deployment{
+			{Sha: "aaa", State: "success", StateAt: now.Add(-48 * time.Hour)},
+			{Sha: "bbb", State: "failure", StateAt: now.Add(-24 * time.Hour)},
+			{Sha: "ccc", State: "success", StateAt: now.Add(-22 * time.Hour)},
+			{Sha: "old", State: "success", StateAt: now.Add(-10 * 24 * time.Hour)},
+		},
+		PullRequests: []dataprocessor.PullRequest{
+			{PullRequestNo: "1", MergeCommitSha: "aaa", MergedAt: now.Add(-50 * time.Hour), MergeToRepo: dataprocessor.MergeToRepository{Branch: "main"}},
+			{PullRequestNo: "2", MergeCommitSha: "zzz", MergedAt: now.Add(-23 * time.Hour), MergeToRepo: dataprocessor.MergeToRepository{Branch: "main"}},
+			{PullRequestNo: "3", MergeCommitSha: "yyy", MergedAt: now.Add(-23 * time.Hour), MergeToRepo: dataprocessor.MergeToRepository{Branch: "feature"}},
+		},
+		FirstCommitAt: map[string]time.Time{
+			"1": now.Add(-52 * time.Hour),
+			"2": now.Add(-26 * time.Hour),
+		},
+		Incidents: []dataprocessor.Issue{
+			{CreatedAt: now.Add(-20 * time.Hour), ClosedAt: now.Add(-18 * time.Hour).Format(time.RFC3339)},
+		},
+	}
+	docs := dc.Compute(in, now, map[string]string{"key1": "value1"})
+	if len(docs) != 1 {
+		t.Fatalf("Compute() returned %d documents, want 1", len(docs))
+	}
+	var metrics []DoraMetric
+	if err := dataprocessor.DecodeDocuments(docs, &metrics); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	m := metrics[0]
+	if m.DeploymentCount != 2 || m.FailedDeploymentCount != 1 || m.IncidentCount != 1 {
+		t.Errorf("counts = %d deployments, %d failed, %d incidents, want 2, 1, 1", m.DeploymentCount, m.FailedDeploymentCount, m.IncidentCount)
+	}
+	if m.ChangeFailureRate != 2.0/3.0 {
+		t.Errorf("ChangeFailureRate = %v, want %v", m.ChangeFailureRate, 2.0/3.0)
+	}
+	// pull request 1 is deployed by its merge commit after 4h , pull request 2 by next deployment after 4h
+	if m.LeadTimeSamples != 2 || m.LeadTimeMedianSeconds != 4*3600 {
+		t.Errorf("lead time = %d samples, median %ds, want 2 samples, median %ds", m.LeadTimeSamples, m.LeadTimeMedianSeconds, 4*3600)
+	}
+	// failed deployment restored after 2h and incident closed after 2h
+	if m.TimeToRestoreSamples != 2 || m.TimeToRestoreMedianSeconds != 2*3600 {
+		t.Errorf("time to restore = %d samples, median %ds, want 2 samples, median %ds", m.TimeToRestoreSamples, m.TimeToRestoreMedianSeconds, 2*3600)
+	}
+	if docs[0].(map[string]interface{})["key1"] != "value1" {
+		t.Errorf("Compute() did not add tags to documents")
+	}
+}
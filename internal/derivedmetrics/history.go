@@ -0,0 +1,176 @@
+package derivedmetrics
+
+import (
+	"time"
+
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+const (
+	HISTORYREWRITE = "history_rewrite"
+
+	// branchRefPrefix is the prefix of branch refs in push events
+	branchRefPrefix = "refs/heads/"
+)
+
+// BranchUpdate represents the move of a branch head between two runs
+type BranchUpdate struct {
+	// Branch is the branch whose head moved
+	Branch string
+
+	// PreviousHead is the head sha in last run
+	PreviousHead string
+
+	// Head is the current head sha
+	Head string
+
+	// Rewritten is true if previous head is not an ancestor of current head
+	Rewritten bool
+
+	// LostCommits are the commits reachable from previous head but not from current head , only fetched for rewrites
+	LostCommits []dataprocessor.Commit
+
+	// LostCommitsResolved is false if previous head is no more present in the repository
+	LostCommitsResolved bool
+}
+
+// LostCommit represents a commit which is no more reachable from the branch after a history rewrite
+type LostCommit struct {
+	// Sha is the sha of commit
+	Sha string `json:"sha"`
+
+	// Message is the commit message
+	Message string `json:"message"`
+
+	// Author shows the author of commit
+	Author dataprocessor.User `json:"author"`
+
+	// CreatedAt represents at what time the commit is created
+	CreatedAt time.Time `json:"created_at"`
+
+	// URL is api url to commit
+	URL string `json:"url"`
+}
+
+// HistoryRewrite represents a force push which removed commits from a branch
+type HistoryRewrite struct {
+	// DocumentType is "history_rewrite"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the rewrite was detected
+	CreatedAt time.Time `json:"created_at"`
+
+	// Branch is the rewritten branch
+	Branch string `json:"branch"`
+
+	// PreviousHead is the head sha before the rewrite
+	PreviousHead string `json:"previous_head"`
+
+	// Head is the head sha after the rewrite
+	Head string `json:"head"`
+
+	// LostCommits are the commits no more reachable from the branch
+	LostCommits []LostCommit `json:"lost_commits"`
+
+	// LostCommitsResolved is false if the previous head is no more present , lost commits are then unknown
+	LostCommitsResolved bool `json:"lost_commits_resolved"`
+
+	// Pusher shows the user who pushed the rewrite , empty if push events do not have the push
+	Pusher dataprocessor.User `json:"pusher"`
+
+	// PushedAt represents at what time the rewrite was pushed , empty if push events do not have the push
+	PushedAt time.Time `json:"pushed_at"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// HistoryRewriteDetector prepares history rewrite documents of branch updates of a repository
+type HistoryRewriteDetector struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+}
+
+// NewHistoryRewriteDetector returns a new history rewrite detector for a repository
+func NewHistoryRewriteDetector(repoName string, repoURL string) *HistoryRewriteDetector {
+	hd := new(HistoryRewriteDetector)
+	hd.RepoName = repoName
+	hd.RepoURL = repoURL
+	hd.MetricFormator = metricformator.NewMetricFormator()
+	return hd
+}
+
+// Detect prepares history rewrite output documents for rewritten branch updates detected at now ,
+// pushes are used to find who pushed the rewrite
+func (hd *HistoryRewriteDetector) Detect(updates []BranchUpdate, pushes []dataprocessor.Push, now time.Time, tags map[string]string) []interface{} {
+	rewrites := make([]HistoryRewrite, 0)
+	for _, u := range updates {
+		if !u.Rewritten {
+			continue
+		}
+		var r HistoryRewrite
+		r.DocumentType = HISTORYREWRITE
+		r.RepoType = dataprocessor.GITHUB
+		r.RepoName = hd.RepoName
+		r.RepoURL = hd.RepoURL
+		r.CreatedAt = now
+		r.Branch = u.Branch
+		r.PreviousHead = u.PreviousHead
+		r.Head = u.Head
+		r.LostCommits = make([]LostCommit, 0, len(u.LostCommits))
+		for _, c := range u.LostCommits {
+			r.LostCommits = append(r.LostCommits, LostCommit{Sha: c.Sha, Message: c.Message, Author: c.Author, CreatedAt: c.CreatedAt, URL: c.CommitURL})
+		}
+		r.LostCommitsResolved = u.LostCommitsResolved
+		if push, ok := findRewritePush(pushes, u); ok {
+			r.Pusher = push.Pusher
+			r.PushedAt = push.CreatedAt
+		}
+		r.Time = now.UnixNano() / 1000000
+		rewrites = append(rewrites, r)
+	}
+	return formatDocuments(hd.MetricFormator, rewrites, tags)
+}
+
+// findRewritePush returns the push to the branch which moved its head from previous head to current head ,
+// or else the first push from previous head , or else the push which resulted in current head
+func findRewritePush(pushes []dataprocessor.Push, u BranchUpdate) (dataprocessor.Push, bool) {
+	var fromPrevious, toHead *dataprocessor.Push
+	for i := range pushes {
+		p := &pushes[i]
+		if p.Ref != branchRefPrefix+u.Branch {
+			continue
+		}
+		switch {
+		case p.Before == u.PreviousHead && p.Head == u.Head:
+			return *p, true
+		case p.Before == u.PreviousHead && fromPrevious == nil:
+			fromPrevious = p
+		case p.Head == u.Head && toHead == nil:
+			toHead = p
+		}
+	}
+	if fromPrevious != nil {
+		return *fromPrevious, true
+	}
+	if toHead != nil {
+		return *toHead, true
+	}
+	return dataprocessor.Push{}, false
+}
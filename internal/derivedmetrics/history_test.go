@@ -0,0 +1,51 @@
+package derivedmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestHistoryRewriteDetector_Detect(t *testing.T) {
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	hd := &HistoryRewriteDetector{RepoName: "testRepo", MetricFormator: &metricformator.MetricFormator{}}
+	pushes := []dataprocessor.Push{
+		{Ref: "refs/heads/release", Before: "r1", Head: "r2", Pusher: dataprocessor.User{User: "releaser"}},
+		{Ref: "refs/heads/main", Before: "x", Head: "new", Pusher: dataprocessor.User{User: "later"}},
+		{Ref: "refs/heads/main", Before: "old", Head: "x", Pusher: dataprocessor.User{User: "forcer"}},
+		{Ref: "refs/heads/dev", Before: "d0", Head: "d2", Pusher: dataprocessor.User{User: "dev"}},
+	}
+	updates := []BranchUpdate{
+		{Branch: "main", PreviousHead: "old", Head: "new", Rewritten: true, LostCommitsResolved: true,
+			LostCommits: []dataprocessor.Commit{{Sha: "lost1", Message: "wip"}}},
+		{Branch: "release", PreviousHead: "r1", Head: "r2"},
+		{Branch: "dev", PreviousHead: "d1", Head: "d2", Rewritten: true},
+		{Branch: "feature", PreviousHead: "f1", Head: "f2", Rewritten: true},
+	}
+	var rewrites []HistoryRewrite
+	if err := dataprocessor.DecodeDocuments(hd.Detect(updates, pushes, now, nil), &rewrites); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	tests := []struct {
+		branch   string
+		pusher   string
+		lost     int
+		resolved bool
+	}{
+		{"main", "forcer", 1, true},
+		{"dev", "dev", 0, false},
+		{"feature", "", 0, false},
+	}
+	if len(rewrites) != len(tests) {
+		t.Fatalf("Detect() returned %v rewrites, want %v", len(rewrites), len(tests))
+	}
+	for i, tt := range tests {
+		r := rewrites[i]
+		if r.Branch != tt.branch || r.Pusher.User != tt.pusher || len(r.LostCommits) != tt.lost || r.LostCommitsResolved != tt.resolved {
+			t.Errorf("Detect()[%v] = %v pushed by %q with %v lost commits resolved %v, want %v pushed by %q with %v resolved %v",
+				i, r.Branch, r.Pusher.User, len(r.LostCommits), r.LostCommitsResolved, tt.branch, tt.pusher, tt.lost, tt.resolved)
+		}
+	}
+}
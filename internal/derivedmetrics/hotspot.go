@@ -0,0 +1,292 @@
+package derivedmetrics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/metricformator"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	FILEHOTSPOT = "file_hotspot"
+
+	// kinds of path in file hotspots
+	PathTypeFile      = "file"
+	PathTypeDirectory = "directory"
+
+	// statuses of changed files as reported by github
+	fileStatusAdded   = "added"
+	fileStatusRemoved = "removed"
+	fileStatusRenamed = "renamed"
+)
+
+// FileHotspot represents the churn of a file or directory within a window
+type FileHotspot struct {
+	// DocumentType is "file_hotspot"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the report is made
+	CreatedAt time.Time `json:"created_at"`
+
+	// Path of file or directory
+	Path string `json:"path"`
+
+	// PathType is file or directory
+	PathType string `json:"path_type"`
+
+	// Rank is the position of path among paths of the same type by changes , 1 for most changed
+	Rank int `json:"rank"`
+
+	// Window is the analysed period , for ex. 30d
+	Window string `json:"window"`
+
+	// WindowStart represents the start time of window
+	WindowStart time.Time `json:"window_start"`
+
+	// WindowEnd represents the end time of window
+	WindowEnd time.Time `json:"window_end"`
+
+	// Changes is the number of commits changing the path
+	Changes int `json:"changes"`
+
+	// DistinctAuthors is the number of distinct authors changing the path
+	DistinctAuthors int `json:"distinct_authors"`
+
+	// Authors are the canonical identities of authors changing the path
+	Authors []string `json:"authors"`
+
+	// LinesAdded is the number of lines added
+	LinesAdded int `json:"lines_added"`
+
+	// LinesRemoved is the number of lines removed
+	LinesRemoved int `json:"lines_removed"`
+
+	// Churn is the sum of lines added and removed
+	Churn int `json:"churn"`
+
+	// ReworkLines is the number of removed lines which were written within rework window
+	ReworkLines int `json:"rework_lines"`
+
+	// ReworkRatio is rework lines per removed line
+	ReworkRatio float64 `json:"rework_ratio"`
+
+	// LastChangedAt represents at what time the path was last changed
+	LastChangedAt time.Time `json:"last_changed_at"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// churn accumulates changes of a path
+type churn struct {
+	path          string
+	changes       int
+	authors       map[string]bool
+	added         int
+	removed       int
+	rework        int
+	lastChangedAt time.Time
+}
+
+// HotspotAnalyzer computes churn per file and directory of a repository from commits and their file changes
+type HotspotAnalyzer struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+
+	windowName   string
+	window       time.Duration
+	reworkWindow time.Duration
+	depth        int
+	top          int
+	resolver     *enrichment.IdentityResolver
+}
+
+// NewHotspotAnalyzer returns a new hotspot analyzer for a repository , resolver merges aliases of authors
+func NewHotspotAnalyzer(repoName string, repoURL string, cfg input.HotspotConfig, resolver *enrichment.IdentityResolver) *HotspotAnalyzer {
+	ha := new(HotspotAnalyzer)
+	ha.RepoName = repoName
+	ha.RepoURL = repoURL
+	ha.MetricFormator = metricformator.NewMetricFormator()
+	ha.windowName = cfg.Window
+	// windows are validated while reading config
+	ha.window, _ = utils.ParseDuration(cfg.Window)
+	ha.reworkWindow, _ = utils.ParseDuration(cfg.ReworkWindow)
+	ha.depth = cfg.DirectoryDepth
+	ha.top = cfg.Top
+	ha.resolver = resolver
+	return ha
+}
+
+// Since returns the time from which commits are needed for a report at now. Commits of rework window before
+// the window are needed to know which lines changed at the start of window are rework.
+func (ha *HotspotAnalyzer) Since(now time.Time) time.Time {
+	return now.Add(-(ha.window + ha.reworkWindow))
+}
+
+// Analyze prepares file hotspot output documents for the most changed files and directories in window ending at now.
+// Merge commits are skipped as their changes are already counted in the merged commits.
+func (ha *HotspotAnalyzer) Analyze(commits []CommitFiles, now time.Time, tags map[string]string) []interface{} {
+	sorted := make([]CommitFiles, 0, len(commits))
+	for _, c := range commits {
+		if len(c.Commit.Parents) <= 1 {
+			sorted = append(sorted, c)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Commit.CreatedAt.Before(sorted[j].Commit.CreatedAt) })
+
+	windowStart := now.Add(-ha.window)
+	fresh := make(map[string]freshLines)
+	files := make(map[string]*churn)
+	directories := make(map[string]*churn)
+	for _, c := range sorted {
+		at := c.Commit.CreatedAt
+		inWindow := !at.Before(windowStart)
+		author := ha.resolver.Resolve(c.Commit.Author).Canonical
+		touched := make(map[string]bool)
+		for _, f := range c.Files {
+			rework := ha.trackRework(fresh, f, at)
+			if !inWindow {
+				continue
+			}
+			fc := accumulate(files, f.Path, f, rework, author, at)
+			fc.changes++
+			for _, dir := range directoriesOf(f.Path, ha.depth) {
+				dc := accumulate(directories, dir, f, rework, author, at)
+				// a commit counts once for each directory it changes
+				if !touched[dir] {
+					touched[dir] = true
+					dc.changes++
+				}
+			}
+		}
+	}
+
+	hotspots := ha.rank(files, PathTypeFile, windowStart, now)
+	hotspots = append(hotspots, ha.rank(directories, PathTypeDirectory, windowStart, now)...)
+	return formatDocuments(ha.MetricFormator, hotspots, tags)
+}
+
+// trackRework applies a file change made at to fresh lines of files and returns the number of reworked lines.
+// Without a patch , for binary files or large diffs , lines of the file are unknown and no rework is counted.
+func (ha *HotspotAnalyzer) trackRework(fresh map[string]freshLines, f dataprocessor.FileChange, at time.Time) int {
+	lines := fresh[f.Path]
+	switch f.Status {
+	case fileStatusRemoved:
+		delete(fresh, f.Path)
+		return 0
+	case fileStatusAdded:
+		lines = nil
+	case fileStatusRenamed:
+		lines = fresh[f.PreviousPath]
+		delete(fresh, f.PreviousPath)
+	}
+	if f.Patch == "" {
+		delete(fresh, f.Path)
+		return 0
+	}
+	lines, rework := applyPatch(lines, f.Patch, at, at.Add(-ha.reworkWindow))
+	fresh[f.Path] = lines
+	return rework
+}
+
+// accumulate adds line counts of a file change to the churn of path and returns it
+func accumulate(churns map[string]*churn, path string, f dataprocessor.FileChange, rework int, author string, at time.Time) *churn {
+	c, ok := churns[path]
+	if !ok {
+		c = &churn{path: path, authors: make(map[string]bool)}
+		churns[path] = c
+	}
+	c.added += f.Additions
+	c.removed += f.Deletions
+	c.rework += rework
+	if author != "" {
+		c.authors[author] = true
+	}
+	if at.After(c.lastChangedAt) {
+		c.lastChangedAt = at
+	}
+	return c
+}
+
+// directoriesOf returns the parent directories of path up to depth path elements , for ex. a and a/b for a/b/c/d.go with depth 2
+func directoriesOf(path string, depth int) []string {
+	elements := strings.Split(path, "/")
+	dirs := make([]string, 0, depth)
+	for i := 1; i < len(elements) && i <= depth; i++ {
+		dirs = append(dirs, strings.Join(elements[:i], "/"))
+	}
+	return dirs
+}
+
+// rank returns hotspots of the top paths by changes , then by churn
+func (ha *HotspotAnalyzer) rank(churns map[string]*churn, pathType string, windowStart time.Time, now time.Time) []FileHotspot {
+	all := make([]*churn, 0, len(churns))
+	for _, c := range churns {
+		all = append(all, c)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].changes != all[j].changes {
+			return all[i].changes > all[j].changes
+		}
+		if all[i].added+all[i].removed != all[j].added+all[j].removed {
+			return all[i].added+all[i].removed > all[j].added+all[j].removed
+		}
+		return all[i].path < all[j].path
+	})
+	if ha.top > 0 && len(all) > ha.top {
+		all = all[:ha.top]
+	}
+	hotspots := make([]FileHotspot, 0, len(all))
+	for i, c := range all {
+		var h FileHotspot
+		h.DocumentType = FILEHOTSPOT
+		h.RepoType = dataprocessor.GITHUB
+		h.RepoName = ha.RepoName
+		h.RepoURL = ha.RepoURL
+		h.CreatedAt = now
+		h.Path = c.path
+		h.PathType = pathType
+		h.Rank = i + 1
+		h.Window = ha.windowName
+		h.WindowStart = windowStart
+		h.WindowEnd = now
+		h.Changes = c.changes
+		h.Authors = make([]string, 0, len(c.authors))
+		for a := range c.authors {
+			h.Authors = append(h.Authors, a)
+		}
+		sort.Strings(h.Authors)
+		h.DistinctAuthors = len(h.Authors)
+		h.LinesAdded = c.added
+		h.LinesRemoved = c.removed
+		h.Churn = c.added + c.removed
+		h.ReworkLines = c.rework
+		if c.removed > 0 {
+			h.ReworkRatio = float64(c.rework) / float64(c.removed)
+		}
+		h.LastChangedAt = c.lastChangedAt
+		h.Time = now.UnixNano() / 1000000
+		hotspots = append(hotspots, h)
+	}
+	return hotspots
+}
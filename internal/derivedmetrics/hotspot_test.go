@@ -0,0 +1,67 @@
+package derivedmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestHotspotAnalyzer_Analyze(t *testing.T) {
+	now := time.Date(2022, 10, 30, 0, 0, 0, 0, time.UTC)
+	days := func(d int) time.Time { return now.Add(-time.Duration(d) * 24 * time.Hour) }
+	cfg := input.HotspotConfig{Window: "30d", ReworkWindow: "21d", DirectoryDepth: 1, Top: 2}
+	ha := NewHotspotAnalyzer("testRepo", "", cfg, enrichment.NewIdentityResolver(input.IdentityConfig{}))
+	ha.MetricFormator = &metricformator.MetricFormator{}
+	commit := func(sha string, email string, at time.Time, parents int, files ...dataprocessor.FileChange) CommitFiles {
+		c := dataprocessor.Commit{Sha: sha, Author: dataprocessor.User{User: email, Email: email}, CreatedAt: at, Parents: make([]string, parents)}
+		return CommitFiles{Commit: c, Files: files}
+	}
+	change := func(path string, status string, added int, removed int, patch string) dataprocessor.FileChange {
+		return dataprocessor.FileChange{Path: path, Status: status, Additions: added, Deletions: removed, Patch: patch}
+	}
+	commits := []CommitFiles{
+		// before window , only tracks lines of main.go written 35 days ago
+		commit("a", "dev1@example.com", days(35), 1, change("cmd/main.go", "added", 2, 0, "@@ -0,0 +1,2 @@\n+a\n+b")),
+		// rewrites the first line in window , 10 days apart so it is rework
+		commit("b", "dev2@example.com", days(25), 1,
+			change("cmd/main.go", "modified", 1, 1, "@@ -1,2 +1,2 @@\n-a\n+a2\n b"),
+			change("cmd/util.go", "added", 5, 0, "@@ -0,0 +1,5 @@\n+1\n+2\n+3\n+4\n+5")),
+		commit("c", "DEV1@example.com", days(5), 1, change("cmd/main.go", "modified", 1, 1, "@@ -1 +1 @@\n-a2\n+a3")),
+		// merge commits repeat changes of merged commits
+		commit("m", "dev2@example.com", days(4), 2, change("cmd/main.go", "modified", 1, 1, "@@ -1 +1 @@\n-a2\n+a3")),
+		commit("d", "dev3@example.com", days(3), 1, change("README.md", "modified", 1, 0, "")),
+	}
+	var hotspots []FileHotspot
+	if err := dataprocessor.DecodeDocuments(ha.Analyze(commits, now, nil), &hotspots); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	type want struct {
+		path      string
+		pathType  string
+		changes   int
+		authors   int
+		added     int
+		removed   int
+		rework    int
+		reworkPct float64
+	}
+	wants := []want{
+		{"cmd/main.go", PathTypeFile, 2, 2, 2, 2, 2, 1},
+		{"cmd/util.go", PathTypeFile, 1, 1, 5, 0, 0, 0},
+		{"cmd", PathTypeDirectory, 2, 2, 7, 2, 2, 1},
+	}
+	if len(hotspots) != len(wants) {
+		t.Fatalf("Analyze() returned %v hotspots, want %v", len(hotspots), len(wants))
+	}
+	for i, w := range wants {
+		h := hotspots[i]
+		got := want{h.Path, h.PathType, h.Changes, h.DistinctAuthors, h.LinesAdded, h.LinesRemoved, h.ReworkLines, h.ReworkRatio}
+		if got != w {
+			t.Errorf("Analyze()[%v] = %+v, want %+v", i, got, w)
+		}
+	}
+}
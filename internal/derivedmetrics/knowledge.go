@@ -0,0 +1,284 @@
+package derivedmetrics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/metricformator"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	KNOWLEDGEDISTRIBUTION = "knowledge_distribution"
+
+	// scopes of knowledge distribution
+	ScopeRepository = "repository"
+	ScopeDirectory  = "directory"
+
+	// topAuthorCount is the number of authors with largest shares listed in knowledge distribution
+	topAuthorCount = 5
+)
+
+// AuthorShare represents the share of an author in changes of a scope
+type AuthorShare struct {
+	// Author is the canonical identity of author
+	Author string `json:"author"`
+
+	// Name is the canonical name of author
+	Name string `json:"name"`
+
+	// Team of author , empty if author is not in any team
+	Team string `json:"team"`
+
+	// Commits is the number of commits of author changing the scope
+	Commits int `json:"commits"`
+
+	// LinesChanged is the number of lines added and removed by author
+	LinesChanged int `json:"lines_changed"`
+
+	// Share is the fraction of lines changed by author
+	Share float64 `json:"share"`
+}
+
+// KnowledgeDistribution represents how concentrated recent changes of a repository or top level directory are among authors
+type KnowledgeDistribution struct {
+	// DocumentType is "knowledge_distribution"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the report is made
+	CreatedAt time.Time `json:"created_at"`
+
+	// Scope is repository or directory
+	Scope string `json:"scope"`
+
+	// Path is the top level directory , empty for repository
+	Path string `json:"path"`
+
+	// Window is the analysed period , for ex. 90d
+	Window string `json:"window"`
+
+	// WindowStart represents the start time of window
+	WindowStart time.Time `json:"window_start"`
+
+	// WindowEnd represents the end time of window
+	WindowEnd time.Time `json:"window_end"`
+
+	// Commits is the number of commits changing the scope
+	Commits int `json:"commits"`
+
+	// LinesChanged is the number of lines added and removed
+	LinesChanged int `json:"lines_changed"`
+
+	// Authors is the number of distinct authors
+	Authors int `json:"authors"`
+
+	// AuthorsFor50Percent is the least number of authors accounting for half of lines changed
+	AuthorsFor50Percent int `json:"authors_for_50_percent"`
+
+	// AuthorsFor80Percent is the least number of authors accounting for 80% of lines changed
+	AuthorsFor80Percent int `json:"authors_for_80_percent"`
+
+	// SingleOwner is true if a single author changed more than half of the lines
+	SingleOwner bool `json:"single_owner"`
+
+	// TopAuthors are the authors with largest shares
+	TopAuthors []AuthorShare `json:"top_authors"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// KnowledgeAnalyzer computes the concentration of changes among authors per repository and top level directory
+type KnowledgeAnalyzer struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+
+	windowName string
+	window     time.Duration
+	resolver   *enrichment.IdentityResolver
+}
+
+// NewKnowledgeAnalyzer returns a new knowledge analyzer for a repository , resolver merges aliases of authors and detects bots
+func NewKnowledgeAnalyzer(repoName string, repoURL string, cfg input.KnowledgeConfig, resolver *enrichment.IdentityResolver) *KnowledgeAnalyzer {
+	ka := new(KnowledgeAnalyzer)
+	ka.RepoName = repoName
+	ka.RepoURL = repoURL
+	ka.MetricFormator = metricformator.NewMetricFormator()
+	ka.windowName = cfg.Window
+	// window is validated while reading config
+	ka.window, _ = utils.ParseDuration(cfg.Window)
+	ka.resolver = resolver
+	return ka
+}
+
+// Since returns the time from which commits are needed for a report at now
+func (ka *KnowledgeAnalyzer) Since(now time.Time) time.Time {
+	return now.Add(-ka.window)
+}
+
+// Analyze prepares knowledge distribution output documents for the repository and each top level directory changed
+// within window ending at now. Shares are of lines changed , commits by bots and merge commits are skipped.
+func (ka *KnowledgeAnalyzer) Analyze(commits []CommitFiles, now time.Time, tags map[string]string) []interface{} {
+	windowStart := ka.Since(now)
+	repository := newKnowledgeScope(ScopeRepository, "")
+	directories := make(map[string]*knowledgeScope)
+	for _, c := range commits {
+		if len(c.Commit.Parents) > 1 || c.Commit.CreatedAt.Before(windowStart) {
+			continue
+		}
+		id := ka.resolver.Resolve(c.Commit.Author)
+		if ka.resolver.IsBot(c.Commit.Author, id) {
+			continue
+		}
+		team := ka.resolver.Team(id)
+		for _, f := range c.Files {
+			lines := f.Additions + f.Deletions
+			repository.add(c.Commit.Sha, id, team, lines)
+			if i := strings.Index(f.Path, "/"); i > 0 {
+				dir := f.Path[:i]
+				if _, ok := directories[dir]; !ok {
+					directories[dir] = newKnowledgeScope(ScopeDirectory, dir)
+				}
+				directories[dir].add(c.Commit.Sha, id, team, lines)
+			}
+		}
+	}
+
+	distributions := make([]KnowledgeDistribution, 0, len(directories)+1)
+	if repository.commits() > 0 {
+		distributions = append(distributions, ka.distribution(repository, windowStart, now))
+	}
+	paths := make([]string, 0, len(directories))
+	for dir := range directories {
+		paths = append(paths, dir)
+	}
+	sort.Strings(paths)
+	for _, dir := range paths {
+		distributions = append(distributions, ka.distribution(directories[dir], windowStart, now))
+	}
+	return formatDocuments(ka.MetricFormator, distributions, tags)
+}
+
+// distribution returns the knowledge distribution of a scope
+func (ka *KnowledgeAnalyzer) distribution(s *knowledgeScope, windowStart time.Time, now time.Time) KnowledgeDistribution {
+	var d KnowledgeDistribution
+	d.DocumentType = KNOWLEDGEDISTRIBUTION
+	d.RepoType = dataprocessor.GITHUB
+	d.RepoName = ka.RepoName
+	d.RepoURL = ka.RepoURL
+	d.CreatedAt = now
+	d.Scope = s.scope
+	d.Path = s.path
+	d.Window = ka.windowName
+	d.WindowStart = windowStart
+	d.WindowEnd = now
+	d.Commits = s.commits()
+
+	shares := s.shares()
+	d.Authors = len(shares)
+	for _, a := range shares {
+		d.LinesChanged += a.LinesChanged
+	}
+	cumulative := 0.0
+	for i, a := range shares {
+		cumulative += a.Share
+		// small tolerance so that shares adding up to exactly a threshold are not missed due to rounding
+		if d.AuthorsFor50Percent == 0 && cumulative >= 0.5-1e-9 {
+			d.AuthorsFor50Percent = i + 1
+		}
+		if d.AuthorsFor80Percent == 0 && cumulative >= 0.8-1e-9 {
+			d.AuthorsFor80Percent = i + 1
+		}
+	}
+	d.SingleOwner = len(shares) > 0 && shares[0].Share > 0.5
+	if len(shares) > topAuthorCount {
+		shares = shares[:topAuthorCount]
+	}
+	d.TopAuthors = shares
+	d.Time = now.UnixNano() / 1000000
+	return d
+}
+
+// knowledgeScope accumulates changes per author of a repository or directory
+type knowledgeScope struct {
+	scope   string
+	path    string
+	shas    map[string]bool
+	authors map[string]*authorChanges
+}
+
+// authorChanges accumulates changes of an author
+type authorChanges struct {
+	identity enrichment.Identity
+	team     string
+	shas     map[string]bool
+	lines    int
+}
+
+// newKnowledgeScope returns an empty scope
+func newKnowledgeScope(scope string, path string) *knowledgeScope {
+	return &knowledgeScope{scope: scope, path: path, shas: make(map[string]bool), authors: make(map[string]*authorChanges)}
+}
+
+// add adds lines changed in a file by a commit of author
+func (s *knowledgeScope) add(sha string, id enrichment.Identity, team string, lines int) {
+	s.shas[sha] = true
+	a, ok := s.authors[id.Canonical]
+	if !ok {
+		a = &authorChanges{identity: id, team: team, shas: make(map[string]bool)}
+		s.authors[id.Canonical] = a
+	}
+	a.shas[sha] = true
+	a.lines += lines
+}
+
+// commits returns the number of commits changing the scope
+func (s *knowledgeScope) commits() int {
+	return len(s.shas)
+}
+
+// shares returns the shares of authors in lines changed , largest first. Commits are used instead of lines
+// if no lines are changed , as for binary files.
+func (s *knowledgeScope) shares() []AuthorShare {
+	shares := make([]AuthorShare, 0, len(s.authors))
+	total, totalCommits := 0, 0
+	for _, a := range s.authors {
+		total += a.lines
+		totalCommits += len(a.shas)
+	}
+	for key, a := range s.authors {
+		share := AuthorShare{Author: key, Name: a.identity.Name, Team: a.team, Commits: len(a.shas), LinesChanged: a.lines}
+		if total > 0 {
+			share.Share = float64(a.lines) / float64(total)
+		} else if totalCommits > 0 {
+			share.Share = float64(len(a.shas)) / float64(totalCommits)
+		}
+		shares = append(shares, share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Share != shares[j].Share {
+			return shares[i].Share > shares[j].Share
+		}
+		return shares[i].Author < shares[j].Author
+	})
+	return shares
+}
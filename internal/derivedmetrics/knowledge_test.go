@@ -0,0 +1,64 @@
+package derivedmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestKnowledgeAnalyzer_Analyze(t *testing.T) {
+	now := time.Date(2022, 10, 30, 0, 0, 0, 0, time.UTC)
+	ka := NewKnowledgeAnalyzer("testRepo", "", input.KnowledgeConfig{Window: "90d"}, enrichment.NewIdentityResolver(input.IdentityConfig{}))
+	ka.MetricFormator = &metricformator.MetricFormator{}
+	commit := func(sha string, name string, email string, at time.Time, files ...dataprocessor.FileChange) CommitFiles {
+		c := dataprocessor.Commit{Sha: sha, Author: dataprocessor.User{User: name, Email: email}, CreatedAt: at}
+		return CommitFiles{Commit: c, Files: files}
+	}
+	change := func(path string, lines int) dataprocessor.FileChange {
+		return dataprocessor.FileChange{Path: path, Additions: lines}
+	}
+	recent := now.Add(-24 * time.Hour)
+	commits := []CommitFiles{
+		commit("a", "Dev One", "dev1@example.com", recent, change("api/server.go", 60), change("docs/guide.md", 10)),
+		commit("b", "Dev One", "Dev1@Example.com", recent, change("api/routes.go", 20)),
+		commit("c", "Dev Two", "dev2@example.com", recent, change("api/server.go", 10), change("docs/guide.md", 10)),
+		commit("d", "Dev Three", "dev3@example.com", recent, change("docs/intro.md", 10), change("README.md", 30)),
+		commit("e", "dependabot[bot]", "49699333+dependabot[bot]@users.noreply.github.com", recent, change("api/go.mod", 500)),
+		commit("f", "Dev Two", "dev2@example.com", now.Add(-100*24*time.Hour), change("docs/guide.md", 500)),
+	}
+	var distributions []KnowledgeDistribution
+	if err := dataprocessor.DecodeDocuments(ka.Analyze(commits, now, nil), &distributions); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	type want struct {
+		path         string
+		commits      int
+		lines        int
+		authors      int
+		for50        int
+		for80        int
+		singleOwner  bool
+		topAuthor    string
+		topAuthorPct float64
+	}
+	wants := []want{
+		// dev1 90 , dev3 40 , dev2 20 of 150 lines
+		{"", 4, 150, 3, 1, 2, true, "dev1@example.com", 0.6},
+		{"api", 3, 90, 2, 1, 1, true, "dev1@example.com", 80.0 / 90},
+		{"docs", 3, 30, 3, 2, 3, false, "dev1@example.com", 1.0 / 3},
+	}
+	if len(distributions) != len(wants) {
+		t.Fatalf("Analyze() returned %v distributions, want %v", len(distributions), len(wants))
+	}
+	for i, w := range wants {
+		d := distributions[i]
+		got := want{d.Path, d.Commits, d.LinesChanged, d.Authors, d.AuthorsFor50Percent, d.AuthorsFor80Percent, d.SingleOwner, d.TopAuthors[0].Author, d.TopAuthors[0].Share}
+		if got != w {
+			t.Errorf("Analyze()[%v] = %+v, want %+v", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package derivedmetrics
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hunkHeader matches the header of a hunk in unified diff , line counts are omitted when they are 1
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// hunk represents the old and new line ranges of a hunk in unified diff
+type hunk struct {
+	oldStart int
+	oldLines int
+	newStart int
+	newLines int
+}
+
+// freshLines holds the time each recently written line of a file was written , with line numbers as of the latest change
+type freshLines map[int]time.Time
+
+// parseHunk parses a hunk header , ok is false if line is not a hunk header
+func parseHunk(line string) (hunk, bool) {
+	m := hunkHeader.FindStringSubmatch(line)
+	if m == nil {
+		return hunk{}, false
+	}
+	number := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	return hunk{oldStart: number(m[1]), oldLines: number(m[2]), newStart: number(m[3]), newLines: number(m[4])}, true
+}
+
+// before checks if hunk changes only lines before old line l , so l is shifted by the hunk
+func (h hunk) before(l int) bool {
+	// hunks without old lines insert after their start line
+	if h.oldLines == 0 {
+		return h.oldStart < l
+	}
+	return h.oldStart+h.oldLines-1 < l
+}
+
+// applyPatch applies the patch of a change made at to the fresh lines of a file. It returns the fresh lines after the change
+// and the number of removed lines which were written after since , which are the lines reworked by the change.
+// Lines written before since are dropped from fresh lines.
+func applyPatch(fresh freshLines, patch string, at time.Time, since time.Time) (freshLines, int) {
+	next := make(freshLines)
+	rework := 0
+	var hunks []hunk
+	deleted := make(map[int]bool)
+	// moved holds new line number of unchanged lines inside hunks with old line number as key
+	moved := make(map[int]int)
+	oldLine, newLine := 0, 0
+	for _, line := range strings.Split(patch, "\n") {
+		if h, ok := parseHunk(line); ok {
+			hunks = append(hunks, h)
+			oldLine, newLine = h.oldStart, h.newStart
+			continue
+		}
+		if len(hunks) == 0 || line == "" {
+			continue
+		}
+		switch line[0] {
+		case ' ':
+			moved[oldLine] = newLine
+			oldLine++
+			newLine++
+		case '-':
+			deleted[oldLine] = true
+			if t, ok := fresh[oldLine]; ok && !t.Before(since) {
+				rework++
+			}
+			oldLine++
+		case '+':
+			next[newLine] = at
+			newLine++
+		}
+	}
+	for l, t := range fresh {
+		if deleted[l] || t.Before(since) {
+			continue
+		}
+		if n, ok := moved[l]; ok {
+			next[n] = t
+			continue
+		}
+		delta := 0
+		for _, h := range hunks {
+			if h.before(l) {
+				delta += h.newLines - h.oldLines
+			}
+		}
+		next[l+delta] = t
+	}
+	return next, rework
+}
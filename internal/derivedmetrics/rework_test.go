@@ -0,0 +1,70 @@
+package derivedmetrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyPatch(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 10, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name       string
+		fresh      freshLines
+		patch      string
+		since      time.Time
+		wantRework int
+		wantFresh  freshLines
+	}{
+		{
+			name:       "new file",
+			fresh:      freshLines{},
+			patch:      "@@ -0,0 +1,3 @@\n+a\n+b\n+c",
+			since:      day(1),
+			wantRework: 0,
+			wantFresh:  freshLines{1: day(10), 2: day(10), 3: day(10)},
+		},
+		{
+			name:  "change of a fresh line shifts lines below",
+			fresh: freshLines{1: day(5), 2: day(5), 3: day(5)},
+			// line 2 is replaced by two lines
+			patch:      "@@ -1,3 +1,4 @@\n a\n-b\n+b1\n+b2\n c",
+			since:      day(1),
+			wantRework: 1,
+			wantFresh:  freshLines{1: day(5), 2: day(10), 3: day(10), 4: day(5)},
+		},
+		{
+			name:  "lines outside hunks are shifted by hunks before them",
+			fresh: freshLines{2: day(5), 20: day(5)},
+			// one line inserted after line 10
+			patch:      "@@ -10,0 +11 @@\n+x",
+			since:      day(1),
+			wantRework: 0,
+			wantFresh:  freshLines{2: day(5), 11: day(10), 21: day(5)},
+		},
+		{
+			name:       "lines written before rework window are not rework",
+			fresh:      freshLines{1: day(2), 2: day(5)},
+			patch:      "@@ -1,2 +0,0 @@\n-a\n-b",
+			since:      day(3),
+			wantRework: 1,
+			wantFresh:  freshLines{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotFresh, gotRework := applyPatch(tt.fresh, tt.patch, day(10), tt.since)
+			if gotRework != tt.wantRework {
+				t.Errorf("applyPatch() rework = %v, want %v", gotRework, tt.wantRework)
+			}
+			if len(gotFresh) != len(tt.wantFresh) {
+				t.Fatalf("applyPatch() fresh = %v, want %v", gotFresh, tt.wantFresh)
+			}
+			for l, at := range tt.wantFresh {
+				if !gotFresh[l].Equal(at) {
+					t.Errorf("applyPatch() fresh = %v, want %v", gotFresh, tt.wantFresh)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package derivedmetrics
+
+import (
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/metricformator"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	SLASTATUS = "sla_status"
+	SLABREACH = "sla_breach"
+
+	// kinds of sla in a rule
+	SLAFirstResponse = "first_response"
+	SLAClose         = "close"
+
+	// status of a sla
+	SLAStatusPending  = "pending"
+	SLAStatusMet      = "met"
+	SLAStatusBreached = "breached"
+
+	issueStateClosed = "closed"
+)
+
+// SLAState represents the sla tracking state of an open issue , saved between runs
+type SLAState struct {
+	// FirstResponseAt represents at what time the first comment by someone other than author was made
+	FirstResponseAt time.Time
+
+	// CommentsCheckedAt represents the updated time of issue when comments were last checked for first response ,
+	// comments are checked again only when the issue is updated after it
+	CommentsCheckedAt time.Time
+
+	// Breaches holds breaches already published with rule name and sla kind as key
+	Breaches map[string]bool
+}
+
+// SLAStatus represents the sla status of an issue for a rule at the time of evaluation
+type SLAStatus struct {
+	// DocumentType is "sla_status"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the status was evaluated
+	CreatedAt time.Time `json:"created_at"`
+
+	// Rule is the name of sla rule
+	Rule string `json:"rule"`
+
+	// IssueNo is issue number
+	IssueNo string `json:"issue_no"`
+
+	// Title represents issue title
+	Title string `json:"title"`
+
+	// URL is api url to issue
+	URL string `json:"url"`
+
+	// State represents the state of issue
+	State string `json:"state"`
+
+	// Labels represents issue labels
+	Labels []string `json:"labels"`
+
+	// CreatedBy shows the user who created the issue
+	CreatedBy dataprocessor.User `json:"created_by"`
+
+	// IssueCreatedAt represents at what time the issue is created
+	IssueCreatedAt time.Time `json:"issue_created_at"`
+
+	// FirstResponseAt represents at what time the issue got first response
+	FirstResponseAt time.Time `json:"first_response_at"`
+
+	// FirstResponseDueAt represents the deadline for first response
+	FirstResponseDueAt time.Time `json:"first_response_due_at"`
+
+	// TimeToFirstResponseSeconds is time taken for first response , or time elapsed without response
+	TimeToFirstResponseSeconds int64 `json:"time_to_first_response_seconds"`
+
+	// FirstResponseStatus is pending , met or breached , empty if rule has no first response limit
+	FirstResponseStatus string `json:"first_response_status"`
+
+	// ClosedAt represents at what time the issue is closed
+	ClosedAt string `json:"closed_at"`
+
+	// CloseDueAt represents the deadline for closing the issue
+	CloseDueAt time.Time `json:"close_due_at"`
+
+	// TimeToCloseSeconds is time taken to close , or age of the issue if still open
+	TimeToCloseSeconds int64 `json:"time_to_close_seconds"`
+
+	// CloseStatus is pending , met or breached , empty if rule has no close limit
+	CloseStatus string `json:"close_status"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// SLABreach represents a breach of sla , published once for an issue , rule and sla kind
+type SLABreach struct {
+	// DocumentType is "sla_breach"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the breach was detected
+	CreatedAt time.Time `json:"created_at"`
+
+	// Rule is the name of sla rule
+	Rule string `json:"rule"`
+
+	// SLA is the breached sla kind , first_response or close
+	SLA string `json:"sla"`
+
+	// IssueNo is issue number
+	IssueNo string `json:"issue_no"`
+
+	// Title represents issue title
+	Title string `json:"title"`
+
+	// URL is api url to issue
+	URL string `json:"url"`
+
+	// State represents the state of issue when breach was detected
+	State string `json:"state"`
+
+	// Labels represents issue labels
+	Labels []string `json:"labels"`
+
+	// CreatedBy shows the user who created the issue
+	CreatedBy dataprocessor.User `json:"created_by"`
+
+	// IssueCreatedAt represents at what time the issue is created
+	IssueCreatedAt time.Time `json:"issue_created_at"`
+
+	// DueAt represents the deadline which is breached
+	DueAt time.Time `json:"due_at"`
+
+	// LimitSeconds is the time limit of sla
+	LimitSeconds int64 `json:"limit_seconds"`
+
+	// ElapsedSeconds is the time taken , or elapsed so far if sla is still not met
+	ElapsedSeconds int64 `json:"elapsed_seconds"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// slaRule is a configured sla rule with parsed time limits
+type slaRule struct {
+	input.SLARule
+	firstResponse time.Duration
+	close         time.Duration
+	businessHours *workingHours
+}
+
+// SLAEvaluator evaluates issues of a repository against sla rules
+type SLAEvaluator struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+
+	rules []slaRule
+}
+
+// NewSLAEvaluator returns a new sla evaluator for a repository
+func NewSLAEvaluator(repoName string, repoURL string, rules []input.SLARule) *SLAEvaluator {
+	se := new(SLAEvaluator)
+	se.RepoName = repoName
+	se.RepoURL = repoURL
+	se.MetricFormator = metricformator.NewMetricFormator()
+	for _, r := range rules {
+		rule := slaRule{SLARule: r}
+		// limits are validated with config
+		if r.FirstResponse != "" {
+			rule.firstResponse, _ = utils.ParseDuration(r.FirstResponse)
+		}
+		if r.Close != "" {
+			rule.close, _ = utils.ParseDuration(r.Close)
+		}
+		if r.BusinessHours != nil {
+			// working hours without working days have no time to count , they are not used
+			if wh := parseWorkingHours(*r.BusinessHours); len(wh.days) > 0 {
+				rule.businessHours = &wh
+			}
+		}
+		se.rules = append(se.rules, rule)
+	}
+	return se
+}
+
+// Matches checks if any rule applies to the issue
+func (se *SLAEvaluator) Matches(issue dataprocessor.Issue) bool {
+	for _, r := range se.rules {
+		if r.matches(issue) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate prepares sla status and sla breach output documents for issues at now. states holds the state of
+// issues with issue number as key , the returned states only contain issues that are still open.
+func (se *SLAEvaluator) Evaluate(issues []dataprocessor.Issue, states map[string]SLAState, now time.Time, tags map[string]string) ([]interface{}, map[string]SLAState) {
+	statuses := make([]SLAStatus, 0)
+	breaches := make([]SLABreach, 0)
+	openStates := make(map[string]SLAState)
+	for _, issue := range issues {
+		state := states[issue.IssueNo]
+		breached := make(map[string]bool, len(state.Breaches))
+		for k, v := range state.Breaches {
+			breached[k] = v
+		}
+		// time is stopped when the issue is closed
+		end := now
+		closedAt, err := time.Parse(time.RFC3339, issue.ClosedAt)
+		isClosed := issue.State == issueStateClosed && err == nil
+		if isClosed {
+			end = closedAt
+		}
+		for _, r := range se.rules {
+			if !r.matches(issue) {
+				continue
+			}
+			s := se.newStatus(issue, r, now)
+			if r.firstResponse > 0 {
+				s.FirstResponseAt = state.FirstResponseAt
+				s.FirstResponseDueAt = r.dueAt(issue.CreatedAt, r.firstResponse)
+				respondedAt := end
+				if !state.FirstResponseAt.IsZero() {
+					respondedAt = state.FirstResponseAt
+				}
+				elapsed := r.elapsed(issue.CreatedAt, respondedAt)
+				s.TimeToFirstResponseSeconds = int64(elapsed.Seconds())
+				s.FirstResponseStatus = slaStatus(elapsed, r.firstResponse, !state.FirstResponseAt.IsZero() || isClosed)
+				if s.FirstResponseStatus == SLAStatusBreached && !breached[r.Name+"$"+SLAFirstResponse] {
+					breached[r.Name+"$"+SLAFirstResponse] = true
+					breaches = append(breaches, se.newBreach(issue, r, SLAFirstResponse, r.firstResponse, elapsed, now))
+				}
+			}
+			if r.close > 0 {
+				s.ClosedAt = issue.ClosedAt
+				s.CloseDueAt = r.dueAt(issue.CreatedAt, r.close)
+				elapsed := r.elapsed(issue.CreatedAt, end)
+				s.TimeToCloseSeconds = int64(elapsed.Seconds())
+				s.CloseStatus = slaStatus(elapsed, r.close, isClosed)
+				if s.CloseStatus == SLAStatusBreached && !breached[r.Name+"$"+SLAClose] {
+					breached[r.Name+"$"+SLAClose] = true
+					breaches = append(breaches, se.newBreach(issue, r, SLAClose, r.close, elapsed, now))
+				}
+			}
+			statuses = append(statuses, s)
+		}
+		if !isClosed {
+			state.Breaches = breached
+			openStates[issue.IssueNo] = state
+		}
+	}
+	docs := formatDocuments(se.MetricFormator, statuses, tags)
+	docs = append(docs, formatDocuments(se.MetricFormator, breaches, tags)...)
+	return docs, openStates
+}
+
+// newStatus returns sla status of issue for rule without the sla specific fields
+func (se *SLAEvaluator) newStatus(issue dataprocessor.Issue, r slaRule, now time.Time) SLAStatus {
+	var s SLAStatus
+	s.DocumentType = SLASTATUS
+	s.RepoType = dataprocessor.GITHUB
+	s.RepoName = se.RepoName
+	s.RepoURL = se.RepoURL
+	s.CreatedAt = now
+	s.Rule = r.Name
+	s.IssueNo = issue.IssueNo
+	s.Title = issue.Title
+	s.URL = issue.URL
+	s.State = issue.State
+	s.Labels = issue.Labels
+	s.CreatedBy = issue.CreatedBy
+	s.IssueCreatedAt = issue.CreatedAt
+	s.Time = now.UnixNano() / 1000000
+	return s
+}
+
+// newBreach returns sla breach of issue for a kind of sla in rule
+func (se *SLAEvaluator) newBreach(issue dataprocessor.Issue, r slaRule, kind string, limit time.Duration, elapsed time.Duration, now time.Time) SLABreach {
+	var b SLABreach
+	b.DocumentType = SLABREACH
+	b.RepoType = dataprocessor.GITHUB
+	b.RepoName = se.RepoName
+	b.RepoURL = se.RepoURL
+	b.CreatedAt = now
+	b.Rule = r.Name
+	b.SLA = kind
+	b.IssueNo = issue.IssueNo
+	b.Title = issue.Title
+	b.URL = issue.URL
+	b.State = issue.State
+	b.Labels = issue.Labels
+	b.CreatedBy = issue.CreatedBy
+	b.IssueCreatedAt = issue.CreatedAt
+	b.DueAt = r.dueAt(issue.CreatedAt, limit)
+	b.LimitSeconds = int64(limit.Seconds())
+	b.ElapsedSeconds = int64(elapsed.Seconds())
+	b.Time = now.UnixNano() / 1000000
+	return b
+}
+
+// matches checks if issue has any of the rule labels , rules without labels match all issues
+func (r slaRule) matches(issue dataprocessor.Issue) bool {
+	if len(r.Labels) == 0 {
+		return true
+	}
+	for _, want := range r.Labels {
+		for _, l := range issue.Labels {
+			if l == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// elapsed returns the time counted for sla between from and to , only the time within business hours if rule has them
+func (r slaRule) elapsed(from time.Time, to time.Time) time.Duration {
+	if r.businessHours == nil {
+		return to.Sub(from)
+	}
+	var elapsed time.Duration
+	for day := r.businessHours.dayOf(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		start, end, ok := r.businessHours.hoursOf(day)
+		if !ok {
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			elapsed += end.Sub(start)
+		}
+	}
+	return elapsed
+}
+
+// dueAt returns the time at which limit is reached counting from from , only the time within business hours is
+// counted if rule has them
+func (r slaRule) dueAt(from time.Time, limit time.Duration) time.Time {
+	if r.businessHours == nil {
+		return from.Add(limit)
+	}
+	remaining := limit
+	// working days are validated to be at least one per week , so a week always has business hours
+	for day := r.businessHours.dayOf(from); ; day = day.AddDate(0, 0, 1) {
+		start, end, ok := r.businessHours.hoursOf(day)
+		if !ok || !end.After(from) {
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.Sub(start) >= remaining {
+			return start.Add(remaining)
+		}
+		remaining -= end.Sub(start)
+	}
+}
+
+// slaStatus returns the status of a sla , done is true when the sla can no more change like for a closed issue
+func slaStatus(elapsed time.Duration, limit time.Duration, done bool) string {
+	if elapsed > limit {
+		return SLAStatusBreached
+	}
+	if done {
+		return SLAStatusMet
+	}
+	return SLAStatusPending
+}
@@ -0,0 +1,129 @@
+package derivedmetrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+)
+
+func newTestSLAEvaluator() *SLAEvaluator {
+	businessHours := &input.WorkingHours{Timezone: "UTC", Start: "09:00", End: "18:00", Days: input.DefaultWorkingDays}
+	return NewSLAEvaluator("testRepo", "", []input.SLARule{
+		{Name: "sev1", Labels: []string{"sev1"}, FirstResponse: "4h", Close: "3d"},
+		{Name: "support", Labels: []string{"support"}, FirstResponse: "4h", BusinessHours: businessHours},
+	})
+}
+
+func TestSLAEvaluator_Matches(t *testing.T) {
+	se := newTestSLAEvaluator()
+	tests := []struct {
+		name   string
+		labels []string
+		want   bool
+	}{
+		{"label of a rule", []string{"bug", "sev1"}, true},
+		{"label of business hours rule", []string{"support"}, true},
+		{"no matching label", []string{"bug"}, false},
+		{"no labels", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := se.Matches(dataprocessor.Issue{Labels: tt.labels}); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSLAEvaluator_Evaluate(t *testing.T) {
+	se := newTestSLAEvaluator()
+	// monday
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2022, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+	issue := func(label string, createdAt time.Time, closedAt string) dataprocessor.Issue {
+		i := dataprocessor.Issue{IssueNo: "1", State: "open", Labels: []string{label}, CreatedAt: createdAt, ClosedAt: closedAt}
+		if closedAt != "" {
+			i.State = issueStateClosed
+		}
+		return i
+	}
+	tests := []struct {
+		name          string
+		issue         dataprocessor.Issue
+		state         SLAState
+		wantStatus    map[string]string
+		wantBreaches  map[string]time.Time
+		wantOpenState *SLAState
+	}{
+		{
+			// 2h on friday and 3h on monday
+			name:          "business hours breach",
+			issue:         issue("support", at(7, 16, 0), ""),
+			wantStatus:    map[string]string{"first_response_status": SLAStatusBreached, "first_response_due_at": "2022-10-10T11:00:00Z"},
+			wantBreaches:  map[string]time.Time{SLAFirstResponse: at(10, 11, 0)},
+			wantOpenState: &SLAState{Breaches: map[string]bool{"support$first_response": true}},
+		},
+		{
+			// 30m on friday and 3h on monday , weekend is not counted
+			name:          "business hours pending",
+			issue:         issue("support", at(7, 17, 30), ""),
+			wantStatus:    map[string]string{"first_response_status": SLAStatusPending, "first_response_due_at": "2022-10-10T12:30:00Z"},
+			wantOpenState: &SLAState{Breaches: map[string]bool{}},
+		},
+		{
+			name:         "closed issue final evaluation",
+			issue:        issue("sev1", at(6, 12, 0), "2022-10-10T10:00:00Z"),
+			state:        SLAState{FirstResponseAt: at(6, 14, 0)},
+			wantStatus:   map[string]string{"first_response_status": SLAStatusMet, "close_status": SLAStatusBreached, "closed_at": "2022-10-10T10:00:00Z"},
+			wantBreaches: map[string]time.Time{SLAClose: at(9, 12, 0)},
+		},
+		{
+			name:  "state carry-over",
+			issue: issue("sev1", at(9, 0, 0), ""),
+			state: SLAState{FirstResponseAt: at(9, 6, 0), CommentsCheckedAt: at(9, 6, 0), Breaches: map[string]bool{"sev1$first_response": true}},
+			wantStatus: map[string]string{"first_response_status": SLAStatusBreached, "first_response_at": "2022-10-09T06:00:00Z",
+				"close_status": SLAStatusPending},
+			wantOpenState: &SLAState{FirstResponseAt: at(9, 6, 0), CommentsCheckedAt: at(9, 6, 0), Breaches: map[string]bool{"sev1$first_response": true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, openStates := se.Evaluate([]dataprocessor.Issue{tt.issue}, map[string]SLAState{"1": tt.state}, now, nil)
+			breaches := make(map[string]time.Time)
+			for _, d := range docs {
+				doc := d.(map[string]interface{})
+				switch doc["document_type"] {
+				case SLASTATUS:
+					for k, want := range tt.wantStatus {
+						if doc[k] != want {
+							t.Errorf("Evaluate() status %v = %v, want %v", k, doc[k], want)
+						}
+					}
+				case SLABREACH:
+					dueAt, _ := time.Parse(time.RFC3339, doc["due_at"].(string))
+					breaches[doc["sla"].(string)] = dueAt
+				}
+			}
+			if len(breaches) != len(tt.wantBreaches) {
+				t.Errorf("Evaluate() breaches = %v, want %v", breaches, tt.wantBreaches)
+			}
+			for kind, want := range tt.wantBreaches {
+				if !breaches[kind].Equal(want) {
+					t.Errorf("Evaluate() breach %v due at %v, want %v", kind, breaches[kind], want)
+				}
+			}
+			state, open := openStates["1"]
+			if open != (tt.wantOpenState != nil) {
+				t.Fatalf("Evaluate() open states = %v, want %v", openStates, tt.wantOpenState)
+			}
+			if open && !reflect.DeepEqual(state, *tt.wantOpenState) {
+				t.Errorf("Evaluate() open state = %v, want %v", state, *tt.wantOpenState)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package derivedmetrics
+
+import (
+	"strings"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/metricformator"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	STALEPULLREQUEST = "stale_pull_request"
+	STALEBRANCH      = "stale_branch"
+
+	// reasons of stale branches
+	StaleReasonInactive = "inactive"
+	StaleReasonMerged   = "merged"
+
+	// zeroSha is the before sha of the push creating a branch
+	zeroSha = "0000000000000000000000000000000000000000"
+)
+
+// StalePullRequest represents an open pull request without activity for longer than the configured age
+type StalePullRequest struct {
+	// DocumentType is "stale_pull_request"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the report is made
+	CreatedAt time.Time `json:"created_at"`
+
+	// PullRequestNo represents pull request number
+	PullRequestNo string `json:"pull_request_no"`
+
+	// Title represents pull request title
+	Title string `json:"title"`
+
+	// URL is the api url for pull request
+	URL string `json:"url"`
+
+	// Branch is the branch pull request is raised from
+	Branch string `json:"branch"`
+
+	// BaseBranch is the branch pull request will be merged to
+	BaseBranch string `json:"base_branch"`
+
+	// CreatedBy shows the user who opened the pull request
+	CreatedBy dataprocessor.User `json:"created_by"`
+
+	// PendingReviewers are the requested reviewers who have not reviewed yet
+	PendingReviewers []dataprocessor.User `json:"pending_reviewers"`
+
+	// OpenedAt represents at what time the pull request is opened
+	OpenedAt time.Time `json:"opened_at"`
+
+	// LastActivityAt represents at what time the pull request is last updated
+	LastActivityAt time.Time `json:"last_activity_at"`
+
+	// LastActivityAgeSeconds is the time since last activity in seconds
+	LastActivityAgeSeconds int64 `json:"last_activity_age_seconds"`
+
+	// LastActivityAgeDays is the time since last activity in whole days
+	LastActivityAgeDays int64 `json:"last_activity_age_days"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// StaleBranch represents a branch without commits for longer than the configured age
+type StaleBranch struct {
+	// DocumentType is "stale_branch"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the report is made
+	CreatedAt time.Time `json:"created_at"`
+
+	// Branch is the stale branch
+	Branch string `json:"branch"`
+
+	// Reason is merged for branches merged to default branch but not deleted and inactive for others
+	Reason string `json:"reason"`
+
+	// MergedPullRequestNo is the number of pull request the branch is merged by , empty if not known
+	MergedPullRequestNo string `json:"merged_pull_request_no"`
+
+	// HeadSha is the sha of head commit
+	HeadSha string `json:"head_sha"`
+
+	// Protected is whether branch is protected
+	Protected bool `json:"protected"`
+
+	// AheadBy is the number of commits of branch missing in default branch
+	AheadBy int `json:"ahead_by"`
+
+	// BehindBy is the number of commits of default branch missing in branch
+	BehindBy int `json:"behind_by"`
+
+	// Author shows the author of head commit
+	Author dataprocessor.User `json:"author"`
+
+	// OpenPullRequests are the numbers of open pull requests raised from branch
+	OpenPullRequests []string `json:"open_pull_requests"`
+
+	// PendingReviewers are the requested reviewers of open pull requests raised from branch
+	PendingReviewers []dataprocessor.User `json:"pending_reviewers"`
+
+	// LastActivityAt represents at what time the head commit is created
+	LastActivityAt time.Time `json:"last_activity_at"`
+
+	// LastActivityAgeSeconds is the time since last activity in seconds
+	LastActivityAgeSeconds int64 `json:"last_activity_age_seconds"`
+
+	// LastActivityAgeDays is the time since last activity in whole days
+	LastActivityAgeDays int64 `json:"last_activity_age_days"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// StaleReporter finds stale open pull requests and branches of a repository
+type StaleReporter struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+
+	pullRequestAge  time.Duration
+	branchAge       time.Duration
+	mergedBranchAge time.Duration
+	excluded        map[string]bool
+}
+
+// NewStaleReporter returns a new stale reporter for a repository , branches of the audit job and excluded branches
+// of config are never reported
+func NewStaleReporter(repoName string, repoURL string, branches []string, cfg input.StaleConfig) *StaleReporter {
+	sr := new(StaleReporter)
+	sr.RepoName = repoName
+	sr.RepoURL = repoURL
+	sr.MetricFormator = metricformator.NewMetricFormator()
+	// ages are validated while reading config
+	sr.pullRequestAge, _ = utils.ParseDuration(cfg.PullRequestAge)
+	sr.branchAge, _ = utils.ParseDuration(cfg.BranchAge)
+	sr.mergedBranchAge, _ = utils.ParseDuration(cfg.MergedBranchAge)
+	sr.excluded = make(map[string]bool)
+	for _, br := range append(append([]string(nil), branches...), cfg.ExcludeBranches...) {
+		sr.excluded[br] = true
+	}
+	return sr
+}
+
+// Report prepares stale pull request and stale branch output documents from open pull requests and branches at now.
+// A branch is merged if a pull request from it is merged at its head commit. Otherwise a branch not ahead of default branch
+// is taken as merged only if its head commit is older than the push creating the branch , found in pushes , so that it holds
+// no commit made on the branch. Branches created before the oldest push event are not taken as merged without a pull request.
+func (sr *StaleReporter) Report(pullRequests []dataprocessor.PullRequest, branches []dataprocessor.Branch, pushes []dataprocessor.Push, now time.Time, tags map[string]string) []interface{} {
+	stalePullRequests := make([]StalePullRequest, 0)
+	// open pull requests raised from branches of the repository itself , forks have branches of their own
+	branchPullRequests := make(map[string][]dataprocessor.PullRequest)
+	for _, pr := range pullRequests {
+		if pr.RequestFromRepo.Name == pr.MergeToRepo.Name {
+			branchPullRequests[pr.RequestFromRepo.Branch] = append(branchPullRequests[pr.RequestFromRepo.Branch], pr)
+		}
+		if now.Sub(pr.UpdatedAt) < sr.pullRequestAge {
+			continue
+		}
+		var s StalePullRequest
+		s.DocumentType = STALEPULLREQUEST
+		s.RepoType = dataprocessor.GITHUB
+		s.RepoName = sr.RepoName
+		s.RepoURL = sr.RepoURL
+		s.CreatedAt = now
+		s.PullRequestNo = pr.PullRequestNo
+		s.Title = pr.Title
+		s.URL = pr.URL
+		s.Branch = pr.RequestFromRepo.Branch
+		s.BaseBranch = pr.MergeToRepo.Branch
+		s.CreatedBy = pr.CreatedBy
+		s.PendingReviewers = make([]dataprocessor.User, 0, len(pr.Reviewers))
+		s.PendingReviewers = append(s.PendingReviewers, pr.Reviewers...)
+		s.OpenedAt = pr.CreatedAt
+		s.LastActivityAt = pr.UpdatedAt
+		s.LastActivityAgeSeconds, s.LastActivityAgeDays = activityAge(pr.UpdatedAt, now)
+		s.Time = now.UnixNano() / 1000000
+		stalePullRequests = append(stalePullRequests, s)
+	}
+
+	// time of first push of branches created within the push events kept by git provider
+	firstPushAt := make(map[string]time.Time)
+	for _, p := range pushes {
+		if p.Before != zeroSha || !strings.HasPrefix(p.Ref, "refs/heads/") {
+			continue
+		}
+		name := strings.TrimPrefix(p.Ref, "refs/heads/")
+		if at, ok := firstPushAt[name]; !ok || p.CreatedAt.Before(at) {
+			firstPushAt[name] = p.CreatedAt
+		}
+	}
+
+	staleBranches := make([]StaleBranch, 0)
+	for _, b := range branches {
+		if sr.excluded[b.Name] || b.Name == b.DefaultBranch {
+			continue
+		}
+		reason, age := StaleReasonInactive, sr.branchAge
+		pushedAt, pushKnown := firstPushAt[b.Name]
+		if b.MergedPullRequestNo != "" || (b.AheadBy == 0 && pushKnown && b.LastCommitAt.Before(pushedAt)) {
+			reason, age = StaleReasonMerged, sr.mergedBranchAge
+		}
+		if now.Sub(b.LastCommitAt) < age {
+			continue
+		}
+		var s StaleBranch
+		s.DocumentType = STALEBRANCH
+		s.RepoType = dataprocessor.GITHUB
+		s.RepoName = sr.RepoName
+		s.RepoURL = sr.RepoURL
+		s.CreatedAt = now
+		s.Branch = b.Name
+		s.Reason = reason
+		s.MergedPullRequestNo = b.MergedPullRequestNo
+		s.HeadSha = b.HeadSha
+		s.Protected = b.Protected
+		s.AheadBy = b.AheadBy
+		s.BehindBy = b.BehindBy
+		s.Author = b.Author
+		s.OpenPullRequests = make([]string, 0)
+		s.PendingReviewers = make([]dataprocessor.User, 0)
+		seen := make(map[string]bool)
+		for _, pr := range branchPullRequests[b.Name] {
+			s.OpenPullRequests = append(s.OpenPullRequests, pr.PullRequestNo)
+			for _, r := range pr.Reviewers {
+				if !seen[r.ID] {
+					seen[r.ID] = true
+					s.PendingReviewers = append(s.PendingReviewers, r)
+				}
+			}
+		}
+		s.LastActivityAt = b.LastCommitAt
+		s.LastActivityAgeSeconds, s.LastActivityAgeDays = activityAge(b.LastCommitAt, now)
+		s.Time = now.UnixNano() / 1000000
+		staleBranches = append(staleBranches, s)
+	}
+
+	docs := formatDocuments(sr.MetricFormator, stalePullRequests, tags)
+	return append(docs, formatDocuments(sr.MetricFormator, staleBranches, tags)...)
+}
+
+// activityAge returns the time since last activity in seconds and in whole days
+func activityAge(lastActivity time.Time, now time.Time) (int64, int64) {
+	age := now.Sub(lastActivity)
+	return int64(age.Seconds()), int64(age / (24 * time.Hour))
+}
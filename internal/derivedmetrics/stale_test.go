@@ -0,0 +1,85 @@
+package derivedmetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+)
+
+func TestStaleReporter_Report(t *testing.T) {
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	days := func(d int) time.Time { return now.Add(-time.Duration(d) * 24 * time.Hour) }
+	cfg := input.StaleConfig{Enabled: true, PullRequestAge: "14d", BranchAge: "30d", MergedBranchAge: "1d", ExcludeBranches: []string{"release"}}
+	sr := NewStaleReporter("testRepo", "", []string{"main"}, cfg)
+	reviewer := dataprocessor.User{ID: "2", User: "reviewer"}
+	pr := func(no string, branch string, fork bool, updatedAt time.Time) dataprocessor.PullRequest {
+		p := dataprocessor.PullRequest{PullRequestNo: no, UpdatedAt: updatedAt, Reviewers: []dataprocessor.User{reviewer}}
+		p.RequestFromRepo.Branch = branch
+		p.RequestFromRepo.Name = "org/testRepo"
+		if fork {
+			p.RequestFromRepo.Name = "fork/testRepo"
+		}
+		p.MergeToRepo.Name = "org/testRepo"
+		return p
+	}
+	pullRequests := []dataprocessor.PullRequest{
+		pr("1", "old-feature", false, days(20)),
+		pr("2", "active", false, days(2)),
+		pr("3", "old-feature", true, days(15)),
+	}
+	branch := func(name string, aheadBy int, mergedPullRequestNo string, lastCommitAt time.Time) dataprocessor.Branch {
+		return dataprocessor.Branch{Name: name, DefaultBranch: "main", AheadBy: aheadBy, MergedPullRequestNo: mergedPullRequestNo, LastCommitAt: lastCommitAt}
+	}
+	branches := []dataprocessor.Branch{
+		branch("old-feature", 3, "", days(40)),
+		branch("active", 1, "", days(2)),
+		branch("merged", 0, "", days(2)),
+		branch("squashed", 3, "7", days(2)),
+		branch("just-merged", 0, "8", now.Add(-time.Hour)),
+		branch("committed-on-branch", 0, "", days(5)),
+		branch("created-before-events", 0, "", days(40)),
+		branch("release", 5, "", days(100)),
+		branch("main", 0, "", days(100)),
+	}
+	created := func(name string, at time.Time) dataprocessor.Push {
+		return dataprocessor.Push{Ref: "refs/heads/" + name, Before: zeroSha, Head: "h", CreatedAt: at}
+	}
+	pushes := []dataprocessor.Push{
+		created("merged", days(1)),
+		created("committed-on-branch", days(10)),
+		{Ref: "refs/heads/committed-on-branch", Before: "h", Head: "h2", CreatedAt: days(5)},
+	}
+	var got []string
+	for _, d := range sr.Report(pullRequests, branches, pushes, now, nil) {
+		doc := d.(map[string]interface{})
+		switch doc["document_type"] {
+		case STALEPULLREQUEST:
+			got = append(got, "pr:"+doc["pull_request_no"].(string))
+		case STALEBRANCH:
+			got = append(got, "branch:"+doc["branch"].(string)+":"+doc["reason"].(string))
+		}
+	}
+	want := []string{"pr:1", "pr:3", "branch:old-feature:inactive", "branch:merged:merged", "branch:squashed:merged",
+		"branch:created-before-events:inactive"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Report() = %v, want %v", got, want)
+	}
+
+	var stale []StaleBranch
+	if err := dataprocessor.DecodeDocuments(sr.Report(nil, branches[:1], nil, now, nil), &stale); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	if stale[0].LastActivityAgeDays != 40 || len(stale[0].OpenPullRequests) != 0 {
+		t.Errorf("Report() stale branch = %+v, want 40 days old without pull requests", stale[0])
+	}
+	stale = nil
+	if err := dataprocessor.DecodeDocuments(sr.Report(pullRequests, branches[:1], nil, now, nil)[2:], &stale); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	if len(stale[0].OpenPullRequests) != 1 || stale[0].OpenPullRequests[0] != "1" || len(stale[0].PendingReviewers) != 1 {
+		t.Errorf("Report() stale branch pull requests = %v reviewers = %v, want [1] and one reviewer", stale[0].OpenPullRequests, stale[0].PendingReviewers)
+	}
+}
@@ -0,0 +1,274 @@
+package derivedmetrics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+const (
+	WORKPATTERN = "work_pattern"
+)
+
+// WorkPattern represents the activity of an author in a week by hour of day and day of week in local time
+type WorkPattern struct {
+	// DocumentType is "work_pattern"
+	DocumentType string `json:"document_type"`
+
+	// RepoType represents the git provider
+	RepoType string `json:"repo_type"`
+
+	// RepoName is repository name
+	RepoName string `json:"repo_name"`
+
+	// RepoURL is repository url
+	RepoURL string `json:"repo_url"`
+
+	// CreatedAt represents at what time the report is made
+	CreatedAt time.Time `json:"created_at"`
+
+	// WeekStart represents the start of week , sunday 00:00 UTC
+	WeekStart time.Time `json:"week_start"`
+
+	// WeekEnd represents the end of week
+	WeekEnd time.Time `json:"week_end"`
+
+	// Author is the canonical identity of author
+	Author string `json:"author"`
+
+	// Name is the canonical name of author
+	Name string `json:"name"`
+
+	// Team of author , empty if author is not in any team
+	Team string `json:"team"`
+
+	// Timezone is the timezone of working hours , used for activities whose timezone is not known
+	Timezone string `json:"timezone"`
+
+	// WorkingHoursStart is the start of working hours , for ex. 09:00
+	WorkingHoursStart string `json:"working_hours_start"`
+
+	// WorkingHoursEnd is the end of working hours , for ex. 18:00
+	WorkingHoursEnd string `json:"working_hours_end"`
+
+	// WorkingDays are the working days
+	WorkingDays []string `json:"working_days"`
+
+	// Commits is the number of commits to monitored branches
+	Commits int `json:"commits"`
+
+	// CommitsWithUTCOffset is the number of commits whose local time is as recorded by the author
+	CommitsWithUTCOffset int `json:"commits_with_utc_offset"`
+
+	// Reviews is the number of pull request reviews submitted
+	Reviews int `json:"reviews"`
+
+	// Comments is the number of comments on issues and pull requests
+	Comments int `json:"comments"`
+
+	// Activities is the number of commits , reviews and comments
+	Activities int `json:"activities"`
+
+	// ByHour is the number of activities by local hour of day , starting at 00
+	ByHour []int `json:"by_hour"`
+
+	// ByDayOfWeek is the number of activities by local day of week , starting on sunday
+	ByDayOfWeek []int `json:"by_day_of_week"`
+
+	// AfterHours is the number of activities outside working hours , including the ones on non working days
+	AfterHours int `json:"after_hours"`
+
+	// NonWorkingDays is the number of activities on non working days
+	NonWorkingDays int `json:"non_working_days"`
+
+	// AfterHoursRatio is the fraction of activities outside working hours
+	AfterHoursRatio float64 `json:"after_hours_ratio"`
+
+	// time in milliseconds
+	Time int64 `json:"time"`
+}
+
+// WorkPatternAnalyzer aggregates activity of authors by local hour of day and day of week against working hours of their teams
+type WorkPatternAnalyzer struct {
+	// Repository Name
+	RepoName string
+
+	// Repository URL
+	RepoURL string
+
+	// Metricformator instance to customise derived data
+	MetricFormator *metricformator.MetricFormator
+
+	workingHours map[string]workingHours
+	resolver     *enrichment.IdentityResolver
+}
+
+// workingHours represents parsed working hours of a team
+type workingHours struct {
+	cfg      input.WorkingHours
+	location *time.Location
+	start    int
+	end      int
+	days     map[time.Weekday]bool
+}
+
+// NewWorkPatternAnalyzer returns a new work pattern analyzer for a repository , resolver merges aliases of authors ,
+// detects bots and finds teams of authors
+func NewWorkPatternAnalyzer(repoName string, repoURL string, cfg input.WorkPatternConfig, resolver *enrichment.IdentityResolver) *WorkPatternAnalyzer {
+	wa := new(WorkPatternAnalyzer)
+	wa.RepoName = repoName
+	wa.RepoURL = repoURL
+	wa.MetricFormator = metricformator.NewMetricFormator()
+	wa.workingHours = make(map[string]workingHours)
+	for _, wh := range cfg.WorkingHours {
+		wa.workingHours[wh.Team] = parseWorkingHours(wh)
+	}
+	if _, ok := wa.workingHours[""]; !ok {
+		wa.workingHours[""] = parseWorkingHours(input.WorkingHours{Timezone: input.DefaultWorkingHoursTimezone, Start: input.DefaultWorkingHoursStart, End: input.DefaultWorkingHoursEnd, Days: input.DefaultWorkingDays})
+	}
+	wa.resolver = resolver
+	return wa
+}
+
+// Analyze prepares work pattern output documents for authors with commits , reviews or comments in the week
+// starting at weekStart. Commits with utc offset are counted in the local time of author , other activities
+// in the timezone of working hours of the team of author. Activities by bots are skipped.
+func (wa *WorkPatternAnalyzer) Analyze(commits []dataprocessor.Commit, reviews []dataprocessor.PullRequestReview, comments []dataprocessor.IssueComment, weekStart time.Time, now time.Time, tags map[string]string) []interface{} {
+	weekEnd := weekStart.AddDate(0, 0, 7)
+	patterns := make(map[string]*WorkPattern)
+	add := func(u dataprocessor.User, at time.Time, localKnown bool) *WorkPattern {
+		if at.Before(weekStart) || !at.Before(weekEnd) {
+			return nil
+		}
+		id := wa.resolver.Resolve(u)
+		if wa.resolver.IsBot(u, id) {
+			return nil
+		}
+		p, ok := patterns[id.Canonical]
+		if !ok {
+			p = wa.newPattern(id, weekStart, weekEnd, now)
+			patterns[id.Canonical] = p
+		}
+		wh := wa.teamHours(p.Team)
+		if !localKnown {
+			at = at.In(wh.location)
+		}
+		p.Activities++
+		p.ByHour[at.Hour()]++
+		p.ByDayOfWeek[at.Weekday()]++
+		minute := at.Hour()*60 + at.Minute()
+		if !wh.days[at.Weekday()] {
+			p.NonWorkingDays++
+			p.AfterHours++
+		} else if minute < wh.start || minute >= wh.end {
+			p.AfterHours++
+		}
+		return p
+	}
+	for _, c := range commits {
+		at, localKnown := c.CreatedAt, c.UTCOffset != ""
+		if localKnown {
+			at = c.AuthorLocalTime
+		}
+		if p := add(c.Author, at, localKnown); p != nil {
+			p.Commits++
+			if c.UTCOffset != "" {
+				p.CommitsWithUTCOffset++
+			}
+		}
+	}
+	for _, r := range reviews {
+		if p := add(r.CreatedBy, r.CreatedAt, false); p != nil {
+			p.Reviews++
+		}
+	}
+	for _, c := range comments {
+		if p := add(c.CreatedBy, c.CreatedAt, false); p != nil {
+			p.Comments++
+		}
+	}
+
+	authors := make([]string, 0, len(patterns))
+	for author := range patterns {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+	workPatterns := make([]WorkPattern, 0, len(authors))
+	for _, author := range authors {
+		p := patterns[author]
+		p.AfterHoursRatio = float64(p.AfterHours) / float64(p.Activities)
+		workPatterns = append(workPatterns, *p)
+	}
+	return formatDocuments(wa.MetricFormator, workPatterns, tags)
+}
+
+// newPattern returns an empty work pattern of an author
+func (wa *WorkPatternAnalyzer) newPattern(id enrichment.Identity, weekStart time.Time, weekEnd time.Time, now time.Time) *WorkPattern {
+	p := new(WorkPattern)
+	p.DocumentType = WORKPATTERN
+	p.RepoType = dataprocessor.GITHUB
+	p.RepoName = wa.RepoName
+	p.RepoURL = wa.RepoURL
+	p.CreatedAt = now
+	p.WeekStart = weekStart
+	p.WeekEnd = weekEnd
+	p.Author = id.Canonical
+	p.Name = id.Name
+	p.Team = wa.resolver.Team(id)
+	wh := wa.teamHours(p.Team)
+	p.Timezone = wh.cfg.Timezone
+	p.WorkingHoursStart = wh.cfg.Start
+	p.WorkingHoursEnd = wh.cfg.End
+	p.WorkingDays = wh.cfg.Days
+	p.ByHour = make([]int, 24)
+	p.ByDayOfWeek = make([]int, 7)
+	p.Time = now.UnixNano() / 1000000
+	return p
+}
+
+// parseWorkingHours returns parsed working hours , they are validated while reading config
+func parseWorkingHours(wh input.WorkingHours) workingHours {
+	parsed := workingHours{cfg: wh, days: make(map[time.Weekday]bool)}
+	parsed.location, _ = time.LoadLocation(wh.Timezone)
+	start, _ := time.Parse("15:04", wh.Start)
+	end, _ := time.Parse("15:04", wh.End)
+	parsed.start = start.Hour()*60 + start.Minute()
+	parsed.end = end.Hour()*60 + end.Minute()
+	for _, d := range wh.Days {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(day.String(), d) {
+				parsed.days[day] = true
+			}
+		}
+	}
+	return parsed
+}
+
+// dayOf returns the start of the day of t in the timezone of working hours
+func (wh workingHours) dayOf(t time.Time) time.Time {
+	t = t.In(wh.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, wh.location)
+}
+
+// hoursOf returns the start and end of working hours on day , ok is false if day is not a working day
+func (wh workingHours) hoursOf(day time.Time) (time.Time, time.Time, bool) {
+	if !wh.days[day.Weekday()] {
+		return time.Time{}, time.Time{}, false
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), wh.start/60, wh.start%60, 0, 0, wh.location)
+	end := time.Date(day.Year(), day.Month(), day.Day(), wh.end/60, wh.end%60, 0, 0, wh.location)
+	return start, end, true
+}
+
+// teamHours returns the working hours of team , the default working hours if team has none
+func (wa *WorkPatternAnalyzer) teamHours(team string) workingHours {
+	if wh, ok := wa.workingHours[team]; ok {
+		return wh
+	}
+	return wa.workingHours[""]
+}
@@ -0,0 +1,91 @@
+package derivedmetrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/metricformator"
+)
+
+func TestWorkPatternAnalyzer_Analyze(t *testing.T) {
+	teams := filepath.Join(t.TempDir(), "teams.yaml")
+	os.WriteFile(teams, []byte("india:\n  - dev1\n"), 0600)
+	cfg := input.WorkPatternConfig{Enabled: true, WorkingHours: []input.WorkingHours{{Team: "india", Timezone: "Asia/Kolkata", Start: "09:30", End: "18:30", Days: []string{"Monday", "tuesday", "wednesday", "thursday", "friday"}}}}
+	cfg.WorkingHours = append(cfg.WorkingHours, input.WorkingHours{Timezone: "UTC", Start: "09:00", End: "18:00", Days: input.DefaultWorkingDays})
+	wa := NewWorkPatternAnalyzer("testRepo", "", cfg, enrichment.NewIdentityResolver(input.IdentityConfig{Teams: teams}))
+	wa.MetricFormator = &metricformator.MetricFormator{}
+
+	weekStart := time.Date(2022, 10, 9, 0, 0, 0, 0, time.UTC)
+	ist := time.FixedZone("", 19800)
+	dev1 := dataprocessor.User{User: "dev1"}
+	dev2 := dataprocessor.User{User: "dev2"}
+	commits := []dataprocessor.Commit{
+		// monday 14:00 as recorded by committer
+		{Author: dev1, CreatedAt: time.Date(2022, 10, 10, 9, 0, 0, 0, time.UTC), AuthorLocalTime: time.Date(2022, 10, 10, 14, 0, 0, 0, ist), UTCOffset: "+05:30"},
+		// monday 08:30 in team timezone
+		{Author: dev1, CreatedAt: time.Date(2022, 10, 10, 3, 0, 0, 0, time.UTC)},
+		// previous week
+		{Author: dev1, CreatedAt: time.Date(2022, 10, 8, 10, 0, 0, 0, time.UTC)},
+	}
+	reviews := []dataprocessor.PullRequestReview{
+		// tuesday 00:30 in team timezone
+		{CreatedBy: dev1, CreatedAt: time.Date(2022, 10, 10, 19, 0, 0, 0, time.UTC)},
+		{CreatedBy: dataprocessor.User{User: "dependabot[bot]"}, CreatedAt: time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)},
+	}
+	comments := []dataprocessor.IssueComment{
+		// saturday
+		{CreatedBy: dev2, CreatedAt: time.Date(2022, 10, 15, 10, 0, 0, 0, time.UTC)},
+		{CreatedBy: dev2, CreatedAt: time.Date(2022, 10, 14, 10, 0, 0, 0, time.UTC)},
+	}
+	var patterns []WorkPattern
+	if err := dataprocessor.DecodeDocuments(wa.Analyze(commits, reviews, comments, weekStart, weekStart.AddDate(0, 0, 8), nil), &patterns); err != nil {
+		t.Fatalf("DecodeDocuments() error = %v", err)
+	}
+	if len(patterns) != 2 {
+		t.Fatalf("Analyze() returned %v patterns, want 2", len(patterns))
+	}
+	tests := []struct {
+		name           string
+		got            WorkPattern
+		team           string
+		commits        int
+		withOffset     int
+		reviews        int
+		comments       int
+		afterHours     int
+		nonWorkingDays int
+		hours          map[int]int
+		days           map[time.Weekday]int
+	}{
+		{"team hours", patterns[0], "india", 2, 1, 1, 0, 2, 0, map[int]int{14: 1, 8: 1, 0: 1}, map[time.Weekday]int{time.Monday: 2, time.Tuesday: 1}},
+		{"default hours", patterns[1], "", 0, 0, 0, 2, 1, 1, map[int]int{10: 2}, map[time.Weekday]int{time.Friday: 1, time.Saturday: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.got
+			if p.Team != tt.team || p.Commits != tt.commits || p.CommitsWithUTCOffset != tt.withOffset || p.Reviews != tt.reviews || p.Comments != tt.comments {
+				t.Errorf("Analyze() = %+v", p)
+			}
+			if p.AfterHours != tt.afterHours || p.NonWorkingDays != tt.nonWorkingDays || p.Activities != tt.commits+tt.reviews+tt.comments {
+				t.Errorf("Analyze() after hours = %v non working days = %v activities = %v", p.AfterHours, p.NonWorkingDays, p.Activities)
+			}
+			for h, n := range p.ByHour {
+				if n != tt.hours[h] {
+					t.Errorf("Analyze() by hour = %v", p.ByHour)
+					break
+				}
+			}
+			for d, n := range p.ByDayOfWeek {
+				if n != tt.days[time.Weekday(d)] {
+					t.Errorf("Analyze() by day of week = %v", p.ByDayOfWeek)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+/* Package enrichment adds fields to output documents prepared by dataprocessor and derivedmetrics , like resolved identities */
+package enrichment
+
+import (
+	"github.com/maplelabs/github-audit/logger"
+)
+
+var (
+	log logger.Logger
+)
+
+func init() {
+	log = logger.GetLogger()
+}
+
+// Enricher is implemented by anything that adds fields to output documents.
+// Documents are modified in place and are expected to be map[string]interface{} as returned by dataprocessor.AddTags.
+type Enricher interface {
+	Enrich(docs []interface{})
+}
+
+// userField returns the user object stored at key in doc , ok is false if doc has no such user
+func userField(doc map[string]interface{}, key string) (map[string]interface{}, bool) {
+	u, ok := doc[key].(map[string]interface{})
+	return u, ok
+}
+
+// stringField returns the string stored at key in m , empty if not present
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
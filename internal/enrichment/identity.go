@@ -0,0 +1,242 @@
+package enrichment
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// githubNoreplyDomain is the email domain github uses for users keeping their email private
+	githubNoreplyDomain = "@users.noreply.github.com"
+
+	// botMarker is part of github app logins like dependabot[bot]
+	botMarker = "[bot]"
+
+	// user fields of output documents
+	authorField      = "author"
+	committerField   = "committer"
+	createdByField   = "created_by"
+	contributorField = "contributor"
+
+	// fields added by identity resolver
+	teamField  = "team"
+	isBotField = "is_bot"
+)
+
+// knownBots are logins and names of commonly used bots which do not use a github app login
+var knownBots = []string{
+	"dependabot",
+	"dependabot-preview",
+	"renovate",
+	"renovate-bot",
+	"github-actions",
+	"greenkeeper",
+	"snyk-bot",
+	"codecov",
+	"mergify",
+	"imgbot",
+	"pre-commit-ci",
+	"allcontributors",
+	"semantic-release-bot",
+}
+
+// Identity represents a contributor after merging aliases to a canonical identity
+type Identity struct {
+	// ID of the github account as present in the document
+	ID string `json:"id"`
+
+	// User contains user name as present in the document , git name for commits and login otherwise
+	User string `json:"user"`
+
+	// Name is the canonical name of the contributor
+	Name string `json:"name"`
+
+	// Email is the canonical email of the contributor , empty if not known
+	Email string `json:"email"`
+
+	// Canonical is the key identifying the contributor across aliases , lowercased email or user
+	Canonical string `json:"canonical"`
+}
+
+// IdentityResolver merges aliases of contributors using a mailmap , maps contributors to teams and detects bots
+type IdentityResolver struct {
+	mailmap *mailmap
+
+	// teams holds team name with lowercased email , name or login of member as key
+	teams map[string]string
+
+	// bots holds lowercased logins , names and emails of bots
+	bots map[string]bool
+
+	botPatterns []*regexp.Regexp
+}
+
+// NewIdentityResolver returns a new identity resolver , files which can not be read are logged and ignored
+func NewIdentityResolver(cfg input.IdentityConfig) *IdentityResolver {
+	ir := new(IdentityResolver)
+	ir.teams = make(map[string]string)
+	ir.bots = make(map[string]bool)
+	if cfg.Mailmap != "" {
+		f, err := os.Open(cfg.Mailmap)
+		if err != nil {
+			log.Errorf("error[%v] in opening mailmap file %v", err, cfg.Mailmap)
+		} else {
+			ir.mailmap, err = parseMailmap(f)
+			if err != nil {
+				log.Errorf("error[%v] in reading mailmap file %v", err, cfg.Mailmap)
+			}
+			f.Close()
+		}
+	}
+	if cfg.Teams != "" {
+		fileByte, err := os.ReadFile(cfg.Teams)
+		if err != nil {
+			log.Errorf("error[%v] in reading teams file %v", err, cfg.Teams)
+		}
+		var teams map[string][]string
+		if err = yaml.Unmarshal(fileByte, &teams); err != nil {
+			log.Errorf("error[%v] in unmarshalling teams file %v", err, cfg.Teams)
+		}
+		ir.setTeams(teams)
+	}
+	for _, b := range append(append([]string(nil), knownBots...), cfg.Bots...) {
+		ir.bots[strings.ToLower(b)] = true
+	}
+	for _, p := range cfg.BotPatterns {
+		// patterns are validated with config
+		if re, err := regexp.Compile(p); err == nil {
+			ir.botPatterns = append(ir.botPatterns, re)
+		}
+	}
+	return ir
+}
+
+// setTeams indexes team members , a member of more than one team belongs to the first team in alphabetical order
+func (ir *IdentityResolver) setTeams(teams map[string][]string) {
+	names := make([]string, 0, len(teams))
+	for name := range teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, member := range teams[name] {
+			member = strings.ToLower(strings.TrimSpace(member))
+			if _, ok := ir.teams[member]; !ok && member != "" {
+				ir.teams[member] = name
+			}
+		}
+	}
+}
+
+// Resolve returns the canonical identity of a user as present in output documents. Users with email are
+// looked up in mailmap by git name and email , users with only a login are looked up by their github noreply emails.
+func (ir *IdentityResolver) Resolve(u dataprocessor.User) Identity {
+	id := Identity{ID: u.ID, User: u.User, Name: u.User, Email: u.Email}
+	if u.Email != "" {
+		id.Name, id.Email, _ = ir.mailmap.lookup(u.User, u.Email)
+	} else if u.User != "" {
+		noreply := []string{u.User + githubNoreplyDomain}
+		if u.ID != "" && u.ID != "0" {
+			noreply = append(noreply, u.ID+"+"+u.User+githubNoreplyDomain)
+		}
+		for _, email := range noreply {
+			if name, canonicalEmail, ok := ir.mailmap.lookup(u.User, email); ok {
+				id.Name = name
+				// noreply email is only a lookup key , not the email of the user
+				if !strings.EqualFold(canonicalEmail, email) {
+					id.Email = canonicalEmail
+				}
+				break
+			}
+		}
+	}
+	id.Canonical = strings.ToLower(id.Email)
+	if id.Canonical == "" {
+		id.Canonical = strings.ToLower(id.User)
+	}
+	return id
+}
+
+// Team returns the team of the identity , empty if it is not a member of any team
+func (ir *IdentityResolver) Team(id Identity) string {
+	for _, key := range []string{id.Email, id.User, id.Name} {
+		if team, ok := ir.teams[strings.ToLower(key)]; ok && key != "" {
+			return team
+		}
+	}
+	return ""
+}
+
+// IsBot checks if the identity or the user it is resolved from belongs to a bot
+func (ir *IdentityResolver) IsBot(u dataprocessor.User, id Identity) bool {
+	for _, key := range []string{u.User, u.Email, id.Name, id.Email} {
+		if key == "" {
+			continue
+		}
+		lower := strings.ToLower(key)
+		if strings.Contains(lower, botMarker) || ir.bots[lower] {
+			return true
+		}
+		for _, re := range ir.botPatterns {
+			if re.MatchString(key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Enrich adds resolved author , committer for commits , team and is_bot fields of the author to documents.
+// The author is taken from author , created_by or contributor field , documents without any of them are not modified.
+func (ir *IdentityResolver) Enrich(docs []interface{}) {
+	for _, d := range docs {
+		doc, ok := d.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var author map[string]interface{}
+		for _, key := range []string{authorField, createdByField, contributorField} {
+			if author, ok = userField(doc, key); ok {
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		u := toUser(author)
+		id := ir.Resolve(u)
+		doc[authorField] = identityToMap(id)
+		doc[teamField] = ir.Team(id)
+		doc[isBotField] = ir.IsBot(u, id)
+		if committer, ok := userField(doc, committerField); ok {
+			doc[committerField] = identityToMap(ir.Resolve(toUser(committer)))
+		}
+	}
+}
+
+// toUser converts user object of a document to dataprocessor user
+func toUser(m map[string]interface{}) dataprocessor.User {
+	return dataprocessor.User{
+		ID:    stringField(m, "id"),
+		User:  stringField(m, "user"),
+		Email: stringField(m, "email"),
+	}
+}
+
+// identityToMap converts identity to the form of other objects in documents
+func identityToMap(id Identity) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        id.ID,
+		"user":      id.User,
+		"name":      id.Name,
+		"email":     id.Email,
+		"canonical": id.Canonical,
+	}
+}
@@ -0,0 +1,109 @@
+package enrichment
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maplelabs/github-audit/input"
+)
+
+const testMailmap = `# aliases of jane
+Jane Doe <jane@example.com> <jane@laptop.local>
+Jane Doe <jane@example.com> jdoe <JDOE@old.example.com>
+Jane Doe <jane@example.com> <janedoe@users.noreply.github.com>
+<john@example.com> <john@home.local> # personal machine
+John Smith <john@example.com> <john@work.local>
+`
+
+func TestIdentityResolver_Enrich(t *testing.T) {
+	mm, err := parseMailmap(strings.NewReader(testMailmap))
+	if err != nil {
+		t.Fatalf("parseMailmap() error = %v", err)
+	}
+	ir := NewIdentityResolver(input.IdentityConfig{BotPatterns: []string{`^ci-`}})
+	ir.mailmap = mm
+	ir.setTeams(map[string][]string{"platform": {"jane@example.com"}, "apps": {"John@Example.com"}})
+
+	tests := []struct {
+		name string
+		doc  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "commit author and committer resolved by email",
+			doc: map[string]interface{}{
+				"document_type": "commit",
+				"author":        map[string]interface{}{"id": "1", "user": "jane", "email": "jane@laptop.local"},
+				"committer":     map[string]interface{}{"id": "2", "user": "john", "email": "john@home.local"},
+			},
+			want: map[string]interface{}{
+				"document_type": "commit",
+				"author":        map[string]interface{}{"id": "1", "user": "jane", "name": "Jane Doe", "email": "jane@example.com", "canonical": "jane@example.com"},
+				"committer":     map[string]interface{}{"id": "2", "user": "john", "name": "john", "email": "john@example.com", "canonical": "john@example.com"},
+				"team":          "platform",
+				"is_bot":        false,
+			},
+		},
+		{
+			name: "commit author resolved by name and email",
+			doc: map[string]interface{}{
+				"author": map[string]interface{}{"id": "0", "user": "JDoe", "email": "jdoe@old.example.com"},
+			},
+			want: map[string]interface{}{
+				"author": map[string]interface{}{"id": "0", "user": "JDoe", "name": "Jane Doe", "email": "jane@example.com", "canonical": "jane@example.com"},
+				"team":   "platform",
+				"is_bot": false,
+			},
+		},
+		{
+			name: "pull request creator resolved by noreply email of login",
+			doc: map[string]interface{}{
+				"created_by": map[string]interface{}{"id": "7", "user": "janedoe"},
+			},
+			want: map[string]interface{}{
+				"created_by": map[string]interface{}{"id": "7", "user": "janedoe"},
+				"author":     map[string]interface{}{"id": "7", "user": "janedoe", "name": "Jane Doe", "email": "jane@example.com", "canonical": "jane@example.com"},
+				"team":       "platform",
+				"is_bot":     false,
+			},
+		},
+		{
+			name: "github app login is a bot",
+			doc: map[string]interface{}{
+				"created_by": map[string]interface{}{"id": "9", "user": "dependabot[bot]"},
+			},
+			want: map[string]interface{}{
+				"created_by": map[string]interface{}{"id": "9", "user": "dependabot[bot]"},
+				"author":     map[string]interface{}{"id": "9", "user": "dependabot[bot]", "name": "dependabot[bot]", "email": "", "canonical": "dependabot[bot]"},
+				"team":       "",
+				"is_bot":     true,
+			},
+		},
+		{
+			name: "contributor matching bot pattern",
+			doc: map[string]interface{}{
+				"contributor": map[string]interface{}{"id": "3", "user": "ci-runner"},
+			},
+			want: map[string]interface{}{
+				"contributor": map[string]interface{}{"id": "3", "user": "ci-runner"},
+				"author":      map[string]interface{}{"id": "3", "user": "ci-runner", "name": "ci-runner", "email": "", "canonical": "ci-runner"},
+				"team":        "",
+				"is_bot":      true,
+			},
+		},
+		{
+			name: "document without user is not modified",
+			doc:  map[string]interface{}{"document_type": "code_frequency"},
+			want: map[string]interface{}{"document_type": "code_frequency"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ir.Enrich([]interface{}{tt.doc})
+			if !reflect.DeepEqual(tt.doc, tt.want) {
+				t.Errorf("Enrich() = %v, want %v", tt.doc, tt.want)
+			}
+		})
+	}
+}
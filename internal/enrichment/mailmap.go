@@ -0,0 +1,96 @@
+package enrichment
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// mailmapLine matches a mailmap entry , for ex. "Proper Name <proper@email> Commit Name <commit@email>"
+var mailmapLine = regexp.MustCompile(`^([^<>]*)<([^<>]*)>(?:([^<>]*)<([^<>]*)>)?$`)
+
+// mailmapEntry holds the proper name and email replacing a commit identity , empty values are not replaced
+type mailmapEntry struct {
+	name  string
+	email string
+}
+
+// mailmap maps commit emails to proper identities as described in gitmailmap documentation
+type mailmap struct {
+	// byEmail holds entries matching only the commit email
+	byEmail map[string]mailmapEntry
+
+	// byNameEmail holds entries matching both commit name and email , with name and email joined by "$" as key
+	byNameEmail map[string]mailmapEntry
+}
+
+// parseMailmap parses a mailmap file. Supported forms of lines are
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+//
+// Names and emails are matched case insensitively , later lines override earlier ones.
+func parseMailmap(r io.Reader) (*mailmap, error) {
+	mm := &mailmap{
+		byEmail:     make(map[string]mailmapEntry),
+		byNameEmail: make(map[string]mailmapEntry),
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// trailing comments are allowed after the last email
+		if i := strings.LastIndex(line, ">"); i >= 0 {
+			if j := strings.Index(line[i:], "#"); j >= 0 {
+				line = strings.TrimSpace(line[:i+j])
+			}
+		}
+		m := mailmapLine.FindStringSubmatch(line)
+		if m == nil {
+			log.Warnf("skipping invalid mailmap line %v", line)
+			continue
+		}
+		properName := strings.TrimSpace(m[1])
+		if m[4] == "" {
+			// single email is the commit email , only name is replaced
+			mm.byEmail[strings.ToLower(strings.TrimSpace(m[2]))] = mailmapEntry{name: properName}
+			continue
+		}
+		entry := mailmapEntry{name: properName, email: strings.TrimSpace(m[2])}
+		commitName := strings.TrimSpace(m[3])
+		commitEmail := strings.ToLower(strings.TrimSpace(m[4]))
+		if commitName == "" {
+			mm.byEmail[commitEmail] = entry
+		} else {
+			mm.byNameEmail[strings.ToLower(commitName)+"$"+commitEmail] = entry
+		}
+	}
+	return mm, scanner.Err()
+}
+
+// lookup returns the proper name and email for a commit name and email , ok is false if no entry matches
+func (mm *mailmap) lookup(name string, email string) (string, string, bool) {
+	if mm == nil || email == "" {
+		return name, email, false
+	}
+	email = strings.ToLower(email)
+	entry, ok := mm.byNameEmail[strings.ToLower(name)+"$"+email]
+	if !ok {
+		entry, ok = mm.byEmail[email]
+	}
+	if !ok {
+		return name, email, false
+	}
+	if entry.name != "" {
+		name = entry.name
+	}
+	if entry.email != "" {
+		email = entry.email
+	}
+	return name, email, true
+}
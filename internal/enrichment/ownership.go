@@ -0,0 +1,213 @@
+package enrichment
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/codeowners"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+)
+
+const (
+	// fields added by ownership enricher
+	owningTeamsField = "owning_teams"
+	authorTeamField  = "author_team"
+
+	// fields identifying the change of a document
+	shaField           = "sha"
+	pullRequestNoField = "pull_request_no"
+	documentTypeField  = "document_type"
+)
+
+// OwnershipEnricher adds owning teams of changed files and team of the author to commit , pull request ,
+// pull request review and pull request cycle time documents. Files changed and teams are fetched once and shared
+// by all targets of a run.
+type OwnershipEnricher struct {
+	gp       gitprovider.GitProvider
+	cfg      input.OwnershipConfig
+	resolver *IdentityResolver
+
+	loadOnce sync.Once
+
+	codeOwners *codeowners.CodeOwners
+
+	githubTeams *codeowners.Teams
+
+	// mu guards owningTeams , files are fetched without holding it
+	mu sync.Mutex
+
+	// owningTeams holds owning teams of a change with document type and sha or pull request number as key
+	owningTeams map[string]*changeOwners
+}
+
+// changeOwners represents the owning teams of a change , resolved once by whichever target needs them first
+type changeOwners struct {
+	once  sync.Once
+	teams []string
+}
+
+// NewOwnershipEnricher returns a new ownership enricher , resolver is used for teams of the identity teams file
+// and must enrich documents before the ownership enricher.
+func NewOwnershipEnricher(gp gitprovider.GitProvider, cfg input.OwnershipConfig, resolver *IdentityResolver) *OwnershipEnricher {
+	oe := new(OwnershipEnricher)
+	oe.gp = gp
+	oe.cfg = cfg
+	oe.resolver = resolver
+	oe.owningTeams = make(map[string]*changeOwners)
+	return oe
+}
+
+// Enrich adds owning_teams and author_team fields to commit , pull request , pull request review and pull request
+// cycle time documents , reviews get the owning teams of files changed in their pull request
+func (oe *OwnershipEnricher) Enrich(docs []interface{}) {
+	oe.loadOnce.Do(oe.load)
+	for _, d := range docs {
+		doc, ok := d.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var key string
+		switch stringField(doc, documentTypeField) {
+		case dataprocessor.COMMIT:
+			key = dataprocessor.COMMIT + "$" + stringField(doc, shaField)
+		case dataprocessor.PULLREQUEST, dataprocessor.PULLREQUESTCYCLETIME, dataprocessor.PULLREQUESTREVIEW:
+			key = dataprocessor.PULLREQUEST + "$" + stringField(doc, pullRequestNoField)
+		default:
+			continue
+		}
+		doc[owningTeamsField] = oe.owningTeamsOf(key)
+		doc[authorTeamField] = oe.authorTeam(doc)
+	}
+}
+
+// load fetches CODEOWNERS file and github teams as configured , failures are logged and the data is skipped
+func (oe *OwnershipEnricher) load() {
+	if oe.cfg.CodeOwners {
+		content, err := oe.gp.GetCodeOwners()
+		if err != nil {
+			log.Errorf("error[%v] in getting CODEOWNERS from gitprovider", err)
+		} else if content != nil {
+			oe.codeOwners, err = codeowners.Parse(bytes.NewReader(content))
+			if err != nil {
+				log.Errorf("error[%v] in parsing CODEOWNERS", err)
+			}
+		}
+	}
+	if oe.cfg.GithubTeams {
+		teamsByte, err := oe.gp.GetTeams()
+		if err != nil {
+			log.Errorf("error[%v] in getting teams from gitprovider", err)
+			return
+		}
+		oe.githubTeams, _ = codeowners.ParseTeams(teamsByte)
+	}
+}
+
+// owningTeamsOf returns the sorted owning teams of files changed in a commit or pull request , they are resolved
+// once for a change , failures are cached as well so that other targets do not retry
+func (oe *OwnershipEnricher) owningTeamsOf(key string) []string {
+	if oe.codeOwners == nil {
+		return make([]string, 0)
+	}
+	oe.mu.Lock()
+	owners, ok := oe.owningTeams[key]
+	if !ok {
+		owners = new(changeOwners)
+		oe.owningTeams[key] = owners
+	}
+	oe.mu.Unlock()
+	owners.once.Do(func() {
+		owners.teams = oe.resolveOwningTeams(key)
+	})
+	return owners.teams
+}
+
+// resolveOwningTeams fetches files changed in a commit or pull request and returns their sorted owning teams
+func (oe *OwnershipEnricher) resolveOwningTeams(key string) []string {
+	teams := make([]string, 0)
+	var (
+		filesByte []byte
+		err       error
+	)
+	kind, id := splitKey(key)
+	if kind == dataprocessor.COMMIT {
+		filesByte, err = oe.gp.GetCommitFiles(id)
+	} else {
+		no, _ := strconv.Atoi(id)
+		filesByte, err = oe.gp.GetPullRequestFiles(no)
+	}
+	var files []github.CommitFile
+	if err == nil {
+		err = json.Unmarshal(filesByte, &files)
+	}
+	if err != nil {
+		log.Errorf("error[%v] in getting files changed for %v", err, key)
+	}
+	seen := make(map[string]bool)
+	for _, f := range files {
+		for _, path := range []string{f.GetFilename(), f.GetPreviousFilename()} {
+			if path == "" {
+				continue
+			}
+			for _, owner := range oe.codeOwners.Owners(path) {
+				if team := oe.ownerTeam(owner); team != "" && !seen[team] {
+					seen[team] = true
+					teams = append(teams, team)
+				}
+			}
+		}
+	}
+	sort.Strings(teams)
+	return teams
+}
+
+// ownerTeam returns the team of a CODEOWNERS owner. @org/team owners are teams themselves , users and emails
+// are mapped to their team , empty if the owner is not in any team.
+func (oe *OwnershipEnricher) ownerTeam(owner string) string {
+	if strings.HasPrefix(owner, "@") {
+		owner = strings.TrimPrefix(owner, "@")
+		if i := strings.Index(owner, "/"); i >= 0 {
+			return owner[i+1:]
+		}
+		if team := oe.resolver.Team(Identity{User: owner}); team != "" {
+			return team
+		}
+		return oe.githubTeams.TeamOf(owner)
+	}
+	return oe.resolver.Team(Identity{Email: owner})
+}
+
+// authorTeam returns the team of the resolved author of document , from identity teams file if present
+// or else from github teams
+func (oe *OwnershipEnricher) authorTeam(doc map[string]interface{}) string {
+	if team := stringField(doc, teamField); team != "" {
+		return team
+	}
+	author, ok := userField(doc, authorField)
+	if !ok {
+		return ""
+	}
+	if id := stringField(author, "id"); id != "" && id != "0" {
+		if team := oe.githubTeams.TeamOf(id); team != "" {
+			return team
+		}
+	}
+	// user is the git name for commits , so only account id is reliable there
+	if stringField(doc, documentTypeField) != dataprocessor.COMMIT {
+		return oe.githubTeams.TeamOf(stringField(author, "user"))
+	}
+	return ""
+}
+
+// splitKey splits a change key into document type and sha or pull request number
+func splitKey(key string) (string, string) {
+	i := strings.Index(key, "$")
+	return key[:i], key[i+1:]
+}
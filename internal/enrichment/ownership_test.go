@@ -0,0 +1,123 @@
+package enrichment
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/input"
+)
+
+// filesProvider is a git provider serving CODEOWNERS and files changed , other methods are not implemented
+type filesProvider struct {
+	gitprovider.GitProvider
+	codeOwners string
+	files      map[string][]string
+	// blocked holds changes whose files are served only once the channel is closed
+	blocked map[string]chan struct{}
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (fp *filesProvider) GetCodeOwners() ([]byte, error) {
+	return []byte(fp.codeOwners), nil
+}
+
+func (fp *filesProvider) GetCommitFiles(sha string) ([]byte, error) {
+	return fp.changeFiles("commit$" + sha)
+}
+
+func (fp *filesProvider) GetPullRequestFiles(no int) ([]byte, error) {
+	return fp.changeFiles("pull_request$" + strconv.Itoa(no))
+}
+
+func (fp *filesProvider) changeFiles(key string) ([]byte, error) {
+	fp.mu.Lock()
+	fp.calls[key]++
+	block := fp.blocked[key]
+	fp.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	var files []github.CommitFile
+	for _, name := range fp.files[key] {
+		files = append(files, github.CommitFile{Filename: github.String(name)})
+	}
+	return json.Marshal(files)
+}
+
+func TestOwnershipEnricher_Enrich(t *testing.T) {
+	fp := &filesProvider{
+		codeOwners: "*.go @org/core\n/docs/ @org/docs\n/web/ jane@example.com\n",
+		files: map[string][]string{
+			"commit$a1":       {"main.go", "docs/guide.md"},
+			"pull_request$1":  {"web/index.html", "README.md"},
+			"commit$blocked":  {"main.go"},
+			"commit$unblocks": {"docs/guide.md"},
+		},
+		blocked: map[string]chan struct{}{"commit$blocked": make(chan struct{})},
+		calls:   make(map[string]int),
+	}
+	ir := NewIdentityResolver(input.IdentityConfig{})
+	ir.setTeams(map[string][]string{"apps": {"jane@example.com"}})
+	oe := NewOwnershipEnricher(fp, input.OwnershipConfig{CodeOwners: true}, ir)
+
+	docs := []interface{}{
+		map[string]interface{}{"document_type": "commit", "sha": "a1"},
+		map[string]interface{}{"document_type": "pull_request", "pull_request_no": "1"},
+		map[string]interface{}{"document_type": "pull_request_review", "pull_request_no": "1", "review_id": "10"},
+		map[string]interface{}{"document_type": "pull_request_cycle_time", "pull_request_no": "1"},
+		map[string]interface{}{"document_type": "issue", "issue_no": "1"},
+	}
+	oe.Enrich(docs)
+	want := [][]string{{"core", "docs"}, {"apps"}, {"apps"}, {"apps"}, nil}
+	for i, d := range docs {
+		got, ok := d.(map[string]interface{})[owningTeamsField]
+		if want[i] == nil {
+			if ok {
+				t.Errorf("Enrich() document %v has owning teams %v", i, got)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("Enrich() document %v has owning teams %v, want %v", i, got, want[i])
+		}
+	}
+	if fp.calls["pull_request$1"] != 1 {
+		t.Errorf("Enrich() fetched files of pull request %v times, want once", fp.calls["pull_request$1"])
+	}
+
+	// a target waiting for files of a change does not block other targets
+	done := make(chan struct{})
+	go func() {
+		oe.Enrich([]interface{}{map[string]interface{}{"document_type": "commit", "sha": "blocked"}})
+		close(done)
+	}()
+	for waiting := false; !waiting; time.Sleep(time.Millisecond) {
+		fp.mu.Lock()
+		waiting = fp.calls["commit$blocked"] == 1
+		fp.mu.Unlock()
+	}
+	other := map[string]interface{}{"document_type": "commit", "sha": "unblocks"}
+	enriched := make(chan struct{})
+	go func() {
+		oe.Enrich([]interface{}{other})
+		close(enriched)
+	}()
+	select {
+	case <-enriched:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Enrich() is blocked by files of another change")
+	}
+	close(fp.blocked["commit$blocked"])
+	<-done
+	if got := other[owningTeamsField]; !reflect.DeepEqual(got, []string{"docs"}) {
+		t.Errorf("Enrich() has owning teams %v, want [docs]", got)
+	}
+}
@@ -0,0 +1,79 @@
+package signature
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// verifyGPG verifies an armored gpg signature of payload against keyring , expiry and revocation of keys are
+// checked at the time the signature was made as commits stay valid after keys of their signers expire
+func verifyGPG(keyring openpgp.EntityList, signature string, payload string, email string) Result {
+	result := Result{Type: TypeGPG, Reason: ReasonInvalid}
+	sig, err := readGPGSignature(signature)
+	if err != nil {
+		result.Reason = ReasonMalformedSignature
+		return result
+	}
+	result.KeyID = gpgKeyID(sig)
+	config := &packet.Config{Time: func() time.Time { return sig.CreationTime }}
+	entity, err := openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(payload), strings.NewReader(signature), config)
+	if err == pgperrors.ErrUnknownIssuer {
+		result.Reason = ReasonUnknownKey
+		return result
+	}
+	if err != nil {
+		return result
+	}
+	emails := make([]string, 0, len(entity.Identities))
+	for _, i := range entity.Identities {
+		emails = append(emails, i.UserId.Email)
+	}
+	if !containsFold(email, emails) {
+		result.Reason = ReasonBadEmail
+		return result
+	}
+	result.Verified = true
+	result.Reason = ReasonValid
+	result.Signer = email
+	return result
+}
+
+// readGPGSignature returns the signature packet of an armored gpg signature
+func readGPGSignature(signature string) (*packet.Signature, error) {
+	block, err := armor.Decode(strings.NewReader(signature))
+	if err != nil {
+		return nil, err
+	}
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, pgperrors.StructuralError("signature packet not found")
+	}
+	return sig, nil
+}
+
+// gpgKeyID returns the id of the key that made a gpg signature , taken from the issuer fingerprint for
+// signatures without issuer key id
+func gpgKeyID(sig *packet.Signature) string {
+	switch {
+	case sig.IssuerKeyId != nil:
+		return fmt.Sprintf("%016X", *sig.IssuerKeyId)
+	case len(sig.IssuerFingerprint) == 20:
+		// key id of v4 keys is the low 64 bits of their fingerprint
+		return fmt.Sprintf("%016X", binary.BigEndian.Uint64(sig.IssuerFingerprint[12:]))
+	case len(sig.IssuerFingerprint) >= 8:
+		// key id of v5 keys is the high 64 bits of their fingerprint
+		return fmt.Sprintf("%016X", binary.BigEndian.Uint64(sig.IssuerFingerprint[:8]))
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+/* Package signature inspects and verifies gpg and ssh signatures of commits against configured keys */
+package signature
+
+import (
+	"strings"
+
+	"github.com/maplelabs/github-audit/logger"
+)
+
+var (
+	log logger.Logger
+)
+
+func init() {
+	log = logger.GetLogger()
+}
+
+const (
+	// signature types
+	TypeGPG  = "gpg"
+	TypeSSH  = "ssh"
+	TypeX509 = "x509"
+
+	// sources of verification
+	VerifiedByGithub = "github"
+	VerifiedByLocal  = "local"
+
+	// verification reasons , same as reported by github where possible
+	ReasonValid                = "valid"
+	ReasonUnsigned             = "unsigned"
+	ReasonUnknownKey           = "unknown_key"
+	ReasonBadEmail             = "bad_email"
+	ReasonInvalid              = "invalid"
+	ReasonMalformedSignature   = "malformed_signature"
+	ReasonUnknownSignatureType = "unknown_signature_type"
+
+	// armor headers of signatures
+	gpgHeader  = "-----BEGIN PGP SIGNATURE-----"
+	sshHeader  = "-----BEGIN SSH SIGNATURE-----"
+	x509Header = "-----BEGIN SIGNED MESSAGE-----"
+)
+
+// Result represents the outcome of verifying a commit signature
+type Result struct {
+	// Verified is whether the signature is valid and made by a key of the committer
+	Verified bool
+
+	// Reason is why the signature is valid or not , for ex. valid , unsigned , unknown_key
+	Reason string
+
+	// Type is gpg , ssh or x509 , empty if unsigned
+	Type string
+
+	// KeyID is the gpg key id or sha256 fingerprint of the ssh key that made the signature
+	KeyID string
+
+	// Signer is the email of the key identity matching the committer
+	Signer string
+}
+
+// Type returns the type of an armored signature , empty if there is no signature
+func Type(signature string) string {
+	signature = strings.TrimSpace(signature)
+	switch {
+	case signature == "":
+		return ""
+	case strings.HasPrefix(signature, gpgHeader):
+		return TypeGPG
+	case strings.HasPrefix(signature, sshHeader):
+		return TypeSSH
+	case strings.HasPrefix(signature, x509Header):
+		return TypeX509
+	}
+	return "unknown"
+}
+
+// Inspect returns the type and key id of an armored signature without verifying it ,
+// key id is empty if it can not be read from the signature
+func Inspect(signature string) (string, string) {
+	typ := Type(signature)
+	var keyID string
+	switch typ {
+	case TypeGPG:
+		if sig, err := readGPGSignature(signature); err == nil {
+			keyID = gpgKeyID(sig)
+		}
+	case TypeSSH:
+		if sig, err := parseSSHSignature(signature); err == nil {
+			keyID = sig.fingerprint()
+		}
+	}
+	return typ, keyID
+}
+
+// containsFold returns true if s is not empty and equals any of list ignoring case
+func containsFold(s string, list []string) bool {
+	if s == "" {
+		return false
+	}
+	for _, l := range list {
+		if strings.EqualFold(l, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// sshSigMagic is the preamble of ssh signatures and of the data they sign
+	sshSigMagic = "SSHSIG"
+
+	// gitNamespace is the namespace git signs commits with
+	gitNamespace = "git"
+)
+
+var (
+	errMalformedSSHSignature = errors.New("malformed ssh signature")
+)
+
+// sshSignature represents a parsed ssh signature as created by ssh-keygen -Y sign
+type sshSignature struct {
+	publicKey     ssh.PublicKey
+	namespace     string
+	reserved      string
+	hashAlgorithm string
+	signature     *ssh.Signature
+}
+
+// sshSignatureBlob is the wire format of ssh signature after the magic preamble
+type sshSignatureBlob struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// allowedSigner represents a line of git allowed signers file
+type allowedSigner struct {
+	principals []string
+	namespaces []string
+	publicKey  ssh.PublicKey
+}
+
+// parseSSHSignature parses an armored ssh signature
+func parseSSHSignature(armored string) (*sshSignature, error) {
+	var encoded strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(armored), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "-----") {
+			continue
+		}
+		encoded.WriteString(line)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil || !bytes.HasPrefix(raw, []byte(sshSigMagic)) {
+		return nil, errMalformedSSHSignature
+	}
+	var blob sshSignatureBlob
+	if err = ssh.Unmarshal(raw[len(sshSigMagic):], &blob); err != nil || blob.Version != 1 {
+		return nil, errMalformedSSHSignature
+	}
+	sig := &sshSignature{namespace: blob.Namespace, reserved: blob.Reserved, hashAlgorithm: blob.HashAlgorithm}
+	if sig.publicKey, err = ssh.ParsePublicKey(blob.PublicKey); err != nil {
+		return nil, errMalformedSSHSignature
+	}
+	sig.signature = new(ssh.Signature)
+	if err = ssh.Unmarshal(blob.Signature, sig.signature); err != nil {
+		return nil, errMalformedSSHSignature
+	}
+	return sig, nil
+}
+
+// fingerprint returns the sha256 fingerprint of the key that made the signature
+func (s *sshSignature) fingerprint() string {
+	return ssh.FingerprintSHA256(s.publicKey)
+}
+
+// verify checks the signature of message with the public key embedded in the signature
+func (s *sshSignature) verify(message string) error {
+	var digest []byte
+	switch s.hashAlgorithm {
+	case "sha256":
+		h := sha256.Sum256([]byte(message))
+		digest = h[:]
+	case "sha512":
+		h := sha512.Sum512([]byte(message))
+		digest = h[:]
+	default:
+		return errMalformedSSHSignature
+	}
+	signed := ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{s.namespace, s.reserved, s.hashAlgorithm, digest})
+	return s.publicKey.Verify(append([]byte(sshSigMagic), signed...), s.signature)
+}
+
+// parseAllowedSigners reads git allowed signers file , lines are principals followed by optional
+// options and the public key. Certificate authorities are not supported and skipped.
+func parseAllowedSigners(r io.Reader) ([]allowedSigner, error) {
+	signers := make([]allowedSigner, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		publicKey, _, options, _, err := ssh.ParseAuthorizedKey([]byte(fields[1]))
+		if err != nil {
+			log.Errorf("error[%v] in parsing allowed signers line %q", err, line)
+			continue
+		}
+		signer := allowedSigner{principals: strings.Split(fields[0], ","), publicKey: publicKey}
+		skip := false
+		for _, o := range options {
+			switch {
+			case strings.EqualFold(o, "cert-authority"):
+				skip = true
+			case strings.HasPrefix(strings.ToLower(o), "namespaces="):
+				signer.namespaces = strings.Split(strings.Trim(o[len("namespaces="):], `"`), ",")
+			}
+		}
+		if !skip {
+			signers = append(signers, signer)
+		}
+	}
+	return signers, scanner.Err()
+}
+
+// verifySSH verifies an armored ssh signature of payload against allowed signers
+func verifySSH(signers []allowedSigner, signature string, payload string, email string) Result {
+	result := Result{Type: TypeSSH, Reason: ReasonInvalid}
+	sig, err := parseSSHSignature(signature)
+	if err != nil {
+		result.Reason = ReasonMalformedSignature
+		return result
+	}
+	result.KeyID = sig.fingerprint()
+	if sig.namespace != gitNamespace || sig.verify(payload) != nil {
+		return result
+	}
+	key := sig.publicKey.Marshal()
+	principals := make([]string, 0)
+	for _, s := range signers {
+		if !bytes.Equal(s.publicKey.Marshal(), key) {
+			continue
+		}
+		if len(s.namespaces) > 0 && !containsFold(gitNamespace, s.namespaces) {
+			continue
+		}
+		principals = append(principals, s.principals...)
+	}
+	if len(principals) == 0 {
+		result.Reason = ReasonUnknownKey
+		return result
+	}
+	if !containsFold(email, principals) {
+		result.Reason = ReasonBadEmail
+		return result
+	}
+	result.Verified = true
+	result.Reason = ReasonValid
+	result.Signer = email
+	return result
+}
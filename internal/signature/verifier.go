@@ -0,0 +1,61 @@
+package signature
+
+import (
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/maplelabs/github-audit/input"
+)
+
+// Verifier verifies commit signatures locally against configured gpg and ssh keys
+// instead of trusting the verification reported by git provider
+type Verifier struct {
+	gpgKeyring openpgp.EntityList
+
+	sshSigners []allowedSigner
+}
+
+// NewVerifier returns a verifier with keys of the configured files , nil if local verification is not configured.
+// Files which can not be read are logged and their keys are missing , so signatures by them are reported as unknown_key.
+func NewVerifier(cfg input.SignatureConfig) *Verifier {
+	if cfg.Mode != input.SignatureModeLocal {
+		return nil
+	}
+	v := new(Verifier)
+	if cfg.GPGKeyring != "" {
+		f, err := os.Open(cfg.GPGKeyring)
+		if err == nil {
+			v.gpgKeyring, err = openpgp.ReadArmoredKeyRing(f)
+			f.Close()
+		}
+		if err != nil {
+			log.Errorf("error[%v] in reading gpg keyring %v", err, cfg.GPGKeyring)
+		}
+	}
+	if cfg.SSHAllowedSigners != "" {
+		f, err := os.Open(cfg.SSHAllowedSigners)
+		if err == nil {
+			v.sshSigners, err = parseAllowedSigners(f)
+			f.Close()
+		}
+		if err != nil {
+			log.Errorf("error[%v] in reading ssh allowed signers %v", err, cfg.SSHAllowedSigners)
+		}
+	}
+	return v
+}
+
+// Verify verifies the armored signature of payload , the raw commit object without signature.
+// Email is of the committer , signatures by keys not belonging to it are reported as bad_email.
+func (v *Verifier) Verify(signature string, payload string, email string) Result {
+	switch typ := Type(signature); typ {
+	case "":
+		return Result{Reason: ReasonUnsigned}
+	case TypeGPG:
+		return verifyGPG(v.gpgKeyring, signature, payload, email)
+	case TypeSSH:
+		return verifySSH(v.sshSigners, signature, payload, email)
+	default:
+		return Result{Type: typ, Reason: ReasonUnknownSignatureType}
+	}
+}
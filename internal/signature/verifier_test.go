@@ -0,0 +1,117 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/maplelabs/github-audit/input"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshSign returns an armored ssh signature of message in namespace like ssh-keygen -Y sign
+func sshSign(t *testing.T, signer ssh.Signer, namespace string, message string) string {
+	h := sha512.Sum512([]byte(message))
+	signed := ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{namespace, "", "sha512", h[:]})
+	sig, err := signer.Sign(rand.Reader, append([]byte(sshSigMagic), signed...))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	blob := ssh.Marshal(sshSignatureBlob{1, signer.PublicKey().Marshal(), namespace, "", "sha512", ssh.Marshal(sig)})
+	return sshHeader + "\n" + base64.StdEncoding.EncodeToString(append([]byte(sshSigMagic), blob...)) + "\n-----END SSH SIGNATURE-----\n"
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	dir := t.TempDir()
+	payload := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\nauthor Dev <dev@example.com> 1665400000 +0530\ncommitter Dev <dev@example.com> 1665400000 +0530\n\nfix build\n"
+
+	entity, err := openpgp.NewEntity("Dev", "", "dev@example.com", nil)
+	if err != nil {
+		t.Fatalf("NewEntity() error = %v", err)
+	}
+	// ed25519 keys are the default of recent gpg versions
+	edEntity, err := openpgp.NewEntity("Dev", "laptop", "dev@users.noreply.github.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("NewEntity() error = %v", err)
+	}
+	var keyring bytes.Buffer
+	w, _ := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+	for _, e := range []*openpgp.Entity{entity, edEntity} {
+		if err = e.Serialize(w); err != nil {
+			t.Fatalf("Serialize() error = %v", err)
+		}
+	}
+	w.Close()
+	var gpgSig, edSig bytes.Buffer
+	if err = openpgp.ArmoredDetachSign(&gpgSig, entity, strings.NewReader(payload), nil); err != nil {
+		t.Fatalf("ArmoredDetachSign() error = %v", err)
+	}
+	if err = openpgp.ArmoredDetachSign(&edSig, edEntity, strings.NewReader(payload), nil); err != nil {
+		t.Fatalf("ArmoredDetachSign() error = %v", err)
+	}
+	stranger, _ := openpgp.NewEntity("Stranger", "", "stranger@example.com", nil)
+	var strangerSig bytes.Buffer
+	openpgp.ArmoredDetachSign(&strangerSig, stranger, strings.NewReader(payload), nil)
+
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(private)
+	allowed := "dev@example.com,dev@users.noreply.github.com namespaces=\"git\" " + string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+
+	cfg := input.SignatureConfig{
+		Mode:              input.SignatureModeLocal,
+		GPGKeyring:        filepath.Join(dir, "keyring.asc"),
+		SSHAllowedSigners: filepath.Join(dir, "allowed_signers"),
+	}
+	os.WriteFile(cfg.GPGKeyring, keyring.Bytes(), 0600)
+	os.WriteFile(cfg.SSHAllowedSigners, []byte("# signers\n"+allowed), 0600)
+	v := NewVerifier(cfg)
+
+	tests := []struct {
+		name      string
+		signature string
+		payload   string
+		email     string
+		want      Result
+	}{
+		{"unsigned", "", payload, "dev@example.com", Result{Reason: ReasonUnsigned}},
+		{"gpg valid", gpgSig.String(), payload, "Dev@Example.com", Result{Verified: true, Reason: ReasonValid, Type: TypeGPG, Signer: "Dev@Example.com"}},
+		{"gpg tampered", gpgSig.String(), payload + "\n", "dev@example.com", Result{Reason: ReasonInvalid, Type: TypeGPG}},
+		{"gpg other committer", gpgSig.String(), payload, "other@example.com", Result{Reason: ReasonBadEmail, Type: TypeGPG}},
+		{"gpg committer without email", gpgSig.String(), payload, "", Result{Reason: ReasonBadEmail, Type: TypeGPG}},
+		{"gpg ed25519 valid", edSig.String(), payload, "dev@users.noreply.github.com", Result{Verified: true, Reason: ReasonValid, Type: TypeGPG, Signer: "dev@users.noreply.github.com"}},
+		{"gpg unknown key", strangerSig.String(), payload, "stranger@example.com", Result{Reason: ReasonUnknownKey, Type: TypeGPG}},
+		{"ssh valid", sshSign(t, signer, "git", payload), payload, "dev@example.com", Result{Verified: true, Reason: ReasonValid, Type: TypeSSH, Signer: "dev@example.com"}},
+		{"ssh committer without email", sshSign(t, signer, "git", payload), payload, "", Result{Reason: ReasonBadEmail, Type: TypeSSH}},
+		{"ssh tampered", sshSign(t, signer, "git", payload), "fix", "dev@example.com", Result{Reason: ReasonInvalid, Type: TypeSSH}},
+		{"ssh wrong namespace", sshSign(t, signer, "file", payload), payload, "dev@example.com", Result{Reason: ReasonInvalid, Type: TypeSSH}},
+		{"ssh malformed", sshHeader + "\nbm90IGEgc2lnbmF0dXJl\n-----END SSH SIGNATURE-----", payload, "dev@example.com", Result{Reason: ReasonMalformedSignature, Type: TypeSSH}},
+		{"x509", x509Header + "\nMIIB\n-----END SIGNED MESSAGE-----", payload, "dev@example.com", Result{Reason: ReasonUnknownSignatureType, Type: TypeX509}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := v.Verify(tt.signature, tt.payload, tt.email)
+			// key ids are random , so only checked to be present for well formed signatures
+			if (got.KeyID == "") != (tt.want.Type == "" || tt.want.Type == TypeX509 || tt.want.Reason == ReasonMalformedSignature) {
+				t.Errorf("Verify() key id = %q", got.KeyID)
+			}
+			got.KeyID = ""
+			if got != tt.want {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
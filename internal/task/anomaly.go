@@ -0,0 +1,79 @@
+package task
+
+import (
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/publisher"
+)
+
+const (
+	// anomalyReport is the report name of anomaly detection in task stats
+	anomalyReport = "anomaly"
+)
+
+// detectAndPublishAnomalies compares commits to monitored branches , issues closed and pushes to protected branches since
+// the last run with baselines of previous runs and publish unusual activity to targets. Baselines are updated on every run.
+func (t *Task) detectAndPublishAnomalies(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats, resolver *enrichment.IdentityResolver, cache *commitFilesCache) error {
+	cfg := t.Config.Anomalies
+	if !cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	lastRun := ts.LastReportTime[anomalyReport]
+	if lastRun.IsZero() {
+		lastRun = now.Add(-(t.SchedulingInterval))
+	}
+	commitFiles, err := t.getCommitFiles(gp, dp, cache, lastRun, now)
+	if err != nil {
+		return err
+	}
+	issueBytes, err := gp.GetIssues(lastRun)
+	if err != nil {
+		log.Errorf("error[%v] in getting updated issues from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	issueDocs, err := dp.ProcessIssues(issueBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing updated issues for task with ID %v", err, t.ID)
+		return err
+	}
+	var updatedIssues []dataprocessor.Issue
+	if err = dataprocessor.DecodeDocuments(issueDocs, &updatedIssues); err != nil {
+		return err
+	}
+	closedIssues := make([]dataprocessor.Issue, 0)
+	for _, issue := range updatedIssues {
+		closedAt, err := time.Parse(time.RFC3339, issue.ClosedAt)
+		if err == nil && closedAt.After(lastRun) {
+			closedIssues = append(closedIssues, issue)
+		}
+	}
+	eventBytes, err := gp.GetPushEvents()
+	if err != nil {
+		log.Errorf("error[%v] in getting push events from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	pushes, err := dp.ProcessPushEvents(eventBytes)
+	if err != nil {
+		log.Errorf("error[%v] in processing push events for task with ID %v", err, t.ID)
+		return err
+	}
+
+	ad := derivedmetrics.NewAnomalyDetector(t.Config.RepositoryName, t.Config.RepositoryURL, cfg, resolver)
+	processed, baselines := ad.Detect(commitFiles, closedIssues, pushes, ts.AnomalyBaselines, lastRun, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing activity anomalies for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	updateTaskStats(t.ID, func(saved *TaskStats) {
+		saved.AnomalyBaselines = baselines
+	})
+	saveReportTime(t.ID, anomalyReport, now)
+	return nil
+}
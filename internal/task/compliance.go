@@ -0,0 +1,233 @@
+package task
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/codeowners"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/publisher"
+)
+
+const (
+	// complianceReport is the report name of compliance checks in task stats
+	complianceReport = "compliance"
+)
+
+// evaluateAndPublishCompliance checks pull requests merged and commits pushed directly to protected branches
+// since the last run against compliance rules and publish violations to targets.
+func (t *Task) evaluateAndPublishCompliance(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats, cache *pullRequestCache) error {
+	cfg := t.Config.Compliance
+	if !cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	lastRun := ts.LastReportTime[complianceReport]
+	if lastRun.IsZero() {
+		lastRun = now.Add(-(t.SchedulingInterval))
+	}
+	rules := make(map[string]bool)
+	for _, r := range cfg.Rules {
+		rules[r] = true
+	}
+	protected := make(map[string]bool)
+	for _, br := range cfg.ProtectedBranches {
+		protected[br] = true
+	}
+
+	var (
+		co    *codeowners.CodeOwners
+		teams *codeowners.Teams
+	)
+	if rules[input.ComplianceNonCodeOwnerApproval] {
+		content, err := gp.GetCodeOwners()
+		if err != nil {
+			log.Errorf("error[%v] in getting CODEOWNERS from gitprovider for task with ID %v", err, t.ID)
+			return err
+		}
+		if content != nil {
+			if co, err = codeowners.Parse(bytes.NewReader(content)); err != nil {
+				log.Errorf("error[%v] in parsing CODEOWNERS for task with ID %v", err, t.ID)
+				return err
+			}
+			// repositories owned by users have no teams , so only user owners are matched for them
+			teamsByte, err := gp.GetTeams()
+			if err == nil {
+				teams, _ = codeowners.ParseTeams(teamsByte)
+			}
+		}
+	}
+
+	changes := make([]derivedmetrics.Change, 0)
+	prBytes, err := gp.GetClosedPullRequests(lastRun)
+	if err != nil {
+		log.Errorf("error[%v] in getting closed pull requests from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	pullRequestDocs, err := dp.ProcessPullRequests(prBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing closed pull requests for task with ID %v", err, t.ID)
+		return err
+	}
+	var pullRequests []dataprocessor.PullRequest
+	if err = dataprocessor.DecodeDocuments(pullRequestDocs, &pullRequests); err != nil {
+		return err
+	}
+	// commits known to be part of merged pull requests are not looked up again when checking direct commits
+	pullRequestCommits := make(map[string]bool)
+	for i := range pullRequests {
+		pr := pullRequests[i]
+		if pr.MergedAt.IsZero() {
+			continue
+		}
+		if pr.MergeCommitSha != "" {
+			pullRequestCommits[pr.MergeCommitSha] = true
+		}
+		if !pr.MergedAt.After(lastRun) || !protected[pr.MergeToRepo.Branch] {
+			continue
+		}
+		change, err := t.getPullRequestChange(gp, dp, cache, &pr, rules, co != nil)
+		if err != nil {
+			log.Errorf("error[%v] in getting changes of pull request %v for task with ID %v", err, pr.PullRequestNo, t.ID)
+			return err
+		}
+		for _, c := range change.Commits {
+			pullRequestCommits[c.Sha] = true
+		}
+		changes = append(changes, change)
+	}
+	for _, br := range cfg.ProtectedBranches {
+		directChanges, err := t.getDirectCommitChanges(gp, dp, br, lastRun, now, rules, pullRequestCommits)
+		if err != nil {
+			log.Errorf("error[%v] in getting direct commits to branch %v for task with ID %v", err, br, t.ID)
+			return err
+		}
+		changes = append(changes, directChanges...)
+	}
+
+	updates, heads, err := t.getBranchUpdates(gp, dp, cfg.ProtectedBranches, ts.BranchHeads, false)
+	if err != nil {
+		return err
+	}
+
+	ce := derivedmetrics.NewComplianceEvaluator(t.Config.RepositoryName, t.Config.RepositoryURL, cfg, co, teams)
+	processed := ce.Evaluate(changes, updates, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing compliance violations for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	saveBranchHeads(t.ID, heads)
+	saveReportTime(t.ID, complianceReport, now)
+	return nil
+}
+
+// getPullRequestChange fetches reviews , commits , and as needed by rules changed files and checks of a merged pull request ,
+// reviews and commits already fetched in the run are taken from cache
+func (t *Task) getPullRequestChange(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, cache *pullRequestCache, pr *dataprocessor.PullRequest, rules map[string]bool, withFiles bool) (derivedmetrics.Change, error) {
+	change := derivedmetrics.Change{PullRequest: pr, Branch: pr.MergeToRepo.Branch}
+	number, err := strconv.Atoi(pr.PullRequestNo)
+	if err != nil {
+		return change, err
+	}
+	reviewBytes, err := cache.getReviews(gp, number)
+	if err != nil {
+		return change, err
+	}
+	reviews, err := dp.ProcessPullRequestReviews(pr.PullRequestNo, reviewBytes, nil)
+	if err != nil {
+		return change, err
+	}
+	if err = dataprocessor.DecodeDocuments(reviews, &change.Reviews); err != nil {
+		return change, err
+	}
+	commitBytes, err := cache.getCommits(gp, number)
+	if err != nil {
+		return change, err
+	}
+	commits, err := dp.ProcessCommits(commitBytes, nil)
+	if err != nil {
+		return change, err
+	}
+	if err = dataprocessor.DecodeDocuments(commits, &change.Commits); err != nil {
+		return change, err
+	}
+	if withFiles {
+		fileBytes, err := gp.GetPullRequestFiles(number)
+		if err != nil {
+			return change, err
+		}
+		if change.Files, err = dp.ProcessChangedFiles(fileBytes); err != nil {
+			return change, err
+		}
+	}
+	if rules[input.ComplianceFailingChecks] {
+		if change.Checks, err = t.getCommitChecks(gp, dp, pr.RequestFromRepo.Sha); err != nil {
+			return change, err
+		}
+	}
+	return change, nil
+}
+
+// getDirectCommitChanges fetches commits to branch between from and to which are not part of any pull request.
+// Pull requests of a commit are fetched from git provider only if the commit is not in pullRequestCommits.
+func (t *Task) getDirectCommitChanges(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, branch string, from time.Time, to time.Time, rules map[string]bool, pullRequestCommits map[string]bool) ([]derivedmetrics.Change, error) {
+	changes := make([]derivedmetrics.Change, 0)
+	commitBytes, err := gp.GetCommits(from, to, branch)
+	if err != nil {
+		return changes, err
+	}
+	commitDocs, err := dp.ProcessCommits(commitBytes, nil)
+	if err != nil {
+		return changes, err
+	}
+	var commits []dataprocessor.Commit
+	if err = dataprocessor.DecodeDocuments(commitDocs, &commits); err != nil {
+		return changes, err
+	}
+	for i := range commits {
+		commit := commits[i]
+		if pullRequestCommits[commit.Sha] {
+			continue
+		}
+		prBytes, err := gp.GetCommitPullRequests(commit.Sha)
+		if err != nil {
+			return changes, err
+		}
+		pullRequestDocs, err := dp.ProcessPullRequests(prBytes, nil)
+		if err != nil {
+			return changes, err
+		}
+		if len(pullRequestDocs) > 0 {
+			continue
+		}
+		change := derivedmetrics.Change{Commit: &commit, Branch: branch}
+		if rules[input.ComplianceFailingChecks] {
+			if change.Checks, err = t.getCommitChecks(gp, dp, commit.Sha); err != nil {
+				return changes, err
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// getCommitChecks fetches check runs and statuses of a commit
+func (t *Task) getCommitChecks(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, sha string) ([]dataprocessor.CommitCheck, error) {
+	var checks []dataprocessor.CommitCheck
+	checkBytes, err := gp.GetCommitChecks(sha)
+	if err != nil {
+		return checks, err
+	}
+	processed, err := dp.ProcessCommitChecks(sha, checkBytes, nil)
+	if err != nil {
+		return checks, err
+	}
+	err = dataprocessor.DecodeDocuments(processed, &checks)
+	return checks, err
+}
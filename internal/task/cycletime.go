@@ -0,0 +1,138 @@
+package task
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/publisher"
+)
+
+// collectAndPublishPullRequestCycleTimes collects pull requests merged since the last run and publish their cycle time
+// along with their reviews to targets.
+func (t *Task) collectAndPublishPullRequestCycleTimes(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats, cache *pullRequestCache) error {
+	lastMergedAt := ts.LastMergedPullRequestTime
+	// task stats saved by older versions do not have last merged pull request time
+	if lastMergedAt.IsZero() {
+		lastMergedAt = time.Now().Add(-(t.SchedulingInterval))
+	}
+	prBytes, err := gp.GetClosedPullRequests(lastMergedAt)
+	if err != nil {
+		log.Errorf("error[%v] in getting closed pull requests from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	pullRequestDocs, err := dp.ProcessPullRequests(prBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing closed pull requests for task with ID %v", err, t.ID)
+		return err
+	}
+	var pullRequests []dataprocessor.PullRequest
+	if err = dataprocessor.DecodeDocuments(pullRequestDocs, &pullRequests); err != nil {
+		return err
+	}
+	processed := make([]interface{}, 0)
+	latestMergedAt := lastMergedAt
+	for _, pr := range pullRequests {
+		if !pr.MergedAt.After(lastMergedAt) {
+			continue
+		}
+		activity, err := t.getPullRequestActivity(gp, cache, pr)
+		if err != nil {
+			log.Errorf("error[%v] in getting activity of pull request %v for task with ID %v", err, pr.PullRequestNo, t.ID)
+			return err
+		}
+		cycleTime, err := dp.ProcessPullRequestCycleTime(activity, t.Config.Tags)
+		if err != nil {
+			log.Errorf("error[%v] in processing cycle time of pull request %v for task with ID %v", err, pr.PullRequestNo, t.ID)
+			return err
+		}
+		processed = append(processed, cycleTime...)
+		reviews, err := dp.ProcessPullRequestReviews(pr.PullRequestNo, activity.Reviews, t.Config.Tags)
+		if err != nil {
+			log.Errorf("error[%v] in processing reviews of pull request %v for task with ID %v", err, pr.PullRequestNo, t.ID)
+			return err
+		}
+		processed = append(processed, reviews...)
+		if pr.MergedAt.After(latestMergedAt) {
+			latestMergedAt = pr.MergedAt
+		}
+	}
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing pull request cycle times and reviews for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	updateTaskStats(t.ID, func(saved *TaskStats) {
+		saved.LastMergedPullRequestTime = latestMergedAt
+	})
+	return nil
+}
+
+// pullRequestCache holds reviews and commits of pull requests as fetched from git provider with pull request number as key.
+// It is shared by all targets and stages of a task run , so merged pull requests are fetched once per run.
+type pullRequestCache struct {
+	mu      sync.Mutex
+	reviews map[int][]byte
+	commits map[int][]byte
+}
+
+// newPullRequestCache returns an empty cache of reviews and commits of pull requests
+func newPullRequestCache() *pullRequestCache {
+	return &pullRequestCache{reviews: make(map[int][]byte), commits: make(map[int][]byte)}
+}
+
+// getReviews returns reviews of a pull request , fetching them if not in cache
+func (pc *pullRequestCache) getReviews(gp gitprovider.GitProvider, number int) ([]byte, error) {
+	return pc.get(pc.reviews, number, gp.GetPullRequestReviews)
+}
+
+// getCommits returns commits of a pull request , fetching them if not in cache
+func (pc *pullRequestCache) getCommits(gp gitprovider.GitProvider, number int) ([]byte, error) {
+	return pc.get(pc.commits, number, gp.GetPullRequestCommits)
+}
+
+// get returns the entry of cache for a pull request , fetch is called without holding the lock so other pull requests
+// are not blocked. Failed fetches are not cached.
+func (pc *pullRequestCache) get(cache map[int][]byte, number int, fetch func(int) ([]byte, error)) ([]byte, error) {
+	pc.mu.Lock()
+	data, ok := cache[number]
+	pc.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+	data, err := fetch(number)
+	if err != nil {
+		return nil, err
+	}
+	pc.mu.Lock()
+	cache[number] = data
+	pc.mu.Unlock()
+	return data, nil
+}
+
+// getPullRequestActivity fetches reviews , commits , review comments and timeline of a pull request , reviews and commits
+// already fetched in the run are taken from cache
+func (t *Task) getPullRequestActivity(gp gitprovider.GitProvider, cache *pullRequestCache, pr dataprocessor.PullRequest) (dataprocessor.PullRequestActivity, error) {
+	activity := dataprocessor.PullRequestActivity{PullRequest: pr}
+	number, err := strconv.Atoi(pr.PullRequestNo)
+	if err != nil {
+		return activity, err
+	}
+	if activity.Reviews, err = cache.getReviews(gp, number); err != nil {
+		return activity, err
+	}
+	if activity.Commits, err = cache.getCommits(gp, number); err != nil {
+		return activity, err
+	}
+	if activity.ReviewComments, err = gp.GetPullRequestComments(number); err != nil {
+		return activity, err
+	}
+	// timeline has the comments on the conversation along with changes of draft state
+	if activity.Timeline, err = gp.GetIssueTimeline(number); err != nil {
+		return activity, err
+	}
+	return activity, nil
+}
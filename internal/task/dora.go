@@ -0,0 +1,138 @@
+package task
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/input"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/publisher"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	// doraReport is the report name of dora metrics in task stats
+	doraReport = "dora"
+)
+
+// computeAndPublishDoraMetrics computes DORA metrics for the configured windows and publish them to targets.
+// Metrics are computed once every report interval as the whole window needs to be fetched again.
+func (t *Task) computeAndPublishDoraMetrics(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats, cache *pullRequestCache) error {
+	cfg := t.Config.Dora
+	if !cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	interval, _ := utils.ParseDuration(cfg.ReportInterval)
+	if now.Sub(ts.LastReportTime[doraReport]) < interval {
+		return nil
+	}
+	dc := derivedmetrics.NewDoraCalculator(t.Config.RepositoryName, t.Config.RepositoryURL, t.Config.Branches, cfg)
+	from := now.Add(-dc.MaxWindow())
+
+	var in derivedmetrics.DoraInput
+	if cfg.DeploymentSource == input.DoraSourceReleases {
+		releaseBytes, err := gp.GetReleases(from)
+		if err != nil {
+			log.Errorf("error[%v] in getting releases from gitprovider for task with ID %v", err, t.ID)
+			return err
+		}
+		releases, err := dp.ProcessReleases(releaseBytes, nil)
+		if err != nil {
+			log.Errorf("error[%v] in processing releases for task with ID %v", err, t.ID)
+			return err
+		}
+		if err = dataprocessor.DecodeDocuments(releases, &in.Releases); err != nil {
+			return err
+		}
+	} else {
+		deploymentBytes, err := gp.GetDeployments(cfg.Environment, from)
+		if err != nil {
+			log.Errorf("error[%v] in getting deployments from gitprovider for task with ID %v", err, t.ID)
+			return err
+		}
+		deployments, err := dp.ProcessDeployments(deploymentBytes, nil)
+		if err != nil {
+			log.Errorf("error[%v] in processing deployments for task with ID %v", err, t.ID)
+			return err
+		}
+		if err = dataprocessor.DecodeDocuments(deployments, &in.Deployments); err != nil {
+			return err
+		}
+	}
+
+	prBytes, err := gp.GetClosedPullRequests(from)
+	if err != nil {
+		log.Errorf("error[%v] in getting closed pull requests from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	pullRequests, err := dp.ProcessPullRequests(prBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing closed pull requests for task with ID %v", err, t.ID)
+		return err
+	}
+	if err = dataprocessor.DecodeDocuments(pullRequests, &in.PullRequests); err != nil {
+		return err
+	}
+	// commits of a merged pull request do not change , so only pull requests merged since the last report are fetched
+	in.FirstCommitAt = make(map[string]time.Time)
+	for _, pr := range in.PullRequests {
+		if pr.MergedAt.IsZero() {
+			continue
+		}
+		if firstCommitAt, ok := ts.FirstCommitTimes[pr.PullRequestNo]; ok {
+			in.FirstCommitAt[pr.PullRequestNo] = firstCommitAt
+			continue
+		}
+		firstCommitAt, err := t.firstCommitTime(gp, dp, cache, pr.PullRequestNo)
+		if err != nil {
+			log.Errorf("error[%v] in getting first commit of pull request %v for task with ID %v", err, pr.PullRequestNo, t.ID)
+			continue
+		}
+		in.FirstCommitAt[pr.PullRequestNo] = firstCommitAt
+	}
+
+	if cfg.FailureSource != input.DoraSourceDeployments {
+		issueBytes, err := gp.GetIssuesByLabels(cfg.IncidentLabels, from)
+		if err != nil {
+			log.Errorf("error[%v] in getting incident issues from gitprovider for task with ID %v", err, t.ID)
+			return err
+		}
+		incidents, err := dp.ProcessIssues(issueBytes, nil)
+		if err != nil {
+			log.Errorf("error[%v] in processing incident issues for task with ID %v", err, t.ID)
+			return err
+		}
+		if err = dataprocessor.DecodeDocuments(incidents, &in.Incidents); err != nil {
+			return err
+		}
+	}
+
+	processed := dc.Compute(in, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing dora metrics for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task , pull requests merged before the windows are dropped
+	updateTaskStats(t.ID, func(saved *TaskStats) {
+		saved.FirstCommitTimes = in.FirstCommitAt
+	})
+	saveReportTime(t.ID, doraReport, now)
+	return nil
+}
+
+// firstCommitTime returns the time the earliest commit of a pull request was authored , as in its cycle time
+func (t *Task) firstCommitTime(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, cache *pullRequestCache, prNo string) (time.Time, error) {
+	number, err := strconv.Atoi(prNo)
+	if err != nil {
+		return time.Time{}, err
+	}
+	commitBytes, err := cache.getCommits(gp, number)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return dp.ProcessFirstCommitTime(commitBytes)
+}
@@ -0,0 +1,20 @@
+package task
+
+import (
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/publisher"
+)
+
+// enrichingPublisher adds fields to documents using enrichers before publishing them
+type enrichingPublisher struct {
+	publisher.Publisher
+	enrichers []enrichment.Enricher
+}
+
+// Publish enriches documents in place and publishes them
+func (ep enrichingPublisher) Publish(docs []interface{}) error {
+	for _, e := range ep.enrichers {
+		e.Enrich(docs)
+	}
+	return ep.Publisher.Publish(docs)
+}
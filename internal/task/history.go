@@ -0,0 +1,104 @@
+package task
+
+import (
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/publisher"
+)
+
+// detectAndPublishHistoryRewrites compares heads of monitored branches with heads of the last run
+// and publish history rewrites of branches whose previous head is no more an ancestor of the head.
+func (t *Task) detectAndPublishHistoryRewrites(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats) error {
+	now := time.Now()
+	updates, heads, err := t.getBranchUpdates(gp, dp, t.Config.Branches, ts.BranchHeads, true)
+	if err != nil {
+		return err
+	}
+	var pushes []dataprocessor.Push
+	for _, u := range updates {
+		if !u.Rewritten {
+			continue
+		}
+		// pusher is optional , so failures in getting push events do not fail the stage
+		eventBytes, err := gp.GetPushEvents()
+		if err == nil {
+			pushes, err = dp.ProcessPushEvents(eventBytes)
+		}
+		if err != nil {
+			log.Errorf("error[%v] in getting push events for task with ID %v", err, t.ID)
+		}
+		break
+	}
+	hd := derivedmetrics.NewHistoryRewriteDetector(t.Config.RepositoryName, t.Config.RepositoryURL)
+	processed := hd.Detect(updates, pushes, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing history rewrites for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	saveBranchHeads(t.ID, heads)
+	return nil
+}
+
+// getBranchUpdates fetches current heads of branches and returns the moves of heads since previous heads along with
+// current heads. Branches without previous head are only recorded. Commits lost by rewrites are fetched if withLostCommits is true.
+func (t *Task) getBranchUpdates(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, branches []string, previousHeads map[string]string, withLostCommits bool) ([]derivedmetrics.BranchUpdate, map[string]string, error) {
+	updates := make([]derivedmetrics.BranchUpdate, 0)
+	heads := make(map[string]string, len(branches))
+	for _, br := range branches {
+		head, err := gp.GetBranchHead(br)
+		if err != nil {
+			log.Errorf("error[%v] in getting head of branch %v for task with ID %v", err, br, t.ID)
+			return updates, heads, err
+		}
+		heads[br] = head
+		previous := previousHeads[br]
+		if previous == "" || previous == head {
+			continue
+		}
+		isAncestor, err := gp.IsAncestor(previous, head)
+		if err != nil {
+			log.Errorf("error[%v] in comparing heads of branch %v for task with ID %v", err, br, t.ID)
+			return updates, heads, err
+		}
+		update := derivedmetrics.BranchUpdate{Branch: br, PreviousHead: previous, Head: head, Rewritten: !isAncestor}
+		if update.Rewritten && withLostCommits {
+			// commits of previous head missing in head are the ones lost by the rewrite
+			commitBytes, err := gp.GetComparedCommits(head, previous)
+			if err != nil {
+				log.Errorf("error[%v] in getting lost commits of branch %v for task with ID %v", err, br, t.ID)
+				return updates, heads, err
+			}
+			if commitBytes != nil {
+				commits, err := dp.ProcessCommits(commitBytes, nil)
+				if err != nil {
+					return updates, heads, err
+				}
+				if err = dataprocessor.DecodeDocuments(commits, &update.LostCommits); err != nil {
+					return updates, heads, err
+				}
+				update.LostCommitsResolved = true
+			}
+		}
+		updates = append(updates, update)
+	}
+	return updates, heads, nil
+}
+
+// saveBranchHeads saves heads of branches in task stats , keeping heads of other branches
+func saveBranchHeads(id string, heads map[string]string) {
+	updateTaskStats(id, func(saved *TaskStats) {
+		merged := make(map[string]string, len(saved.BranchHeads)+len(heads))
+		for k, v := range saved.BranchHeads {
+			merged[k] = v
+		}
+		for k, v := range heads {
+			merged[k] = v
+		}
+		saved.BranchHeads = merged
+	})
+}
@@ -0,0 +1,227 @@
+package task
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/publisher"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	// hotspotReport is the report name of file hotspots in task stats
+	hotspotReport = "hotspot"
+)
+
+// analyzeAndPublishHotspots computes churn of files and directories changed by commits to monitored branches within
+// the window and publish the most changed ones to targets. Reports are made once every report interval as files
+// of every commit in window need to be fetched again.
+func (t *Task) analyzeAndPublishHotspots(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats, resolver *enrichment.IdentityResolver, cache *commitFilesCache) error {
+	cfg := t.Config.Hotspots
+	if !cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	interval, _ := utils.ParseDuration(cfg.ReportInterval)
+	if now.Sub(ts.LastReportTime[hotspotReport]) < interval {
+		return nil
+	}
+	ha := derivedmetrics.NewHotspotAnalyzer(t.Config.RepositoryName, t.Config.RepositoryURL, cfg, resolver)
+	commitFiles, err := t.getCommitFiles(gp, dp, cache, ha.Since(now), now)
+	if err != nil {
+		return err
+	}
+	processed := ha.Analyze(commitFiles, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing file hotspots for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	saveReportTime(t.ID, hotspotReport, now)
+	return nil
+}
+
+// commitFilesCache holds changes of files of commits with commit sha as key. It is shared by all targets and reports
+// of a task run and kept on disk across runs , so files of a commit are fetched once and later runs only fetch files of new commits.
+// Changes of a commit never change , so entries are not refreshed. Entries not used for longer than the retention are pruned.
+type commitFilesCache struct {
+	mu    sync.Mutex
+	files map[string][]dataprocessor.FileChange
+	// dir is the directory having a file of changes per commit , empty to keep entries only in memory
+	dir string
+}
+
+// newCommitFilesCache returns a cache of changes of files of commits kept in dir
+func newCommitFilesCache(dir string) *commitFilesCache {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Errorf("error[%v] in creating commit files cache directory %v", err, dir)
+			dir = ""
+		}
+	}
+	return &commitFilesCache{files: make(map[string][]dataprocessor.FileChange), dir: dir}
+}
+
+func (cc *commitFilesCache) get(sha string) ([]dataprocessor.FileChange, bool) {
+	cc.mu.Lock()
+	files, ok := cc.files[sha]
+	cc.mu.Unlock()
+	if ok || cc.dir == "" {
+		return files, ok
+	}
+	path := cc.path(sha)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	if err = json.Unmarshal(b, &files); err != nil {
+		log.Errorf("error[%v] in unmarshalling commit files cache file %v", err, path)
+		return nil, false
+	}
+	// modification time tells when the entry was last used for pruning
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	cc.mu.Lock()
+	cc.files[sha] = files
+	cc.mu.Unlock()
+	return files, true
+}
+
+func (cc *commitFilesCache) set(sha string, files []dataprocessor.FileChange) {
+	cc.mu.Lock()
+	cc.files[sha] = files
+	cc.mu.Unlock()
+	if cc.dir == "" {
+		return
+	}
+	b, _ := json.Marshal(files)
+	// writing to a temporary file first so a partly written entry is never read
+	f, err := os.CreateTemp(cc.dir, sha+".*.tmp")
+	if err != nil {
+		log.Errorf("error[%v] in creating commit files cache file of commit %v", err, sha)
+		return
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), cc.path(sha))
+	}
+	if err != nil {
+		log.Errorf("error[%v] in writing commit files cache file of commit %v", err, sha)
+		os.Remove(f.Name())
+	}
+}
+
+// prune removes files of entries not used within retention
+func (cc *commitFilesCache) prune(retention time.Duration) {
+	if cc.dir == "" {
+		return
+	}
+	entries, err := os.ReadDir(cc.dir)
+	if err != nil {
+		log.Errorf("error[%v] in reading commit files cache directory %v", err, cc.dir)
+		return
+	}
+	before := time.Now().Add(-retention)
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(before) {
+			continue
+		}
+		if err = os.Remove(filepath.Join(cc.dir, e.Name())); err != nil {
+			log.Errorf("error[%v] in removing commit files cache file %v", err, e.Name())
+		}
+	}
+}
+
+func (cc *commitFilesCache) path(sha string) string {
+	return filepath.Join(cc.dir, sha+".json")
+}
+
+// commitFilesRetention returns for how long unused entries of commit files cache are kept. Every report using the cache
+// reads all commits of its window once every report interval , so entries in a window are used at least that often.
+func (t *Task) commitFilesRetention() time.Duration {
+	retention := t.SchedulingInterval
+	for _, report := range []struct {
+		enabled  bool
+		interval string
+	}{
+		{t.Config.Hotspots.Enabled, t.Config.Hotspots.ReportInterval},
+		{t.Config.Knowledge.Enabled, t.Config.Knowledge.ReportInterval},
+	} {
+		interval, _ := utils.ParseDuration(report.interval)
+		if report.enabled && interval > retention {
+			retention = interval
+		}
+	}
+	// keeping entries for twice as long so a delayed run still finds them
+	return 2 * retention
+}
+
+// getCommitFiles fetches commits to monitored branches between from and to along with changes of their files ,
+// files of commits found in cache are not fetched again
+func (t *Task) getCommitFiles(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, cache *commitFilesCache, from time.Time, to time.Time) ([]derivedmetrics.CommitFiles, error) {
+	commitFiles := make([]derivedmetrics.CommitFiles, 0)
+	commits, err := t.getBranchCommits(gp, dp, from, to)
+	if err != nil {
+		return commitFiles, err
+	}
+	for _, c := range commits {
+		if files, ok := cache.get(c.Sha); ok {
+			commitFiles = append(commitFiles, derivedmetrics.CommitFiles{Commit: c, Files: files})
+			continue
+		}
+		fileBytes, err := gp.GetCommitFiles(c.Sha)
+		if err != nil {
+			log.Errorf("error[%v] in getting files of commit %v for task with ID %v", err, c.Sha, t.ID)
+			return commitFiles, err
+		}
+		files, err := dp.ProcessFileChanges(fileBytes)
+		if err != nil {
+			log.Errorf("error[%v] in processing files of commit %v for task with ID %v", err, c.Sha, t.ID)
+			return commitFiles, err
+		}
+		cache.set(c.Sha, files)
+		commitFiles = append(commitFiles, derivedmetrics.CommitFiles{Commit: c, Files: files})
+	}
+	return commitFiles, nil
+}
+
+// getBranchCommits fetches commits to monitored branches between from and to , commits present in more than one branch are returned once
+func (t *Task) getBranchCommits(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, from time.Time, to time.Time) ([]dataprocessor.Commit, error) {
+	commits := make([]dataprocessor.Commit, 0)
+	seen := make(map[string]bool)
+	for _, br := range t.Config.Branches {
+		commitBytes, err := gp.GetCommits(from, to, br)
+		if err != nil {
+			log.Errorf("error[%v] in getting commits of branch %v from gitprovider for task with ID %v", err, br, t.ID)
+			return commits, err
+		}
+		commitDocs, err := dp.ProcessCommits(commitBytes, nil)
+		if err != nil {
+			log.Errorf("error[%v] in processing commits of branch %v for task with ID %v", err, br, t.ID)
+			return commits, err
+		}
+		var branchCommits []dataprocessor.Commit
+		if err = dataprocessor.DecodeDocuments(commitDocs, &branchCommits); err != nil {
+			return commits, err
+		}
+		for _, c := range branchCommits {
+			if !seen[c.Sha] {
+				seen[c.Sha] = true
+				commits = append(commits, c)
+			}
+		}
+	}
+	return commits, nil
+}
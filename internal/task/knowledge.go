@@ -0,0 +1,45 @@
+package task
+
+import (
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/publisher"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	// knowledgeReport is the report name of knowledge distribution in task stats
+	knowledgeReport = "knowledge"
+)
+
+// analyzeAndPublishKnowledge computes how concentrated changes to monitored branches within the window are among authors ,
+// for the repository and each top level directory , and publish them to targets. Reports are made once every report interval.
+func (t *Task) analyzeAndPublishKnowledge(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats, resolver *enrichment.IdentityResolver, cache *commitFilesCache) error {
+	cfg := t.Config.Knowledge
+	if !cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	interval, _ := utils.ParseDuration(cfg.ReportInterval)
+	if now.Sub(ts.LastReportTime[knowledgeReport]) < interval {
+		return nil
+	}
+	ka := derivedmetrics.NewKnowledgeAnalyzer(t.Config.RepositoryName, t.Config.RepositoryURL, cfg, resolver)
+	commitFiles, err := t.getCommitFiles(gp, dp, cache, ka.Since(now), now)
+	if err != nil {
+		return err
+	}
+	processed := ka.Analyze(commitFiles, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing knowledge distribution for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	saveReportTime(t.ID, knowledgeReport, now)
+	return nil
+}
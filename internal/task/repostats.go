@@ -0,0 +1,90 @@
+package task
+
+import (
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/publisher"
+)
+
+// repositoryStats represents a kind of weekly stats with the functions to fetch and process it
+type repositoryStats struct {
+	name         string
+	documentType string
+	fetch        func() ([]byte, error)
+	process      func(data []byte, from time.Time, to time.Time, tags map[string]string) ([]interface{}, error)
+}
+
+// collectAndPublishRepositoryStats collects weekly contributor , code frequency and commit activity stats and publish them to targets.
+// Stats are only published for completed weeks , so the git provider is queried at most once a week for each kind of stats.
+// Stats which git provider has not computed yet are fetched again in the next run , along with the weeks missed meanwhile.
+// Stats which git provider does not compute for the repository are skipped for the week.
+func (t *Task) collectAndPublishRepositoryStats(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats) error {
+	if !t.Config.RepositoryStats.Enabled {
+		return nil
+	}
+	lastWeek := lastCompletedWeek(time.Now())
+	stats := []repositoryStats{
+		{"contributor stats", dataprocessor.CONTRIBUTORSTATS, gp.GetContributorStats, dp.ProcessContributorStats},
+		{"code frequency", dataprocessor.CODEFREQUENCY, gp.GetCodeFrequency, dp.ProcessCodeFrequency},
+		{"commit activity", dataprocessor.COMMITACTIVITY, gp.GetCommitActivity, dp.ProcessCommitActivity},
+	}
+	for _, s := range stats {
+		from, ok := ts.LastStatsWeeks[s.documentType]
+		// task stats saved by older versions have a single week for all stats
+		if !ok {
+			from = ts.LastStatsWeek
+		}
+		if !lastWeek.After(from) {
+			log.Debugf("%v already published for week %v for task with ID %v", s.name, lastWeek, t.ID)
+			continue
+		}
+		data, err := s.fetch()
+		if err == gitprovider.ErrStatsNotReady {
+			log.Warnf("%v not ready for week %v for task with ID %v , fetching again in next run", s.name, lastWeek, t.ID)
+			continue
+		}
+		if err == gitprovider.ErrStatsUnavailable {
+			log.Warnf("%v skipped for week %v for task with ID %v , %v", s.name, lastWeek, t.ID, err)
+			saveStatsWeek(t.ID, s.documentType, lastWeek)
+			continue
+		}
+		if err != nil {
+			log.Errorf("error[%v] in getting %v from gitprovider for task with ID %v", err, s.name, t.ID)
+			return err
+		}
+		processed, err := s.process(data, from, lastWeek, t.Config.Tags)
+		if err != nil {
+			log.Errorf("error[%v] in processing %v for task with ID %v", err, s.name, t.ID)
+			return err
+		}
+		err = pb.Publish(processed)
+		if err != nil {
+			log.Errorf("error[%v] in publishing %v for task with ID %v", err, s.name, t.ID)
+			return err
+		}
+		// saving stats after finished task
+		saveStatsWeek(t.ID, s.documentType, lastWeek)
+	}
+	return nil
+}
+
+// saveStatsWeek saves the last week for which a kind of repository stats was published for a particular task.
+func saveStatsWeek(id string, documentType string, week time.Time) {
+	updateTaskStats(id, func(saved *TaskStats) {
+		weeks := make(map[string]time.Time, len(saved.LastStatsWeeks)+1)
+		for k, v := range saved.LastStatsWeeks {
+			weeks[k] = v
+		}
+		weeks[documentType] = week
+		saved.LastStatsWeeks = weeks
+	})
+}
+
+// lastCompletedWeek returns the start of the last completed week , github weeks start on sunday 00:00 UTC.
+func lastCompletedWeek(now time.Time) time.Time {
+	now = now.UTC()
+	currentWeek := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -int(now.Weekday()))
+	return currentWeek.AddDate(0, 0, -7)
+}
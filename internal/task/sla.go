@@ -0,0 +1,133 @@
+package task
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/publisher"
+)
+
+const (
+	// slaReport is the report name of sla evaluation in task stats
+	slaReport = "sla"
+)
+
+// evaluateAndPublishSLAs evaluates open issues and issues closed since the last run against sla rules and publish
+// sla status and breaches to targets. Open issues are evaluated on every run so breaches are published while the issue is open.
+func (t *Task) evaluateAndPublishSLAs(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats) error {
+	if len(t.Config.SLARules) == 0 {
+		return nil
+	}
+	now := time.Now()
+	lastRun := ts.LastReportTime[slaReport]
+	if lastRun.IsZero() {
+		lastRun = now.Add(-(t.SchedulingInterval))
+	}
+	openBytes, err := gp.GetOpenIssues()
+	if err != nil {
+		log.Errorf("error[%v] in getting open issues from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	openDocs, err := dp.ProcessIssues(openBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing open issues for task with ID %v", err, t.ID)
+		return err
+	}
+	updatedBytes, err := gp.GetIssues(lastRun)
+	if err != nil {
+		log.Errorf("error[%v] in getting updated issues from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	updatedDocs, err := dp.ProcessIssues(updatedBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing updated issues for task with ID %v", err, t.ID)
+		return err
+	}
+	var openIssues, updatedIssues []dataprocessor.Issue
+	if err = dataprocessor.DecodeDocuments(openDocs, &openIssues); err != nil {
+		return err
+	}
+	if err = dataprocessor.DecodeDocuments(updatedDocs, &updatedIssues); err != nil {
+		return err
+	}
+	// closed issues are evaluated one last time after they are closed
+	issues := openIssues
+	for _, issue := range updatedIssues {
+		closedAt, err := time.Parse(time.RFC3339, issue.ClosedAt)
+		if err == nil && closedAt.After(lastRun) {
+			issues = append(issues, issue)
+		}
+	}
+
+	se := derivedmetrics.NewSLAEvaluator(t.Config.RepositoryName, t.Config.RepositoryURL, t.Config.SLARules)
+	states := make(map[string]derivedmetrics.SLAState, len(ts.SLAStates))
+	for k, v := range ts.SLAStates {
+		states[k] = v
+	}
+	matched := make([]dataprocessor.Issue, 0)
+	for _, issue := range issues {
+		if !se.Matches(issue) {
+			continue
+		}
+		matched = append(matched, issue)
+		state := states[issue.IssueNo]
+		// a new comment updates the issue , so comments are fetched again only for issues updated after they were checked
+		if !state.FirstResponseAt.IsZero() || (!state.CommentsCheckedAt.IsZero() && !issue.UpdatedAt.After(state.CommentsCheckedAt)) {
+			continue
+		}
+		state.FirstResponseAt, err = t.firstResponseTime(gp, dp, issue)
+		if err != nil {
+			log.Errorf("error[%v] in getting first response of issue %v for task with ID %v", err, issue.IssueNo, t.ID)
+			return err
+		}
+		// updated time of issue is kept instead of now so that clocks of git provider and host are not compared
+		state.CommentsCheckedAt = issue.UpdatedAt
+		states[issue.IssueNo] = state
+	}
+
+	processed, openStates := se.Evaluate(matched, states, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing issue slas for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	updateTaskStats(t.ID, func(saved *TaskStats) {
+		saved.SLAStates = openStates
+	})
+	saveReportTime(t.ID, slaReport, now)
+	return nil
+}
+
+// firstResponseTime returns the time of first comment on issue by someone other than the author , zero if there is none
+func (t *Task) firstResponseTime(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, issue dataprocessor.Issue) (time.Time, error) {
+	var firstResponseAt time.Time
+	number, err := strconv.Atoi(issue.IssueNo)
+	if err != nil {
+		return firstResponseAt, err
+	}
+	commentBytes, err := gp.GetIssueComments(number)
+	if err != nil {
+		return firstResponseAt, err
+	}
+	processed, err := dp.ProcessIssueComments(issue.IssueNo, commentBytes, nil)
+	if err != nil {
+		return firstResponseAt, err
+	}
+	var comments []dataprocessor.IssueComment
+	if err = dataprocessor.DecodeDocuments(processed, &comments); err != nil {
+		return firstResponseAt, err
+	}
+	for _, c := range comments {
+		if c.CreatedBy.ID == issue.CreatedBy.ID {
+			continue
+		}
+		if firstResponseAt.IsZero() || c.CreatedAt.Before(firstResponseAt) {
+			firstResponseAt = c.CreatedAt
+		}
+	}
+	return firstResponseAt, nil
+}
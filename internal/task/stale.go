@@ -0,0 +1,74 @@
+package task
+
+import (
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/publisher"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	// staleReport is the report name of stale pull requests and branches in task stats
+	staleReport = "stale"
+)
+
+// reportAndPublishStale finds open pull requests and branches without activity for longer than the configured ages
+// and publish them to targets. Reports are made once every report interval as all branches need to be fetched again.
+func (t *Task) reportAndPublishStale(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats) error {
+	cfg := t.Config.Stale
+	if !cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	interval, _ := utils.ParseDuration(cfg.ReportInterval)
+	if now.Sub(ts.LastReportTime[staleReport]) < interval {
+		return nil
+	}
+	prBytes, err := gp.GetOpenPullRequests()
+	if err != nil {
+		log.Errorf("error[%v] in getting open pull requests from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	pullRequestDocs, err := dp.ProcessPullRequests(prBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing open pull requests for task with ID %v", err, t.ID)
+		return err
+	}
+	var pullRequests []dataprocessor.PullRequest
+	if err = dataprocessor.DecodeDocuments(pullRequestDocs, &pullRequests); err != nil {
+		return err
+	}
+	branchBytes, err := gp.GetBranches()
+	if err != nil {
+		log.Errorf("error[%v] in getting branches from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	branches, err := dp.ProcessBranches(branchBytes)
+	if err != nil {
+		log.Errorf("error[%v] in processing branches for task with ID %v", err, t.ID)
+		return err
+	}
+	eventBytes, err := gp.GetPushEvents()
+	if err != nil {
+		log.Errorf("error[%v] in getting push events from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	pushes, err := dp.ProcessPushEvents(eventBytes)
+	if err != nil {
+		log.Errorf("error[%v] in processing push events for task with ID %v", err, t.ID)
+		return err
+	}
+	sr := derivedmetrics.NewStaleReporter(t.Config.RepositoryName, t.Config.RepositoryURL, t.Config.Branches, cfg)
+	processed := sr.Report(pullRequests, branches, pushes, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing stale pull requests and branches for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	saveReportTime(t.ID, staleReport, now)
+	return nil
+}
@@ -6,7 +6,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync"
@@ -15,6 +17,9 @@ import (
 	"github.com/maplelabs/github-audit/gitprovider"
 	"github.com/maplelabs/github-audit/input"
 	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/internal/signature"
 	"github.com/maplelabs/github-audit/logger"
 	"github.com/maplelabs/github-audit/publisher"
 )
@@ -29,6 +34,8 @@ var (
 	taskStatsMutex = &sync.Mutex{}
 	// saveTaskPeriodicInterval is interval for which checkpoint for task is done
 	saveTaskPeriodicInterval = 30 * time.Second
+	// commitFilesDir is the directory where changes of files of commits are cached with a directory per task.
+	commitFilesDir = "commitFiles"
 )
 
 // Task represents a single task where one auditjob = one task
@@ -81,6 +88,34 @@ type TaskStats struct {
 
 	// LastIssueTime represents the last issue time for the audit job.
 	LastIssueTime time.Time
+
+	// LastStatsWeek represents the start of the last completed week for which repository stats were published ,
+	// it is only read for task stats saved by older versions which do not have LastStatsWeeks.
+	LastStatsWeek time.Time
+
+	// LastStatsWeeks represents the start of the last completed week for which each repository stat was published with document type as key.
+	LastStatsWeeks map[string]time.Time
+
+	// LastReportTime represents the last time each periodic report was published with report name as key.
+	LastReportTime map[string]time.Time
+
+	// LastMergedPullRequestTime represents the merge time of last pull request for which cycle time was published.
+	LastMergedPullRequestTime time.Time
+
+	// SLAStates represents the sla tracking state of open issues with issue number as key.
+	SLAStates map[string]derivedmetrics.SLAState
+
+	// BranchHeads represents the head commit sha of each monitored and protected branch in the last run with branch as key.
+	BranchHeads map[string]string
+
+	// AnomalyBaselines represents the baselines of repository activity against which anomalies are detected.
+	AnomalyBaselines derivedmetrics.AnomalyBaselines
+
+	// LastWorkPatternWeek represents the start of the last completed week for which work patterns were published.
+	LastWorkPatternWeek time.Time
+
+	// FirstCommitTimes represents the time of first commit of pull requests merged within dora windows with pull request number as key.
+	FirstCommitTimes map[string]time.Time
 }
 
 func init() {
@@ -145,6 +180,29 @@ func saveTaskStats(id string, ts TaskStats) {
 	TaskStatsMap[id] = ts
 }
 
+// updateTaskStats applies update to the saved task stats of a particular task while holding the lock,
+// so that only the fields touched by update are modified. Maps in task stats are shared between goroutines
+// and must be replaced instead of being modified in place.
+func updateTaskStats(id string, update func(ts *TaskStats)) {
+	taskStatsMutex.Lock()
+	defer taskStatsMutex.Unlock()
+	ts := TaskStatsMap[id]
+	update(&ts)
+	TaskStatsMap[id] = ts
+}
+
+// saveReportTime saves the time at which a periodic report was published for a particular task.
+func saveReportTime(id string, report string, at time.Time) {
+	updateTaskStats(id, func(saved *TaskStats) {
+		reportTimes := make(map[string]time.Time, len(saved.LastReportTime)+1)
+		for k, v := range saved.LastReportTime {
+			reportTimes[k] = v
+		}
+		reportTimes[report] = at
+		saved.LastReportTime = reportTimes
+	})
+}
+
 // Newtask returns new task instance.
 func Newtask() *Task {
 	task := new(Task)
@@ -177,6 +235,7 @@ func (t *Task) Start() error {
 	// if error reading previous stats , putting default values for task stats map
 	if err != nil {
 		ts.LastIssueTime = time.Now().Add(-(t.SchedulingInterval))
+		ts.LastMergedPullRequestTime = time.Now().Add(-(t.SchedulingInterval))
 		for _, br := range t.Config.Branches {
 			ts.LastCommitTime[br] = time.Now().Add(-(t.SchedulingInterval))
 		}
@@ -186,6 +245,13 @@ func (t *Task) Start() error {
 		saveTaskStats(t.ID, ts)
 	}
 	gp := gitprovider.NewGitProvider(t.Config.RepositoryHost, t.Config.RepositoryOwner, t.Config.RepositoryName, t.Config.Username, t.DecodeAccessKey)
+	// enrichers are shared by all targets of the run , identity resolver must come first
+	resolver := enrichment.NewIdentityResolver(t.Config.Identity)
+	enrichers := []enrichment.Enricher{resolver, enrichment.NewOwnershipEnricher(gp, t.Config.Ownership, resolver)}
+	verifier := signature.NewVerifier(t.Config.SignatureVerification)
+	// files of commits are fetched once for all runs , reviews and commits of pull requests once per run , for all targets and reports using them
+	commitFiles := newCommitFilesCache(filepath.Join(commitFilesDir, url.PathEscape(t.ID)))
+	pullRequests := newPullRequestCache()
 
 	// max concurrency guard to control goroutines
 	maxConcurrencyGuard := make(chan struct{}, runtime.NumCPU()*2)
@@ -203,35 +269,51 @@ func (t *Task) Start() error {
 				log.Errorf("error[%v] in getting publisher for the task with ID %v", err, t.ID)
 				return
 			}
+			pb = enrichingPublisher{Publisher: pb, enrichers: enrichers}
 			// getting new dataprocessor
-			dp := dataprocessor.NewDataProcessor(t.Config.RepositoryHost, t.Config.RepositoryName, t.Config.RepositoryURL)
-			err = t.collectAndPublishCommits(gp, pb, dp, ts)
-			if err != nil {
-				log.Errorf("error[%v] in collecting commits for task with ID %v", err, t.ID)
-				return
-			}
-			err = t.collectAndPublishPullRequests(gp, pb, dp, ts)
-			if err != nil {
-				log.Errorf("error[%v] in collecting pull requests for task with ID %v", err, t.ID)
-				return
+			dp := dataprocessor.NewDataProcessor(t.Config.RepositoryHost, t.Config.RepositoryName, t.Config.RepositoryURL, t.Config.TicketPatterns, verifier)
+			// every stage fetches its own data and keeps its own progress in task stats , none of them
+			// depends on what another stage collected , so a failing stage is logged and the remaining ones still run
+			stages := []struct {
+				name string
+				run  func() error
+			}{
+				{"collecting commits", func() error { return t.collectAndPublishCommits(gp, pb, dp, ts) }},
+				{"collecting pull requests", func() error { return t.collectAndPublishPullRequests(gp, pb, dp, ts) }},
+				{"collecting issues", func() error { return t.collectAndPublishIssues(gp, pb, dp, ts) }},
+				{"collecting workflow runs", func() error { return t.collectAndPublishWorkflowRuns(gp, pb, dp, ts) }},
+				{"counting pull requests and issues", func() error { return t.countAndPublishItems(gp, pb, dp, ts) }},
+				{"collecting pull request cycle times", func() error { return t.collectAndPublishPullRequestCycleTimes(gp, pb, dp, ts, pullRequests) }},
+				{"evaluating issue slas", func() error { return t.evaluateAndPublishSLAs(gp, pb, dp, ts) }},
+				{"detecting history rewrites", func() error { return t.detectAndPublishHistoryRewrites(gp, pb, dp, ts) }},
+				{"evaluating compliance", func() error { return t.evaluateAndPublishCompliance(gp, pb, dp, ts, pullRequests) }},
+				{"detecting activity anomalies", func() error { return t.detectAndPublishAnomalies(gp, pb, dp, ts, resolver, commitFiles) }},
+				{"reporting stale pull requests and branches", func() error { return t.reportAndPublishStale(gp, pb, dp, ts) }},
+				{"analyzing file hotspots", func() error { return t.analyzeAndPublishHotspots(gp, pb, dp, ts, resolver, commitFiles) }},
+				{"analyzing knowledge distribution", func() error { return t.analyzeAndPublishKnowledge(gp, pb, dp, ts, resolver, commitFiles) }},
+				{"analyzing work patterns", func() error { return t.analyzeAndPublishWorkPatterns(gp, pb, dp, ts, resolver) }},
+				{"collecting repository stats", func() error { return t.collectAndPublishRepositoryStats(gp, pb, dp, ts) }},
+				{"computing dora metrics", func() error { return t.computeAndPublishDoraMetrics(gp, pb, dp, ts, pullRequests) }},
 			}
-			err = t.collectAndPublishIssues(gp, pb, dp, ts)
-			if err != nil {
-				log.Errorf("error[%v] in collecting issues for task with ID %v", err, t.ID)
-				return
+			for _, stage := range stages {
+				if err := stage.run(); err != nil {
+					log.Errorf("error[%v] in %v for task with ID %v", err, stage.name, t.ID)
+				}
 			}
 		}(tar, maxConcurrencyGuard, wg)
 	}
 	// waiting for all concurrent goroutines to complete
 	wg.Wait()
-	ts, err = getTaskStats(t.ID)
+	commitFiles.prune(t.commitFilesRetention())
+	_, err = getTaskStats(t.ID)
 	if err != nil {
 		log.Errorf("error[%v] in getting task stats for task with ID %v", err, t.ID)
 		return err
 	}
-	ts.LastSuccessFullRunTime = time.Now()
 	// saving task stats.
-	saveTaskStats(t.ID, ts)
+	updateTaskStats(t.ID, func(saved *TaskStats) {
+		saved.LastSuccessFullRunTime = time.Now()
+	})
 	return nil
 }
 
@@ -264,6 +346,10 @@ func (t *Task) collectAndPublishCommits(gp gitprovider.GitProvider, pb publisher
 				errChan <- err
 				return
 			}
+			// commits are collected per branch , a commit in several branches has a document for each
+			for _, v := range processed {
+				v.(map[string]interface{})["branch"] = br
+			}
 			err = pb.Publish(processed)
 			if err != nil {
 				log.Errorf("error[%v] in publishing commits for task with ID %v", err, t.ID)
@@ -275,8 +361,14 @@ func (t *Task) collectAndPublishCommits(gp gitprovider.GitProvider, pb publisher
 				//taking latest commit time
 				lastCommitTime := v.(map[string]interface{})
 				timeParsed, _ := time.Parse(time.RFC3339, lastCommitTime["created_at"].(string))
-				ts.LastCommitTime[br] = timeParsed
-				saveTaskStats(t.ID, ts)
+				updateTaskStats(t.ID, func(saved *TaskStats) {
+					commitTimes := make(map[string]time.Time, len(saved.LastCommitTime)+1)
+					for k, v := range saved.LastCommitTime {
+						commitTimes[k] = v
+					}
+					commitTimes[br] = timeParsed
+					saved.LastCommitTime = commitTimes
+				})
 				break
 			}
 			errChan <- nil
@@ -313,8 +405,10 @@ func (t *Task) collectAndPublishPullRequests(gp gitprovider.GitProvider, pb publ
 	for _, v := range processed {
 		//taking latest pull request number
 		lastPrNo := v.(map[string]interface{})
-		ts.LastPullRequestNo, _ = strconv.Atoi(lastPrNo["pull_request_no"].(string))
-		saveTaskStats(t.ID, ts)
+		prNo, _ := strconv.Atoi(lastPrNo["pull_request_no"].(string))
+		updateTaskStats(t.ID, func(saved *TaskStats) {
+			saved.LastPullRequestNo = prNo
+		})
 		break
 	}
 	return nil
@@ -342,8 +436,9 @@ func (t *Task) collectAndPublishIssues(gp gitprovider.GitProvider, pb publisher.
 		//taking latest issue time
 		lastIssueTime := v.(map[string]interface{})
 		timeParsed, _ := time.Parse(time.RFC3339, lastIssueTime["created_at"].(string))
-		ts.LastIssueTime = timeParsed
-		saveTaskStats(t.ID, ts)
+		updateTaskStats(t.ID, func(saved *TaskStats) {
+			saved.LastIssueTime = timeParsed
+		})
 		break
 	}
 	return nil
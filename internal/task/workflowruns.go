@@ -0,0 +1,93 @@
+package task
+
+import (
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/publisher"
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	// workflowRunReport is the report name of workflow runs in task stats
+	workflowRunReport = "workflow_run"
+
+	// itemCountReport is the report name of pull request and issue counts in task stats
+	itemCountReport = "item_count"
+
+	// workflowRunLookback is how long before the last published completion time runs are fetched again ,
+	// runs are listed by creation time so long running runs created before the last run are fetched too.
+	workflowRunLookback = 24 * time.Hour
+)
+
+// collectAndPublishWorkflowRuns collects workflow runs completed since the last run and publish them to targets.
+func (t *Task) collectAndPublishWorkflowRuns(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats) error {
+	if !t.Config.WorkflowRuns.Enabled {
+		return nil
+	}
+	now := time.Now()
+	from, ok := ts.LastReportTime[workflowRunReport]
+	if !ok {
+		from = now.Add(-(t.SchedulingInterval))
+	}
+	data, err := gp.GetWorkflowRuns(from.Add(-workflowRunLookback))
+	if err != nil {
+		log.Errorf("error[%v] in getting workflow runs from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	processed, err := dp.ProcessWorkflowRuns(data, from, now, t.Config.Tags)
+	if err != nil {
+		log.Errorf("error[%v] in processing workflow runs for task with ID %v", err, t.ID)
+		return err
+	}
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing workflow runs for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	saveReportTime(t.ID, workflowRunReport, now)
+	return nil
+}
+
+// countAndPublishItems counts all open pull requests by base branch and issues by state and publish the counts to targets.
+// Counts are made once every report interval as all open pull requests need to be fetched again.
+func (t *Task) countAndPublishItems(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats) error {
+	cfg := t.Config.ItemCounts
+	if !cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	interval, _ := utils.ParseDuration(cfg.ReportInterval)
+	if now.Sub(ts.LastReportTime[itemCountReport]) < interval {
+		return nil
+	}
+	prBytes, err := gp.GetOpenPullRequests()
+	if err != nil {
+		log.Errorf("error[%v] in getting open pull requests from gitprovider for task with ID %v", err, t.ID)
+		return err
+	}
+	issueCounts := make(map[string]int)
+	for _, state := range []string{"open", "closed"} {
+		n, err := gp.GetIssueCount(state)
+		if err != nil {
+			log.Errorf("error[%v] in getting %v issue count from gitprovider for task with ID %v", err, state, t.ID)
+			return err
+		}
+		issueCounts[state] = n
+	}
+	processed, err := dp.ProcessItemCounts(prBytes, issueCounts, now, t.Config.Tags)
+	if err != nil {
+		log.Errorf("error[%v] in processing item counts for task with ID %v", err, t.ID)
+		return err
+	}
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing item counts for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	saveReportTime(t.ID, itemCountReport, now)
+	return nil
+}
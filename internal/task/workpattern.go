@@ -0,0 +1,141 @@
+package task
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/maplelabs/github-audit/gitprovider"
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+	"github.com/maplelabs/github-audit/internal/enrichment"
+	"github.com/maplelabs/github-audit/publisher"
+)
+
+// analyzeAndPublishWorkPatterns aggregates commits to monitored branches , pull request reviews and comments on issues and
+// pull requests of each author in the last completed week by local hour and day , and publish them to targets.
+// Patterns are only published for completed weeks , as reviews and comments of every pull request and issue updated
+// in the week need to be fetched.
+func (t *Task) analyzeAndPublishWorkPatterns(gp gitprovider.GitProvider, pb publisher.Publisher, dp dataprocessor.DataProcessor, ts TaskStats, resolver *enrichment.IdentityResolver) error {
+	cfg := t.Config.WorkPatterns
+	if !cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	weekStart := lastCompletedWeek(now)
+	if !weekStart.After(ts.LastWorkPatternWeek) {
+		return nil
+	}
+	weekEnd := weekStart.AddDate(0, 0, 7)
+	commits, err := t.getBranchCommits(gp, dp, weekStart, weekEnd)
+	if err != nil {
+		return err
+	}
+	reviews, comments, err := t.getWeekReviewsAndComments(gp, dp, weekStart)
+	if err != nil {
+		return err
+	}
+	wa := derivedmetrics.NewWorkPatternAnalyzer(t.Config.RepositoryName, t.Config.RepositoryURL, cfg, resolver)
+	processed := wa.Analyze(commits, reviews, comments, weekStart, now, t.Config.Tags)
+	err = pb.Publish(processed)
+	if err != nil {
+		log.Errorf("error[%v] in publishing work patterns for task with ID %v", err, t.ID)
+		return err
+	}
+	// saving stats after finished task
+	updateTaskStats(t.ID, func(saved *TaskStats) {
+		saved.LastWorkPatternWeek = weekStart
+	})
+	return nil
+}
+
+// getWeekReviewsAndComments fetches reviews of pull requests open or closed since weekStart along with comments on them
+// and on issues updated since weekStart. Activities after the week are filtered by the analyzer.
+func (t *Task) getWeekReviewsAndComments(gp gitprovider.GitProvider, dp dataprocessor.DataProcessor, weekStart time.Time) ([]dataprocessor.PullRequestReview, []dataprocessor.IssueComment, error) {
+	reviews := make([]dataprocessor.PullRequestReview, 0)
+	comments := make([]dataprocessor.IssueComment, 0)
+	openBytes, err := gp.GetOpenPullRequests()
+	if err != nil {
+		log.Errorf("error[%v] in getting open pull requests from gitprovider for task with ID %v", err, t.ID)
+		return reviews, comments, err
+	}
+	closedBytes, err := gp.GetClosedPullRequests(weekStart)
+	if err != nil {
+		log.Errorf("error[%v] in getting closed pull requests from gitprovider for task with ID %v", err, t.ID)
+		return reviews, comments, err
+	}
+	openDocs, err := dp.ProcessPullRequests(openBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing open pull requests for task with ID %v", err, t.ID)
+		return reviews, comments, err
+	}
+	closedDocs, err := dp.ProcessPullRequests(closedBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing closed pull requests for task with ID %v", err, t.ID)
+		return reviews, comments, err
+	}
+	var pullRequests []dataprocessor.PullRequest
+	if err = dataprocessor.DecodeDocuments(append(openDocs, closedDocs...), &pullRequests); err != nil {
+		return reviews, comments, err
+	}
+	numbers := make([]string, 0, len(pullRequests))
+	for _, pr := range pullRequests {
+		number, err := strconv.Atoi(pr.PullRequestNo)
+		if err != nil {
+			continue
+		}
+		reviewBytes, err := gp.GetPullRequestReviews(number)
+		if err != nil {
+			log.Errorf("error[%v] in getting reviews of pull request %v for task with ID %v", err, number, t.ID)
+			return reviews, comments, err
+		}
+		reviewDocs, err := dp.ProcessPullRequestReviews(pr.PullRequestNo, reviewBytes, nil)
+		if err != nil {
+			return reviews, comments, err
+		}
+		var prReviews []dataprocessor.PullRequestReview
+		if err = dataprocessor.DecodeDocuments(reviewDocs, &prReviews); err != nil {
+			return reviews, comments, err
+		}
+		reviews = append(reviews, prReviews...)
+		numbers = append(numbers, pr.PullRequestNo)
+	}
+	issueBytes, err := gp.GetIssues(weekStart)
+	if err != nil {
+		log.Errorf("error[%v] in getting updated issues from gitprovider for task with ID %v", err, t.ID)
+		return reviews, comments, err
+	}
+	issueDocs, err := dp.ProcessIssues(issueBytes, nil)
+	if err != nil {
+		log.Errorf("error[%v] in processing updated issues for task with ID %v", err, t.ID)
+		return reviews, comments, err
+	}
+	var issues []dataprocessor.Issue
+	if err = dataprocessor.DecodeDocuments(issueDocs, &issues); err != nil {
+		return reviews, comments, err
+	}
+	for _, issue := range issues {
+		numbers = append(numbers, issue.IssueNo)
+	}
+	// comments on pull requests are issue comments of the pull request number
+	for _, issueNo := range numbers {
+		number, err := strconv.Atoi(issueNo)
+		if err != nil {
+			continue
+		}
+		commentBytes, err := gp.GetIssueComments(number)
+		if err != nil {
+			log.Errorf("error[%v] in getting comments of %v for task with ID %v", err, number, t.ID)
+			return reviews, comments, err
+		}
+		commentDocs, err := dp.ProcessIssueComments(issueNo, commentBytes, nil)
+		if err != nil {
+			return reviews, comments, err
+		}
+		var issueComments []dataprocessor.IssueComment
+		if err = dataprocessor.DecodeDocuments(commentDocs, &issueComments); err != nil {
+			return reviews, comments, err
+		}
+		comments = append(comments, issueComments...)
+	}
+	return reviews, comments, nil
+}
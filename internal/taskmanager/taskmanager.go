@@ -32,6 +32,10 @@ type TaskManager struct {
 	// The max concurrency guard will make sure that only fixed number of
 	// allowed tasks to run concurrently.
 	maxConcurrency chan struct{}
+
+	// running tracks the tasks being executed , so that scheduler returns only once they are completed
+	// and documents they publish are not missed by the final flush of targets.
+	running sync.WaitGroup
 }
 
 // StartTasks starts the tasks passed as input.
@@ -64,6 +68,9 @@ func (tm *TaskManager) startScheduling(ctx context.Context) {
 		case <-ticker.C:
 			tm.runReadyTasks()
 		case <-ctx.Done():
+			ticker.Stop()
+			log.Infof("waiting for running tasks to complete")
+			tm.running.Wait()
 			log.Infof("stopped task manager")
 			return
 		}
@@ -115,7 +122,9 @@ func (tm *TaskManager) runReadyTasks() {
 
 	for _, t := range readyTasks {
 		tm.maxConcurrency <- struct{}{}
+		tm.running.Add(1)
 		go func(t *task.Task) {
+			defer tm.running.Done()
 			log.Debugf("running task with id %v", t.ID)
 			err := t.Start()
 			if err != nil {
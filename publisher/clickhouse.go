@@ -0,0 +1,610 @@
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	retryhttp "github.com/hashicorp/go-retryablehttp"
+
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+	"github.com/maplelabs/github-audit/internal/derivedmetrics"
+)
+
+const (
+	// prefix of config keys holding table of a document type , for ex. table.commit
+	clickhouseTablePrefix = "table."
+
+	defaultClickHouseDatabase    = "default"
+	defaultClickHouseTablePrefix = "github_audit_"
+	defaultClickHouseEngine      = "MergeTree"
+	defaultClickHouseBatchSize   = 10000
+
+	// column types
+	clickhouseString   = "String"
+	clickhouseInt64    = "Int64"
+	clickhouseUInt64   = "UInt64"
+	clickhouseFloat64  = "Float64"
+	clickhouseBool     = "Bool"
+	clickhouseDateTime = "DateTime64(3, 'UTC')"
+
+	// zeroTimePrefix is the prefix of zero time of go in json , such times are left to the default of column
+	// as they are out of range of clickhouse
+	zeroTimePrefix = "0001-01-01T00:00:00"
+)
+
+var (
+	ErrMissingClickHouseURL = errors.New("missing clickhouse url")
+
+	// clickhouseDocuments are the documents of document types , their fields are the columns of created tables
+	// and give the types of columns added for new fields
+	clickhouseDocuments = map[string]interface{}{
+		dataprocessor.COMMIT:                 dataprocessor.Commit{},
+		dataprocessor.PULLREQUEST:            dataprocessor.PullRequest{},
+		dataprocessor.ISSUE:                  dataprocessor.Issue{},
+		dataprocessor.ISSUECOMMENT:           dataprocessor.IssueComment{},
+		dataprocessor.PULLREQUESTREVIEW:      dataprocessor.PullRequestReview{},
+		dataprocessor.COMMITCHECK:            dataprocessor.CommitCheck{},
+		dataprocessor.PULLREQUESTCYCLETIME:   dataprocessor.PullRequestCycleTime{},
+		dataprocessor.DEPLOYMENT:             dataprocessor.Deployment{},
+		dataprocessor.RELEASE:                dataprocessor.Release{},
+		dataprocessor.WORKFLOWRUN:            dataprocessor.WorkflowRun{},
+		dataprocessor.ITEMCOUNT:              dataprocessor.ItemCount{},
+		dataprocessor.CONTRIBUTORSTATS:       dataprocessor.ContributorStats{},
+		dataprocessor.CODEFREQUENCY:          dataprocessor.CodeFrequency{},
+		dataprocessor.COMMITACTIVITY:         dataprocessor.CommitActivity{},
+		derivedmetrics.DORAMETRIC:            derivedmetrics.DoraMetric{},
+		derivedmetrics.KNOWLEDGEDISTRIBUTION: derivedmetrics.KnowledgeDistribution{},
+		derivedmetrics.COMPLIANCEVIOLATION:   derivedmetrics.ComplianceViolation{},
+		derivedmetrics.FILEHOTSPOT:           derivedmetrics.FileHotspot{},
+		derivedmetrics.ACTIVITYANOMALY:       derivedmetrics.ActivityAnomaly{},
+		derivedmetrics.HISTORYREWRITE:        derivedmetrics.HistoryRewrite{},
+		derivedmetrics.STALEPULLREQUEST:      derivedmetrics.StalePullRequest{},
+		derivedmetrics.STALEBRANCH:           derivedmetrics.StaleBranch{},
+		derivedmetrics.SLASTATUS:             derivedmetrics.SLAStatus{},
+		derivedmetrics.SLABREACH:             derivedmetrics.SLABreach{},
+		derivedmetrics.WORKPATTERN:           derivedmetrics.WorkPattern{},
+	}
+
+	// clickhouseTables holds columns of tables with url , database and table as key , so that tables are
+	// described once and not for every run of a task
+	clickhouseTables      = make(map[string]*clickhouseTable)
+	clickhouseTablesMutex sync.Mutex
+
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// ClickHouseClient holds config for clickhouse target , documents are inserted with the http interface in
+// JSONEachRow format into a table per document type , nested fields are columns named with dots , for ex. author.user
+type ClickHouseClient struct {
+	// URL of http interface , for ex. http://clickhouse:8123
+	URL string `yaml:"url" json:"url"`
+
+	// Database of tables , Default: default
+	Database string `yaml:"database,omitempty" json:"database,omitempty"`
+
+	// Username for authentication
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+
+	// Password for authentication
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+
+	// TablePrefix of tables of document types without table.<document_type> , Default: github_audit_
+	TablePrefix string `yaml:"table_prefix,omitempty" json:"table_prefix,omitempty"`
+
+	// CreateTables creates missing tables with columns of the documents of dataprocessor and derivedmetrics
+	CreateTables bool `yaml:"create_tables,omitempty" json:"create_tables,omitempty,string"`
+
+	// AddColumns adds columns for fields of documents which are not in table , fields are dropped otherwise
+	AddColumns bool `yaml:"add_columns,omitempty" json:"add_columns,omitempty,string"`
+
+	// Engine of created tables , Default: MergeTree
+	Engine string `yaml:"engine,omitempty" json:"engine,omitempty"`
+
+	// OrderBy of created tables , Default: the columns repo_name and created_at of document
+	OrderBy string `yaml:"order_by,omitempty" json:"order_by,omitempty"`
+
+	// PartitionBy of created tables , Default: toYYYYMM(created_at) for documents with created_at
+	PartitionBy string `yaml:"partition_by,omitempty" json:"partition_by,omitempty"`
+
+	// BatchSize is the number of rows in an insert , Default: 10000
+	BatchSize int `yaml:"batch_size,omitempty" json:"batch_size,omitempty,string"`
+
+	// Gzip compresses inserts , Default: true
+	Gzip *bool `yaml:"gzip,omitempty" json:"gzip,omitempty,string"`
+
+	// InsecureSkipVerify skips verification of server certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty,string"`
+
+	// Tables by document type , given as config keys table.<document_type>
+	Tables map[string]string `yaml:"-" json:"-"`
+}
+
+// clickhouseTable holds the columns of a table with their types , nil until the table is described
+type clickhouseTable struct {
+	mutex   sync.Mutex
+	columns map[string]string
+}
+
+// clickhouseColumn is a column of a table
+type clickhouseColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Publish inserts documents into tables of their document types in batches , tables and columns are
+// created before inserting when enabled
+func (cc *ClickHouseClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	client := HTTPClientWithRetry()
+	if cc.InsecureSkipVerify {
+		client.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Proxy: http.ProxyFromEnvironment,
+		}
+	}
+	var documentTypes []string
+	byType := make(map[string][]map[string]interface{})
+	for _, doc := range documentMaps(data) {
+		documentType, _ := doc["document_type"].(string)
+		if documentType == "" {
+			log.Debugf("skipping document without document type")
+			continue
+		}
+		if _, ok := byType[documentType]; !ok {
+			documentTypes = append(documentTypes, documentType)
+		}
+		byType[documentType] = append(byType[documentType], doc)
+	}
+
+	count := 0
+	for _, documentType := range documentTypes {
+		docs := byType[documentType]
+		for start := 0; start < len(docs); start += cc.BatchSize {
+			end := start + cc.BatchSize
+			if end > len(docs) {
+				end = len(docs)
+			}
+			if err := cc.insert(client, documentType, docs[start:end]); err != nil {
+				log.Errorf("error[%v] in inserting %d %s docs to %s", err, end-start, documentType, cc.URL)
+				return err
+			}
+			count += end - start
+		}
+	}
+	log.Infof("successfully inserted %d docs to %s", count, cc.URL)
+	return nil
+}
+
+// insert inserts documents of a document type , the table is created and columns are added for new fields
+// before inserting when enabled
+func (cc *ClickHouseClient) insert(client *retryhttp.Client, documentType string, docs []map[string]interface{}) error {
+	table := cc.tableName(documentType)
+	columns, err := cc.syncTable(client, table, documentType, docs)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	for _, doc := range docs {
+		row := make(map[string]interface{})
+		clickhouseFields(doc, "", columns, func(name string, value interface{}) {
+			if columnType, ok := columns[name]; ok {
+				if value = clickhouseValue(value, columnType); value != nil {
+					row[name] = value
+				}
+			}
+		})
+		byteData, err := json.Marshal(row)
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		body.Write(byteData)
+		body.WriteByte('\n')
+	}
+	params := url.Values{}
+	params.Set("date_time_input_format", "best_effort")
+	params.Set("input_format_skip_unknown_fields", "1")
+	_, err = cc.query(client, "INSERT INTO "+cc.qualifiedName(table)+" FORMAT JSONEachRow", params, body.Bytes())
+	return err
+}
+
+// syncTable returns the columns of table , creating the table and adding columns for new fields of documents
+// when enabled
+func (cc *ClickHouseClient) syncTable(client *retryhttp.Client, table string, documentType string, docs []map[string]interface{}) (map[string]string, error) {
+	t := getClickHouseTable(cc.URL + "|" + cc.Database + "|" + table)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	schema := clickhouseSchema(clickhouseDocuments[documentType])
+	if t.columns == nil {
+		if cc.CreateTables {
+			// fields of documents which are not in schema , for ex. added by enrichers , are columns too
+			existing := make(map[string]string)
+			for _, column := range schema {
+				existing[column.Name] = column.Type
+			}
+			columns := append(append([]clickhouseColumn{}, schema...), newClickHouseColumns(docs, existing, schema)...)
+			if _, err := cc.query(client, cc.createTable(table, columns), nil, nil); err != nil {
+				return nil, err
+			}
+		}
+		columns, err := cc.describeTable(client, table)
+		if err != nil {
+			return nil, err
+		}
+		t.columns = columns
+	}
+
+	if newColumns := newClickHouseColumns(docs, t.columns, schema); len(newColumns) > 0 && cc.AddColumns {
+		var adds []string
+		for _, column := range newColumns {
+			adds = append(adds, "ADD COLUMN IF NOT EXISTS "+clickhouseIdentifier(column.Name)+" "+column.Type)
+		}
+		if _, err := cc.query(client, "ALTER TABLE "+cc.qualifiedName(table)+" "+strings.Join(adds, ", "), nil, nil); err != nil {
+			return nil, err
+		}
+		log.Infof("added %d columns to table %s", len(newColumns), table)
+		for _, column := range newColumns {
+			t.columns[column.Name] = column.Type
+		}
+	}
+
+	columns := make(map[string]string, len(t.columns))
+	for name, columnType := range t.columns {
+		columns[name] = columnType
+	}
+	return columns, nil
+}
+
+// createTable returns the query creating table with columns , ordered by repo_name and created_at and
+// partitioned by month of created_at by default
+func (cc *ClickHouseClient) createTable(table string, columns []clickhouseColumn) string {
+	types := make(map[string]string)
+	var definitions []string
+	for _, column := range columns {
+		types[column.Name] = column.Type
+		definitions = append(definitions, clickhouseIdentifier(column.Name)+" "+column.Type)
+	}
+	orderBy := cc.OrderBy
+	if orderBy == "" {
+		var keys []string
+		for _, key := range []string{"repo_name", "created_at"} {
+			if _, ok := types[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+		orderBy = "tuple()"
+		if len(keys) > 0 {
+			orderBy = "(" + strings.Join(keys, ", ") + ")"
+		}
+	}
+	partitionBy := cc.PartitionBy
+	if partitionBy == "" && types["created_at"] == clickhouseDateTime {
+		partitionBy = "toYYYYMM(created_at)"
+	}
+	query := "CREATE TABLE IF NOT EXISTS " + cc.qualifiedName(table) + " (" + strings.Join(definitions, ", ") + ") ENGINE = " + cc.Engine
+	if partitionBy != "" {
+		query += " PARTITION BY " + partitionBy
+	}
+	return query + " ORDER BY " + orderBy
+}
+
+// describeTable returns the columns of table with their types
+func (cc *ClickHouseClient) describeTable(client *retryhttp.Client, table string) (map[string]string, error) {
+	respBody, err := cc.query(client, "DESCRIBE TABLE "+cc.qualifiedName(table)+" FORMAT JSONEachRow", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(respBody))
+	for scanner.Scan() {
+		var column clickhouseColumn
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &column); err != nil {
+			return nil, fmt.Errorf("invalid clickhouse describe response: %w", err)
+		}
+		columns[column.Name] = column.Type
+	}
+	return columns, nil
+}
+
+// query sends query to clickhouse with data as body and returns the response
+func (cc *ClickHouseClient) query(client *retryhttp.Client, query string, params url.Values, data []byte) ([]byte, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("database", cc.Database)
+	params.Set("query", query)
+	body := data
+	if *cc.Gzip && len(data) > 0 {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		body = buf.Bytes()
+	}
+	request, err := retryhttp.NewRequest(http.MethodPost, strings.TrimSuffix(cc.URL, "/")+"/?"+params.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	if *cc.Gzip && len(data) > 0 {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	if cc.Username != "" {
+		request.Header.Set("X-ClickHouse-User", cc.Username)
+		request.Header.Set("X-ClickHouse-Key", cc.Password)
+	}
+
+	timeout, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	response, err := client.Do(request.WithContext(timeout))
+	if err != nil {
+		log.Errorf("error[%v] request failed", err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return ioutil.ReadAll(response.Body)
+	}
+	respBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+	return nil, fmt.Errorf("clickhouse query failed with status %s: %s", response.Status, strings.TrimSpace(string(respBody)))
+}
+
+// tableName returns the table of a document type
+func (cc *ClickHouseClient) tableName(documentType string) string {
+	if table, ok := cc.Tables[documentType]; ok {
+		return table
+	}
+	return cc.TablePrefix + documentType
+}
+
+// qualifiedName returns the quoted name of table with its database
+func (cc *ClickHouseClient) qualifiedName(table string) string {
+	return clickhouseIdentifier(cc.Database) + "." + clickhouseIdentifier(table)
+}
+
+// getClickHouseTable returns the table of key , creating it for first use
+func getClickHouseTable(key string) *clickhouseTable {
+	clickhouseTablesMutex.Lock()
+	defer clickhouseTablesMutex.Unlock()
+	t, ok := clickhouseTables[key]
+	if !ok {
+		t = &clickhouseTable{}
+		clickhouseTables[key] = t
+	}
+	return t
+}
+
+// clickhouseIdentifier returns name quoted with backticks
+func clickhouseIdentifier(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+// clickhouseFields calls fn for the fields of document which are columns , objects which are not columns
+// are flattened to fields named with dots
+func clickhouseFields(doc map[string]interface{}, prefix string, columns map[string]string, fn func(name string, value interface{})) {
+	for key, value := range doc {
+		name := prefix + key
+		if _, ok := columns[name]; !ok {
+			if m, ok := value.(map[string]interface{}); ok {
+				clickhouseFields(m, name+".", columns, fn)
+				continue
+			}
+		}
+		fn(name, value)
+	}
+}
+
+// newClickHouseColumns returns columns for fields of documents which are not in columns , sorted by name ,
+// types are taken from schema of document type or else from values of fields
+func newClickHouseColumns(docs []map[string]interface{}, columns map[string]string, schema []clickhouseColumn) []clickhouseColumn {
+	schemaTypes := make(map[string]string)
+	for _, column := range schema {
+		schemaTypes[column.Name] = column.Type
+	}
+	if columns == nil {
+		columns = make(map[string]string)
+	}
+
+	found := make(map[string]string)
+	for _, doc := range docs {
+		clickhouseFields(doc, "", columns, func(name string, value interface{}) {
+			if _, ok := columns[name]; ok || value == nil {
+				return
+			}
+			if _, ok := found[name]; ok {
+				return
+			}
+			if columnType, ok := schemaTypes[name]; ok {
+				found[name] = columnType
+				return
+			}
+			found[name] = clickhouseValueType(value)
+		})
+	}
+	var newColumns []clickhouseColumn
+	for name, columnType := range found {
+		newColumns = append(newColumns, clickhouseColumn{Name: name, Type: columnType})
+	}
+	sort.Slice(newColumns, func(i, j int) bool { return newColumns[i].Name < newColumns[j].Name })
+	return newColumns
+}
+
+// clickhouseValueType returns the column type of a json value of a field not in documents
+func clickhouseValueType(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return clickhouseFloat64
+	case bool:
+		return clickhouseBool
+	case []interface{}:
+		elemType := clickhouseString
+		for i, elem := range v {
+			switch elem.(type) {
+			case map[string]interface{}, []interface{}:
+				return clickhouseString
+			}
+			if t := clickhouseValueType(elem); i == 0 {
+				elemType = t
+			} else if t != elemType {
+				return clickhouseString
+			}
+		}
+		return "Array(" + elemType + ")"
+	}
+	return clickhouseString
+}
+
+// clickhouseValue returns a json value for a column of type , non string values of string columns are json
+// strings and zero times are nil so that the default of column is used
+func clickhouseValue(value interface{}, columnType string) interface{} {
+	switch columnType {
+	case clickhouseString:
+		if _, ok := value.(string); !ok && value != nil {
+			b, _ := json.Marshal(value)
+			return string(b)
+		}
+	case "Array(" + clickhouseString + ")":
+		if values, ok := value.([]interface{}); ok {
+			strs := make([]string, 0, len(values))
+			for _, v := range values {
+				if s, ok := v.(string); ok {
+					strs = append(strs, s)
+				} else {
+					b, _ := json.Marshal(v)
+					strs = append(strs, string(b))
+				}
+			}
+			return strs
+		}
+	}
+	if s, ok := value.(string); ok && strings.HasPrefix(columnType, "DateTime") && strings.HasPrefix(s, zeroTimePrefix) {
+		return nil
+	}
+	return value
+}
+
+// clickhouseSchema returns the columns of fields of a document struct in their order , nested structs are
+// columns named with dots , slices of scalars are arrays while maps and slices of structs are json strings
+func clickhouseSchema(doc interface{}) []clickhouseColumn {
+	if doc == nil {
+		return nil
+	}
+	var columns []clickhouseColumn
+	addClickHouseColumns(reflect.TypeOf(doc), "", &columns)
+	return columns
+}
+
+// addClickHouseColumns adds columns of fields of struct type t with prefix
+func addClickHouseColumns(t reflect.Type, prefix string, columns *[]clickhouseColumn) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addClickHouseColumns(fieldType, prefix, columns)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			addClickHouseColumns(fieldType, prefix+name+".", columns)
+			continue
+		}
+		*columns = append(*columns, clickhouseColumn{Name: prefix + name, Type: clickhouseType(fieldType)})
+	}
+}
+
+// clickhouseType returns the column type of a go type
+func clickhouseType(t reflect.Type) string {
+	if t == timeType {
+		return clickhouseDateTime
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return clickhouseBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return clickhouseInt64
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return clickhouseUInt64
+	case reflect.Float32, reflect.Float64:
+		return clickhouseFloat64
+	case reflect.Slice, reflect.Array:
+		elem := t.Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		switch elem.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+			if elem != timeType {
+				return clickhouseString
+			}
+		}
+		return "Array(" + clickhouseType(elem) + ")"
+	}
+	return clickhouseString
+}
+
+// init validates config and sets defaults
+func (cc *ClickHouseClient) init(config map[string]string) error {
+	if cc.URL == "" {
+		return ErrMissingClickHouseURL
+	}
+	if cc.Database == "" {
+		cc.Database = defaultClickHouseDatabase
+	}
+	if cc.TablePrefix == "" {
+		cc.TablePrefix = defaultClickHouseTablePrefix
+	}
+	if cc.Engine == "" {
+		cc.Engine = defaultClickHouseEngine
+	}
+	if cc.BatchSize <= 0 {
+		cc.BatchSize = defaultClickHouseBatchSize
+	}
+	if cc.Gzip == nil {
+		gzip := true
+		cc.Gzip = &gzip
+	}
+	cc.Tables = make(map[string]string)
+	for key, value := range config {
+		if strings.HasPrefix(key, clickhouseTablePrefix) {
+			cc.Tables[strings.TrimPrefix(key, clickhouseTablePrefix)] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package publisher
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maplelabs/github-audit/internal/dataprocessor"
+)
+
+func TestClickHouseSchema(t *testing.T) {
+	columns := make(map[string]string)
+	for _, column := range clickhouseSchema(dataprocessor.Commit{}) {
+		columns[column.Name] = column.Type
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"created_at", "DateTime64(3, 'UTC')"},
+		{"author.user", "String"},
+		{"verification.verified", "Bool"},
+		{"parents", "Array(String)"},
+		{"time", "Int64"},
+	}
+	for _, tt := range tests {
+		if got := columns[tt.name]; got != tt.want {
+			t.Errorf("clickhouseSchema() column %v = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, ok := columns["author"]; ok {
+		t.Errorf("clickhouseSchema() has column of nested struct author")
+	}
+
+	cc, _ := loadConfigClickHouse(map[string]string{"url": "http://localhost:8123"})
+	want := "CREATE TABLE IF NOT EXISTS `default`.`github_audit_commit` (`repo_name` String, `created_at` DateTime64(3, 'UTC'), `labels` Array(String)) " +
+		"ENGINE = MergeTree PARTITION BY toYYYYMM(created_at) ORDER BY (repo_name, created_at)"
+	if got := cc.createTable("github_audit_commit", []clickhouseColumn{{"repo_name", clickhouseString}, {"created_at", clickhouseDateTime}, {"labels", "Array(String)"}}); got != want {
+		t.Errorf("createTable() = %v, want %v", got, want)
+	}
+}
+
+func TestClickHouseClient_Publish(t *testing.T) {
+	var queries []string
+	var inserts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		queries = append(queries, query)
+		if r.Header.Get("X-ClickHouse-User") != "audit" || r.URL.Query().Get("database") != "git" {
+			t.Errorf("query %v has user %v database %v", query, r.Header.Get("X-ClickHouse-User"), r.URL.Query().Get("database"))
+		}
+		switch {
+		case strings.HasPrefix(query, "DESCRIBE TABLE `git`.`github_audit_commit`"):
+			// table of an earlier version of commit documents
+			w.Write([]byte(`{"name":"document_type","type":"String"}` + "\n" + `{"name":"repo_name","type":"String"}` + "\n" +
+				`{"name":"created_at","type":"DateTime64(3, 'UTC')"}` + "\n" + `{"name":"sha","type":"String"}` + "\n"))
+		case strings.HasPrefix(query, "DESCRIBE TABLE"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Code: 60. DB::Exception: Table git.audit_issues does not exist. (UNKNOWN_TABLE)"))
+		case strings.HasPrefix(query, "INSERT"):
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Fatalf("gzip.NewReader() error = %v", err)
+			}
+			b, _ := ioutil.ReadAll(zr)
+			inserts = append(inserts, string(b))
+		}
+	}))
+	defer server.Close()
+
+	cc, err := loadConfigClickHouse(map[string]string{"url": server.URL, "database": "git", "username": "audit", "password": "secret",
+		"create_tables": "true", "add_columns": "true", "table.issue": "audit_issues"})
+	if err != nil {
+		t.Fatalf("loadConfigClickHouse() error = %v", err)
+	}
+	commit := map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "created_at": "2022-10-10T10:00:00+05:30", "sha": "a1",
+		"author": map[string]interface{}{"id": "1", "user": "dev1"}, "parents": []string{"p1"}, "branch": "main",
+		"team": map[string]interface{}{"name": "core"}, "merged_at": "0001-01-01T00:00:00Z"}
+	if err = cc.Publish([]interface{}{commit}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(queries) != 4 || !strings.HasPrefix(queries[0], "CREATE TABLE IF NOT EXISTS `git`.`github_audit_commit` (`document_type` String") {
+		t.Fatalf("Publish() queries = %v", queries)
+	}
+	for _, want := range []string{"ADD COLUMN IF NOT EXISTS `author.user` String", "ADD COLUMN IF NOT EXISTS `branch` String", "ADD COLUMN IF NOT EXISTS `team.name` String",
+		"ADD COLUMN IF NOT EXISTS `parents` Array(String)", "ADD COLUMN IF NOT EXISTS `merged_at` String"} {
+		if !strings.Contains(queries[2], want) {
+			t.Errorf("Publish() alter = %v , want %v", queries[2], want)
+		}
+	}
+	for _, want := range []string{`"author.user":"dev1"`, `"team.name":"core"`, `"parents":["p1"]`, `"created_at":"2022-10-10T10:00:00+05:30"`} {
+		if !strings.Contains(inserts[0], want) {
+			t.Errorf("Publish() inserted %v , want %v", inserts[0], want)
+		}
+	}
+
+	// columns of tables are kept for later runs
+	queries = nil
+	if err = cc.Publish([]interface{}{commit}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(queries) != 1 || !strings.HasPrefix(queries[0], "INSERT INTO `git`.`github_audit_commit` FORMAT JSONEachRow") {
+		t.Errorf("Publish() queries = %v", queries)
+	}
+
+	cc, _ = loadConfigClickHouse(map[string]string{"url": server.URL, "database": "git", "username": "audit", "table.issue": "audit_issues"})
+	if err = cc.Publish([]interface{}{map[string]interface{}{"document_type": "issue", "repo_name": "repo1"}}); err == nil || !strings.Contains(err.Error(), "UNKNOWN_TABLE") {
+		t.Errorf("Publish() error = %v, want UNKNOWN_TABLE", err)
+	}
+}
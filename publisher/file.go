@@ -0,0 +1,296 @@
+package publisher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	defaultFileMaxSizeMB = 100
+	backupTimeFormat     = "20060102T150405.000"
+
+	// fileIdleTimeout is the time after which files not written are closed , for ex. files of previous days
+	fileIdleTimeout = 10 * time.Minute
+)
+
+var (
+	ErrMissingFilePath = errors.New("missing file path")
+
+	// fileWriters holds open files with their path as key , as publishers are created for every run of a task
+	// while files are appended by all runs
+	fileWriters      = make(map[string]*rotatingFile)
+	fileWritersMutex sync.Mutex
+)
+
+// FileClient holds config for file target , documents are appended to files as one json document per line
+type FileClient struct {
+	// Path is a go template of path of file , for ex. /var/log/github-audit/{{.repo_name}}/{{.document_type}}-{{date}}.ndjson ,
+	// fields of document and date , the utc date of writing , are available
+	Path string `yaml:"path" json:"path"`
+
+	// MaxSize in megabytes after which file is rotated , Default: 100
+	MaxSize int `yaml:"max_size,omitempty" json:"max_size,omitempty,string"`
+
+	// RotateInterval after which file is rotated , for ex. 1h or 1d , file is not rotated by time if empty
+	RotateInterval string `yaml:"rotate_interval,omitempty" json:"rotate_interval,omitempty"`
+
+	// MaxBackups is the number of rotated files kept , all are kept if 0
+	MaxBackups int `yaml:"max_backups,omitempty" json:"max_backups,omitempty,string"`
+
+	// Compress gzips rotated files
+	Compress bool `yaml:"compress,omitempty" json:"compress,omitempty,string"`
+
+	// Fsync syncs files to disk after documents of a run are written , Default: true
+	Fsync *bool `yaml:"fsync,omitempty" json:"fsync,omitempty,string"`
+
+	template       *template.Template
+	rotateInterval time.Duration
+}
+
+// rotatingFile is an append only file rotated by size and age
+type rotatingFile struct {
+	mutex    sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	lastUsed time.Time
+}
+
+// Publish appends documents to files of their path , files are synced before returning so that documents
+// are on disk when the task saves its checkpoint
+func (fc *FileClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	now := time.Now()
+	fc.template.Funcs(template.FuncMap{"date": func() string { return now.UTC().Format("2006-01-02") }})
+	var paths []string
+	byPath := make(map[string][]byte)
+	for _, doc := range data {
+		byteData, err := json.Marshal(doc)
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		path, err := fc.render(byteData)
+		if err != nil {
+			log.Errorf("error[%v] in rendering file path", err)
+			return err
+		}
+		if _, ok := byPath[path]; !ok {
+			paths = append(paths, path)
+		}
+		byPath[path] = append(append(byPath[path], byteData...), '\n')
+	}
+	for _, path := range paths {
+		if err := fc.write(path, byPath[path], now); err != nil {
+			log.Errorf("error[%v] in writing to file %s", err, path)
+			return err
+		}
+	}
+	closeIdleFiles(now)
+	log.Infof("successfully wrote %d docs to %d files", len(data), len(paths))
+	return nil
+}
+
+// render returns the path of file of a document , path separators in string fields are replaced so that
+// documents can not write outside of the directories of template
+func (fc *FileClient) render(byteData []byte) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(byteData, &fields); err != nil {
+		return "", err
+	}
+	for key, value := range fields {
+		if s, ok := value.(string); ok {
+			fields[key] = strings.NewReplacer("/", "_", `\`, "_", "..", "_").Replace(s)
+		}
+	}
+	var buf bytes.Buffer
+	if err := fc.template.Execute(&buf, fields); err != nil {
+		return "", err
+	}
+	return filepath.Abs(buf.String())
+}
+
+// write appends lines to file at path , rotating the file when it is larger than max size or older than rotate interval
+func (fc *FileClient) write(path string, lines []byte, now time.Time) error {
+	rf := getRotatingFile(path)
+	rf.mutex.Lock()
+	defer rf.mutex.Unlock()
+	rf.lastUsed = now
+	if rf.file == nil {
+		if err := rf.open(now); err != nil {
+			return err
+		}
+	}
+	maxSize := int64(fc.MaxSize) * 1024 * 1024
+	if (rf.size > 0 && rf.size+int64(len(lines)) > maxSize) || (fc.rotateInterval > 0 && now.Sub(rf.openedAt) >= fc.rotateInterval) {
+		if err := rf.rotate(now, fc.Compress, fc.MaxBackups); err != nil {
+			return err
+		}
+	}
+	n, err := rf.file.Write(lines)
+	rf.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if *fc.Fsync {
+		return rf.file.Sync()
+	}
+	return nil
+}
+
+// getRotatingFile returns the file of path , creating it for first use
+func getRotatingFile(path string) *rotatingFile {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	rf, ok := fileWriters[path]
+	if !ok {
+		rf = &rotatingFile{path: path}
+		fileWriters[path] = rf
+	}
+	return rf
+}
+
+// closeIdleFiles closes files not written since file idle timeout , they are opened again when written
+func closeIdleFiles(now time.Time) {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	for _, rf := range fileWriters {
+		rf.mutex.Lock()
+		if rf.file != nil && now.Sub(rf.lastUsed) >= fileIdleTimeout {
+			if err := rf.file.Close(); err != nil {
+				log.Errorf("error[%v] in closing file %s", err, rf.path)
+			}
+			rf.file = nil
+		}
+		rf.mutex.Unlock()
+	}
+}
+
+// open opens file for appending , age of file is kept when it is opened again after being idle , an existing
+// file is rotated by age from the time it is first opened
+func (rf *rotatingFile) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(rf.path), 0750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	if rf.openedAt.IsZero() || rf.size == 0 {
+		rf.openedAt = now
+	}
+	return nil
+}
+
+// rotate renames file to a backup with time of rotation , compresses it and removes backups beyond max backups
+func (rf *rotatingFile) rotate(now time.Time, compress bool, maxBackups int) error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+	rf.openedAt = time.Time{}
+	ext := filepath.Ext(rf.path)
+	prefix := strings.TrimSuffix(rf.path, ext) + "-"
+	backup := prefix + now.UTC().Format(backupTimeFormat) + ext
+	if err := os.Rename(rf.path, backup); err != nil {
+		return err
+	}
+	if compress {
+		if err := gzipFile(backup); err != nil {
+			log.Errorf("error[%v] in compressing rotated file %s", err, backup)
+		}
+	}
+	if maxBackups > 0 {
+		backups, _ := filepath.Glob(prefix + "*")
+		var rotated []string
+		for _, b := range backups {
+			stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(b, prefix), ".gz"), ext)
+			if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+				rotated = append(rotated, b)
+			}
+		}
+		sort.Strings(rotated)
+		for i := 0; i < len(rotated)-maxBackups; i++ {
+			if err := os.Remove(rotated[i]); err != nil {
+				log.Errorf("error[%v] in removing rotated file %s", err, rotated[i])
+			}
+		}
+	}
+	return rf.open(now)
+}
+
+// gzipFile compresses file to file.gz and removes it
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// init validates config , sets defaults and parses path template
+func (fc *FileClient) init() error {
+	if fc.Path == "" {
+		return ErrMissingFilePath
+	}
+	t, err := template.New("path").Funcs(template.FuncMap{"date": func() string { return "" }}).Parse(fc.Path)
+	if err != nil {
+		return fmt.Errorf("invalid file path template: %w", err)
+	}
+	fc.template = t
+	if fc.MaxSize <= 0 {
+		fc.MaxSize = defaultFileMaxSizeMB
+	}
+	if fc.RotateInterval != "" {
+		if fc.rotateInterval, err = utils.ParseDuration(fc.RotateInterval); err != nil {
+			return err
+		}
+	}
+	if fc.Fsync == nil {
+		fsync := true
+		fc.Fsync = &fsync
+	}
+	return nil
+}
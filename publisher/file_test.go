@@ -0,0 +1,98 @@
+package publisher
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileClient_Publish(t *testing.T) {
+	dir := t.TempDir()
+	fc, err := loadConfigFile(map[string]string{"path": dir + "/{{.repo_name}}/{{.document_type}}-{{date}}.ndjson"})
+	if err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	docs := []interface{}{
+		map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "sha": "a1"},
+		map[string]interface{}{"document_type": "issue", "repo_name": "repo1", "issue_no": "1"},
+		map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "sha": "a2"},
+		map[string]interface{}{"document_type": "commit", "repo_name": "../etc", "sha": "a3"},
+	}
+	for i := 0; i < 2; i++ {
+		if err = fc.Publish(docs); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	date := time.Now().UTC().Format("2006-01-02")
+	tests := []struct {
+		path  string
+		lines int
+	}{
+		{filepath.Join(dir, "repo1", "commit-"+date+".ndjson"), 4},
+		{filepath.Join(dir, "repo1", "issue-"+date+".ndjson"), 2},
+		{filepath.Join(dir, "__etc", "commit-"+date+".ndjson"), 2},
+	}
+	for _, tt := range tests {
+		b, err := ioutil.ReadFile(tt.path)
+		if err != nil {
+			t.Errorf("ReadFile() error = %v", err)
+			continue
+		}
+		if lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"); len(lines) != tt.lines || !strings.HasPrefix(lines[0], "{") {
+			t.Errorf("Publish() wrote %v to %v, want %v lines", lines, tt.path, tt.lines)
+		}
+	}
+}
+
+func TestFileClient_rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.ndjson")
+	fc, err := loadConfigFile(map[string]string{"path": path, "max_size": "1", "max_backups": "2", "compress": "true", "rotate_interval": "1h"})
+	if err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	line := []byte(strings.Repeat("x", 400*1024) + "\n")
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	// the third write rotates by size , the fourth by age , the rotated file of the first is removed by max backups
+	for i, at := range []time.Time{now, now.Add(time.Minute), now.Add(2 * time.Minute), now.Add(62 * time.Minute), now.Add(63 * time.Minute)} {
+		if err = fc.write(path, line, at); err != nil {
+			t.Fatalf("write() %v error = %v", i, err)
+		}
+	}
+	// idle files are closed and keep their age when opened again
+	closeIdleFiles(now.Add(2 * time.Hour))
+	small := []byte("{}\n")
+	if err = fc.write(path, small, now.Add(2*time.Hour+3*time.Minute)); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "audit-*.ndjson.gz"))
+	want := []string{"audit-20221010T130200.000.ndjson.gz", "audit-20221010T140300.000.ndjson.gz"}
+	if len(backups) != len(want) {
+		t.Fatalf("rotated files = %v, want %v", backups, want)
+	}
+	for i, b := range backups {
+		if filepath.Base(b) != want[i] {
+			t.Errorf("rotated file = %v, want %v", filepath.Base(b), want[i])
+		}
+	}
+	f, err := os.Open(backups[1])
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	if b, _ := ioutil.ReadAll(zr); len(b) != 2*len(line) {
+		t.Errorf("rotated file has %v bytes, want %v", len(b), 2*len(line))
+	}
+	if info, _ := os.Stat(path); info.Size() != int64(len(small)) {
+		t.Errorf("file has %v bytes, want %v", info.Size(), len(small))
+	}
+}
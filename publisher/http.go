@@ -0,0 +1,264 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	retryhttp "github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// http auth types
+	HTTPAuthNone   = "none"
+	HTTPAuthBasic  = "basic"
+	HTTPAuthBearer = "bearer"
+	HTTPAuthHMAC   = "hmac"
+
+	// http modes , a request for all documents of a run or a request per document
+	HTTPModeBatch    = "batch"
+	HTTPModeDocument = "document"
+
+	// httpHeaderPrefix is the prefix of config keys holding request headers , for ex. header.X-Source
+	httpHeaderPrefix = "header."
+
+	defaultHTTPContentType = "application/json"
+	defaultHMACHeader      = "X-Signature-256"
+)
+
+var (
+	ErrMissingHTTPURL = errors.New("missing http url")
+	ErrHTTPAuthType   = errors.New("http auth type must be none , basic , bearer or hmac")
+	ErrHTTPMode       = errors.New("http mode must be batch or document")
+	ErrHTTPHMACSecret = errors.New("missing secret for hmac signature")
+)
+
+// HTTPClient holds config for http webhook target
+type HTTPClient struct {
+	// URL of webhook
+	URL string `yaml:"url" json:"url"`
+
+	// Method of request , Default: POST
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+
+	// ContentType of body , Default: application/json
+	ContentType string `yaml:"content_type,omitempty" json:"content_type,omitempty"`
+
+	// Mode is batch for a request with all documents or document for a request per document , Default: batch
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+
+	// AuthType is none , basic , bearer or hmac , Default: none
+	AuthType string `yaml:"auth_type,omitempty" json:"auth_type,omitempty"`
+
+	// Username if basic auth is used
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+
+	// Password if basic auth is used
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+
+	// Token if bearer auth is used
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+
+	// Secret to sign body with HMAC-SHA256 if hmac auth is used
+	Secret string `yaml:"secret,omitempty" json:"secret,omitempty"`
+
+	// SignatureHeader holds the signature as sha256=<hex> , Default: X-Signature-256
+	SignatureHeader string `yaml:"signature_header,omitempty" json:"signature_header,omitempty"`
+
+	// BodyTemplate is a go template rendering the body , . is the list of documents in batch mode and
+	// the document in document mode , body is the json of documents if empty
+	BodyTemplate string `yaml:"body_template,omitempty" json:"body_template,omitempty"`
+
+	// InsecureSkipVerify skips verification of server certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty,string"`
+
+	// Headers of request , given as config keys header.<name>
+	Headers map[string]string `yaml:"-" json:"-"`
+
+	template *template.Template
+}
+
+// Publish pushes the data to target
+func (hc *HTTPClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	client := HTTPClientWithRetry()
+	if hc.InsecureSkipVerify {
+		client.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Proxy: http.ProxyFromEnvironment,
+		}
+	}
+	docs := make([]interface{}, 0, len(data))
+	for _, doc := range data {
+		var m map[string]interface{}
+		byteData, err := json.Marshal(doc)
+		if err == nil {
+			err = json.Unmarshal(byteData, &m)
+		}
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		docs = append(docs, m)
+	}
+
+	if hc.Mode == HTTPModeDocument {
+		var failed int
+		var lastErr error
+		for _, doc := range docs {
+			if err := hc.send(client, doc); err != nil {
+				failed++
+				lastErr = err
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d documents not sent to %s , last error: %w", failed, len(docs), hc.URL, lastErr)
+		}
+		log.Infof("successfully sent %d docs to %s", len(docs), hc.URL)
+		return nil
+	}
+	if err := hc.send(client, docs); err != nil {
+		return err
+	}
+	log.Infof("successfully sent %d docs to %s", len(docs), hc.URL)
+	return nil
+}
+
+// send renders the body for value and makes a request to webhook
+func (hc *HTTPClient) send(client *retryhttp.Client, value interface{}) error {
+	body, err := hc.render(value)
+	if err != nil {
+		log.Errorf("error[%v] in rendering body for %s", err, hc.URL)
+		return err
+	}
+	request, err := retryhttp.NewRequest(hc.Method, hc.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", hc.ContentType)
+	for name, value := range hc.Headers {
+		request.Header.Set(name, value)
+	}
+	switch hc.AuthType {
+	case HTTPAuthBasic:
+		request.SetBasicAuth(hc.Username, hc.Password)
+	case HTTPAuthBearer:
+		request.Header.Set("Authorization", "Bearer "+hc.Token)
+	case HTTPAuthHMAC:
+		request.Header.Set(hc.SignatureHeader, "sha256="+signBody(hc.Secret, body))
+	}
+
+	timeout, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	response, err := client.Do(request.WithContext(timeout))
+	if err != nil {
+		log.Errorf("error[%v] request failed", err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		log.Errorf("error[%v] in sending data to %s , response: %s", response.Status, hc.URL, respBody)
+		return fmt.Errorf("error in sending data to %s , status: %s", hc.URL, response.Status)
+	}
+	return nil
+}
+
+// render returns the body for value , json of value if there is no body template
+func (hc *HTTPClient) render(value interface{}) ([]byte, error) {
+	if hc.template == nil {
+		return json.Marshal(value)
+	}
+	var buf bytes.Buffer
+	if err := hc.template.Execute(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// signBody returns hex encoded HMAC-SHA256 of body
+func signBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// httpTemplateFuncs are the functions available in body templates
+var httpTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// init validates config , sets defaults and parses body template
+func (hc *HTTPClient) init(config map[string]string) error {
+	if hc.URL == "" {
+		return ErrMissingHTTPURL
+	}
+	if hc.Method == "" {
+		hc.Method = http.MethodPost
+	}
+	hc.Method = strings.ToUpper(hc.Method)
+	if hc.ContentType == "" {
+		hc.ContentType = defaultHTTPContentType
+	}
+	hc.Mode = strings.ToLower(hc.Mode)
+	switch hc.Mode {
+	case "":
+		hc.Mode = HTTPModeBatch
+	case HTTPModeBatch, HTTPModeDocument:
+	default:
+		return ErrHTTPMode
+	}
+	hc.AuthType = strings.ToLower(hc.AuthType)
+	switch hc.AuthType {
+	case "":
+		hc.AuthType = HTTPAuthNone
+	case HTTPAuthNone, HTTPAuthBasic, HTTPAuthBearer:
+	case HTTPAuthHMAC:
+		if hc.Secret == "" {
+			return ErrHTTPHMACSecret
+		}
+		if hc.SignatureHeader == "" {
+			hc.SignatureHeader = defaultHMACHeader
+		}
+	default:
+		return ErrHTTPAuthType
+	}
+	hc.Headers = make(map[string]string)
+	for key, value := range config {
+		if strings.HasPrefix(key, httpHeaderPrefix) {
+			hc.Headers[strings.TrimPrefix(key, httpHeaderPrefix)] = value
+		}
+	}
+	if hc.BodyTemplate != "" {
+		t, err := template.New("body").Funcs(httpTemplateFuncs).Parse(hc.BodyTemplate)
+		if err != nil {
+			return err
+		}
+		hc.template = t
+	}
+	return nil
+}
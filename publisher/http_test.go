@@ -0,0 +1,99 @@
+package publisher
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPClient_Publish(t *testing.T) {
+	docs := []interface{}{
+		map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "sha": "9a5a338"},
+		struct {
+			DocumentType string `json:"document_type"`
+			RepoName     string `json:"repo_name"`
+		}{"issue", "repo2"},
+	}
+	tests := []struct {
+		name       string
+		config     map[string]string
+		wantBodies []string
+		wantHeader map[string]string
+	}{
+		{
+			"batch json",
+			map[string]string{"auth_type": "bearer", "token": "xxxx", "header.X-Source": "github-audit"},
+			[]string{`[{"document_type":"commit","repo_name":"repo1","sha":"9a5a338"},{"document_type":"issue","repo_name":"repo2"}]`},
+			map[string]string{"Authorization": "Bearer xxxx", "X-Source": "github-audit", "Content-Type": "application/json"},
+		},
+		{
+			"document template",
+			map[string]string{"mode": "document", "method": "put", "auth_type": "hmac", "secret": "s3cret", "content_type": "text/plain", "body_template": "{{.repo_name}}:{{upper .document_type}}"},
+			[]string{"repo1:COMMIT", "repo2:ISSUE"},
+			map[string]string{"X-Signature-256": "sha256=" + signBody("s3cret", []byte("repo2:ISSUE")), "Content-Type": "text/plain"},
+		},
+		{
+			"batch template",
+			map[string]string{"body_template": `{"text":"{{len .}} documents","docs":{{json .}}}`},
+			[]string{`{"text":"2 documents","docs":[{"document_type":"commit","repo_name":"repo1","sha":"9a5a338"},{"document_type":"issue","repo_name":"repo2"}]}`},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.config["method"] != "" && r.Method != http.MethodPut {
+					t.Errorf("Publish() method = %v", r.Method)
+				}
+				b, _ := ioutil.ReadAll(r.Body)
+				bodies = append(bodies, string(b))
+				header = r.Header
+			}))
+			defer server.Close()
+			tt.config["url"] = server.URL
+			hc, err := loadConfigHTTP(tt.config)
+			if err != nil {
+				t.Fatalf("loadConfigHTTP() error = %v", err)
+			}
+			if err = hc.Publish(docs); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			if len(bodies) != len(tt.wantBodies) {
+				t.Fatalf("Publish() made %v requests, want %v", len(bodies), len(tt.wantBodies))
+			}
+			for i := range bodies {
+				if bodies[i] != tt.wantBodies[i] {
+					t.Errorf("Publish() body = %v, want %v", bodies[i], tt.wantBodies[i])
+				}
+			}
+			for name, value := range tt.wantHeader {
+				if header.Get(name) != value {
+					t.Errorf("Publish() header %v = %v, want %v", name, header.Get(name), value)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigHTTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]string
+		wantErr error
+	}{
+		{"missing url", map[string]string{}, ErrMissingHTTPURL},
+		{"unknown mode", map[string]string{"url": "http://localhost", "mode": "stream"}, ErrHTTPMode},
+		{"unknown auth", map[string]string{"url": "http://localhost", "auth_type": "digest"}, ErrHTTPAuthType},
+		{"hmac without secret", map[string]string{"url": "http://localhost", "auth_type": "hmac"}, ErrHTTPHMACSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadConfigHTTP(tt.config); err != tt.wantErr {
+				t.Errorf("loadConfigHTTP() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package publisher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	retryhttp "github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// influxdb write api versions
+	InfluxDBVersion1 = "1"
+	InfluxDBVersion2 = "2"
+
+	// prefixes of config keys holding measurement , tags and fields of a document type , for ex. tags.commit
+	influxMeasurementPrefix = "measurement."
+	influxTagsPrefix        = "tags."
+	influxFieldsPrefix      = "fields."
+
+	defaultInfluxDBTags      = "repo_name,repo_type"
+	defaultInfluxDBBatchSize = 5000
+)
+
+var (
+	ErrMissingInfluxDBURL    = errors.New("missing influxdb url")
+	ErrMissingInfluxDBBucket = errors.New("missing influxdb bucket and org for version 2 or database for version 1")
+	ErrInfluxDBVersion       = errors.New("influxdb version must be 1 or 2")
+
+	// influxDocumentTags are the fields identifying a document among the documents of its type published at the same
+	// time , they are added to default tags so that for ex. hotspots of different paths of a report are distinct series
+	influxDocumentTags = map[string][]string{
+		"commit":                  {"sha"},
+		"pull_request":            {"pull_request_no"},
+		"pull_request_review":     {"pull_request_no", "review_id"},
+		"pull_request_cycle_time": {"pull_request_no"},
+		"commit_check":            {"sha", "source", "name"},
+		"issue":                   {"issue_no"},
+		"issue_comment":           {"issue_no", "comment_id"},
+		"deployment":              {"deployment_id", "environment"},
+		"release":                 {"release_id"},
+		"contributor_stats":       {"contributor.user"},
+		"activity_anomaly":        {"kind", "author", "branch", "sha"},
+		"compliance_violation":    {"rule_id", "change_type", "branch", "pull_request_no", "sha"},
+		"dora_metric":             {"window"},
+		"history_rewrite":         {"branch", "head"},
+		"file_hotspot":            {"path", "path_type", "window"},
+		"knowledge_distribution":  {"scope", "path", "window"},
+		"sla_status":              {"rule", "issue_no"},
+		"sla_breach":              {"rule", "sla", "issue_no"},
+		"stale_pull_request":      {"pull_request_no"},
+		"stale_branch":            {"branch"},
+		"work_pattern":            {"author"},
+	}
+
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", " ")
+	influxKeyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", " ")
+	influxStringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// InfluxDBClient holds config for influxdb target , documents are written as points in line protocol
+type InfluxDBClient struct {
+	// URL of influxdb , for ex. http://influxdb:8086
+	URL string `yaml:"url" json:"url"`
+
+	// Version of write api , 2 for /api/v2/write or 1 for /write of influxdb 1.x and v1 compatibility api , Default: 2
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+
+	// Org to write to , version 2
+	Org string `yaml:"org,omitempty" json:"org,omitempty"`
+
+	// Bucket to write to , version 2
+	Bucket string `yaml:"bucket,omitempty" json:"bucket,omitempty"`
+
+	// Token for authentication , version 2
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+
+	// Database to write to , version 1
+	Database string `yaml:"database,omitempty" json:"database,omitempty"`
+
+	// RetentionPolicy to write to , version 1
+	RetentionPolicy string `yaml:"retention_policy,omitempty" json:"retention_policy,omitempty"`
+
+	// Username if basic auth is used , version 1
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+
+	// Password if basic auth is used , version 1
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+
+	// Tags are comma separated document fields used as tags of document types without tags.<document_type> ,
+	// the fields identifying a document of known document types are always added , Default: repo_name,repo_type
+	Tags string `yaml:"tags,omitempty" json:"tags,omitempty"`
+
+	// BatchSize is the number of points in a write request , Default: 5000
+	BatchSize int `yaml:"batch_size,omitempty" json:"batch_size,omitempty,string"`
+
+	// Gzip compresses write requests , Default: true
+	Gzip *bool `yaml:"gzip,omitempty" json:"gzip,omitempty,string"`
+
+	// InsecureSkipVerify skips verification of server certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty,string"`
+
+	// Measurements by document type , given as config keys measurement.<document_type> , Default: document type
+	Measurements map[string]string `yaml:"-" json:"-"`
+
+	// TagKeys by document type , given as config keys tags.<document_type> with comma separated document fields
+	TagKeys map[string][]string `yaml:"-" json:"-"`
+
+	// FieldKeys by document type , given as config keys fields.<document_type> with comma separated document fields ,
+	// Default: numeric and boolean fields of document
+	FieldKeys map[string][]string `yaml:"-" json:"-"`
+
+	tags []string
+}
+
+// Publish pushes the data to target in batches , a batch rejected for its points is skipped while other
+// batches are written , errors of authorization or bucket stop publishing
+func (ic *InfluxDBClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	client := HTTPClientWithRetry()
+	if ic.InsecureSkipVerify {
+		client.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Proxy: http.ProxyFromEnvironment,
+		}
+	}
+	now := time.Now()
+	var lines []string
+	for _, doc := range documentMaps(data) {
+		if line := ic.line(doc, now); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var (
+		failed  int
+		lastErr error
+	)
+	for start := 0; start < len(lines); start += ic.BatchSize {
+		end := start + ic.BatchSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+		status, err := ic.write(client, lines[start:end])
+		if err == nil {
+			continue
+		}
+		if status != http.StatusBadRequest && status != http.StatusUnprocessableEntity {
+			return err
+		}
+		failed += end - start
+		lastErr = err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d points not written to influxdb , last error: %w", failed, len(lines), lastErr)
+	}
+	log.Infof("successfully sent %d points to %s", len(lines), ic.URL)
+	return nil
+}
+
+// line returns the point of a document in line protocol , empty if document has no fields
+func (ic *InfluxDBClient) line(doc map[string]interface{}, now time.Time) string {
+	documentType, _ := doc["document_type"].(string)
+	measurement := documentType
+	if m, ok := ic.Measurements[documentType]; ok {
+		measurement = m
+	}
+	tagKeys, ok := ic.TagKeys[documentType]
+	if !ok {
+		tagKeys = append(append([]string{}, ic.tags...), influxDocumentTags[documentType]...)
+	}
+	isTag := make(map[string]bool)
+	var tags []string
+	for _, key := range tagKeys {
+		isTag[key] = true
+		value := influxTagValue(documentField(doc, key))
+		if value != "" {
+			tags = append(tags, influxKeyEscaper.Replace(key)+"="+influxKeyEscaper.Replace(value))
+		}
+	}
+	sort.Strings(tags)
+
+	fieldKeys, ok := ic.FieldKeys[documentType]
+	if !ok {
+		for key, value := range doc {
+			switch value.(type) {
+			case float64, bool:
+				if !isTag[key] && key != "time" {
+					fieldKeys = append(fieldKeys, key)
+				}
+			}
+		}
+		sort.Strings(fieldKeys)
+	}
+	var fields []string
+	for _, key := range fieldKeys {
+		if value := influxFieldValue(documentField(doc, key)); value != "" {
+			fields = append(fields, influxKeyEscaper.Replace(key)+"="+value)
+		}
+	}
+	if len(fields) == 0 {
+		log.Debugf("skipping %s document without fields", documentType)
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(influxMeasurementEscaper.Replace(measurement))
+	for _, tag := range tags {
+		b.WriteString(",")
+		b.WriteString(tag)
+	}
+	b.WriteString(" ")
+	b.WriteString(strings.Join(fields, ","))
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatInt(documentTime(doc, now).UnixNano(), 10))
+	return b.String()
+}
+
+// documentField returns the value of a field of document , nested fields are separated by dots , for ex. created_by.user
+func documentField(doc map[string]interface{}, key string) interface{} {
+	if value, ok := doc[key]; ok {
+		return value
+	}
+	var value interface{} = doc
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[part]
+	}
+	return value
+}
+
+// influxTagValue returns the tag value of a json value , empty for values which are not scalar
+func influxTagValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// influxFieldValue returns the field value of a json value in line protocol , numbers are always floats as
+// json does not keep their type and influxdb rejects a field with values of different types
+func influxFieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return `"` + influxStringEscaper.Replace(v) + `"`
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}, map[string]interface{}:
+		b, _ := json.Marshal(v)
+		return `"` + influxStringEscaper.Replace(string(b)) + `"`
+	}
+	return ""
+}
+
+// write sends lines to influxdb and returns the status of response
+func (ic *InfluxDBClient) write(client *retryhttp.Client, lines []string) (int, error) {
+	body := []byte(strings.Join(lines, "\n"))
+	if *ic.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		zw.Close()
+		body = buf.Bytes()
+	}
+	request, err := retryhttp.NewRequest(http.MethodPost, ic.writeURL(), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if *ic.Gzip {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	if ic.Token != "" {
+		request.Header.Set("Authorization", "Token "+ic.Token)
+	}
+	if ic.Username != "" && ic.Password != "" {
+		request.SetBasicAuth(ic.Username, ic.Password)
+	}
+
+	timeout, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	response, err := client.Do(request.WithContext(timeout))
+	if err != nil {
+		log.Errorf("error[%v] request failed", err)
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return response.StatusCode, nil
+	}
+	respBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+	var influxErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	message := strings.TrimSpace(string(respBody))
+	if json.Unmarshal(respBody, &influxErr) == nil {
+		if influxErr.Message != "" {
+			message = influxErr.Message
+		} else if influxErr.Error != "" {
+			message = influxErr.Error
+		}
+	}
+	err = fmt.Errorf("influxdb write failed with status %s: %s", response.Status, message)
+	log.Errorf("error[%v] in sending %d points to %s", err, len(lines), ic.URL)
+	return response.StatusCode, err
+}
+
+// writeURL returns the url of write api
+func (ic *InfluxDBClient) writeURL() string {
+	params := url.Values{}
+	params.Set("precision", "ns")
+	base := strings.TrimSuffix(ic.URL, "/")
+	if ic.Version == InfluxDBVersion1 {
+		params.Set("db", ic.Database)
+		if ic.RetentionPolicy != "" {
+			params.Set("rp", ic.RetentionPolicy)
+		}
+		return base + "/write?" + params.Encode()
+	}
+	params.Set("org", ic.Org)
+	params.Set("bucket", ic.Bucket)
+	return base + "/api/v2/write?" + params.Encode()
+}
+
+// init validates config and sets defaults
+func (ic *InfluxDBClient) init(config map[string]string) error {
+	if ic.URL == "" {
+		return ErrMissingInfluxDBURL
+	}
+	switch ic.Version {
+	case "":
+		ic.Version = InfluxDBVersion2
+	case InfluxDBVersion1, InfluxDBVersion2:
+	default:
+		return ErrInfluxDBVersion
+	}
+	if (ic.Version == InfluxDBVersion2 && (ic.Bucket == "" || ic.Org == "")) || (ic.Version == InfluxDBVersion1 && ic.Database == "") {
+		return ErrMissingInfluxDBBucket
+	}
+	if ic.Tags == "" {
+		ic.Tags = defaultInfluxDBTags
+	}
+	ic.tags = splitList(ic.Tags)
+	if ic.BatchSize <= 0 {
+		ic.BatchSize = defaultInfluxDBBatchSize
+	}
+	if ic.Gzip == nil {
+		gzip := true
+		ic.Gzip = &gzip
+	}
+	ic.Measurements = make(map[string]string)
+	ic.TagKeys = make(map[string][]string)
+	ic.FieldKeys = make(map[string][]string)
+	for key, value := range config {
+		switch {
+		case strings.HasPrefix(key, influxMeasurementPrefix):
+			ic.Measurements[strings.TrimPrefix(key, influxMeasurementPrefix)] = value
+		case strings.HasPrefix(key, influxTagsPrefix):
+			ic.TagKeys[strings.TrimPrefix(key, influxTagsPrefix)] = splitList(value)
+		case strings.HasPrefix(key, influxFieldsPrefix):
+			ic.FieldKeys[strings.TrimPrefix(key, influxFieldsPrefix)] = splitList(value)
+		}
+	}
+	return nil
+}
+
+// splitList returns the comma separated values
+func splitList(values string) []string {
+	var list []string
+	for _, v := range strings.Split(values, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
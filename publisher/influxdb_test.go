@@ -0,0 +1,154 @@
+package publisher
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInfluxDBClient_line(t *testing.T) {
+	ic, err := loadConfigInfluxDB(map[string]string{
+		"url": "http://localhost:8086", "org": "org1", "bucket": "audit",
+		"measurement.pull_request_cycle_time": "cycle time",
+		"tags.pull_request_cycle_time":        "repo_name,branch,created_by.user",
+		"fields.commit":                       "message,verification.verified",
+	})
+	if err != nil {
+		t.Fatalf("loadConfigInfluxDB() error = %v", err)
+	}
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		doc  map[string]interface{}
+		want string
+	}{
+		{
+			"configured tags",
+			map[string]interface{}{"document_type": "pull_request_cycle_time", "repo_name": "repo1", "branch": "feature a,b", "created_by": map[string]interface{}{"user": "dev1"},
+				"created_at": "2022-10-10T10:00:00Z", "cycle_time_seconds": 3600.0, "review_rounds": 2.0, "time": 1665396000000.0, "merged": true},
+			`cycle\ time,branch=feature\ a\,b,created_by.user=dev1,repo_name=repo1 cycle_time_seconds=3600,merged=true,review_rounds=2 1665396000000000000`,
+		},
+		{
+			"configured fields",
+			map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "repo_type": "github", "message": "fix \"quoted\" path\\name", "verification": map[string]interface{}{"verified": false}},
+			`commit,repo_name=repo1,repo_type=github message="fix \"quoted\" path\\name",verification.verified=false 1665403200000000000`,
+		},
+		{
+			"default fields",
+			map[string]interface{}{"document_type": "dora_metric", "repo_name": "repo1", "repo_type": "github", "change_failure_rate": 0.25, "window": "7d"},
+			`dora_metric,repo_name=repo1,repo_type=github,window=7d change_failure_rate=0.25 1665403200000000000`,
+		},
+		{
+			"no fields",
+			map[string]interface{}{"document_type": "issue", "repo_name": "repo1", "title": "bug"},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ic.line(tt.doc, now); got != tt.want {
+				t.Errorf("line() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfluxDBClient_Publish(t *testing.T) {
+	var requests []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery+" "+r.Header.Get("Authorization"))
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Fatalf("gzip.NewReader() error = %v", err)
+			}
+			body = zr
+		}
+		b, _ := ioutil.ReadAll(body)
+		bodies = append(bodies, string(b))
+		if strings.Contains(string(b), "invalid") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":"invalid","message":"partial write: field type conflict"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	docs := []interface{}{
+		map[string]interface{}{"document_type": "dora_metric", "repo_name": "repo1", "deployment_count": 3},
+		map[string]interface{}{"document_type": "invalid", "repo_name": "repo1", "count": 1},
+		map[string]interface{}{"document_type": "dora_metric", "repo_name": "repo2", "deployment_count": 1},
+	}
+
+	ic, err := loadConfigInfluxDB(map[string]string{"url": server.URL, "org": "org1", "bucket": "audit", "token": "xxxx", "batch_size": "1"})
+	if err != nil {
+		t.Fatalf("loadConfigInfluxDB() error = %v", err)
+	}
+	if err = ic.Publish(docs); err == nil || !strings.HasPrefix(err.Error(), "1 of 3 points") {
+		t.Errorf("Publish() error = %v, want 1 of 3 points not written", err)
+	}
+	if len(bodies) != 3 || requests[0] != "/api/v2/write?bucket=audit&org=org1&precision=ns Token xxxx" {
+		t.Errorf("Publish() requests = %v", requests)
+	}
+
+	requests, bodies = nil, nil
+	ic, err = loadConfigInfluxDB(map[string]string{"url": server.URL, "version": "1", "database": "audit", "retention_policy": "autogen", "gzip": "false"})
+	if err != nil {
+		t.Fatalf("loadConfigInfluxDB() error = %v", err)
+	}
+	if err = ic.Publish(docs[:1]); err != nil {
+		t.Errorf("Publish() error = %v", err)
+	}
+	if len(requests) != 1 || requests[0] != "/write?db=audit&precision=ns&rp=autogen " || !strings.HasPrefix(bodies[0], "dora_metric,repo_name=repo1 deployment_count=3 ") {
+		t.Errorf("Publish() requests = %v bodies = %v", requests, bodies)
+	}
+}
+
+func TestInfluxDBClient_PublishDocumentTags(t *testing.T) {
+	var lines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		lines = append(lines, strings.Split(string(b), "\n")...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	// hotspots of a report have the same created_at
+	hotspot := func(path string, pathType string, window string) map[string]interface{} {
+		return map[string]interface{}{"document_type": "file_hotspot", "repo_name": "repo1", "repo_type": "github", "created_at": "2022-10-10T10:00:00Z",
+			"path": path, "path_type": pathType, "window": window, "rank": 1.0, "changes": 4.0}
+	}
+	docs := []interface{}{
+		hotspot("main.go", "file", "30d"),
+		hotspot("internal/task/task.go", "file", "30d"),
+		hotspot("internal/task", "directory", "30d"),
+		hotspot("main.go", "file", "90d"),
+	}
+	ic, err := loadConfigInfluxDB(map[string]string{"url": server.URL, "org": "org1", "bucket": "audit", "gzip": "false"})
+	if err != nil {
+		t.Fatalf("loadConfigInfluxDB() error = %v", err)
+	}
+	if err = ic.Publish(docs); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(lines) != len(docs) {
+		t.Fatalf("Publish() wrote %v , want %v points", lines, len(docs))
+	}
+	// a point overwrites the point of the same series and time , so every document needs its own series
+	series := make(map[string]bool)
+	for _, line := range lines {
+		key := line[:strings.Index(line, " ")]
+		if series[key] {
+			t.Errorf("Publish() wrote series %v more than once", key)
+		}
+		series[key] = true
+	}
+	if want := "file_hotspot,path=internal/task,path_type=directory,repo_name=repo1,repo_type=github,window=30d"; !series[want] {
+		t.Errorf("Publish() wrote series %v , want %v", series, want)
+	}
+}
@@ -0,0 +1,326 @@
+package publisher
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+const (
+	// kafka acks
+	KafkaAcksNone   = "none"
+	KafkaAcksLeader = "leader"
+	KafkaAcksAll    = "all"
+
+	// kafka sasl mechanisms
+	KafkaSASLPlain       = "PLAIN"
+	KafkaSASLScramSHA256 = "SCRAM-SHA-256"
+	KafkaSASLScramSHA512 = "SCRAM-SHA-512"
+
+	// defaultKafkaVersion is the least kafka version supporting idempotent production
+	defaultKafkaVersion = "0.11.0.0"
+)
+
+var (
+	ErrMissingKafkaBrokers  = errors.New("missing kafka brokers or topic")
+	ErrKafkaAcks            = errors.New("kafka acks must be none , leader or all")
+	ErrKafkaIdempotentAcks  = errors.New("idempotent kafka producer needs acks all")
+	ErrKafkaSASLMechanism   = errors.New("kafka sasl mechanism must be PLAIN , SCRAM-SHA-256 or SCRAM-SHA-512")
+	ErrKafkaCompressionType = errors.New("kafka compression must be none , gzip , snappy , lz4 or zstd")
+
+	// kafkaProducers holds producers with their config as key , as publishers are created for every run of a task
+	// while connections to brokers are kept open by producers
+	kafkaProducers      = make(map[string]sarama.SyncProducer)
+	kafkaProducersMutex sync.Mutex
+
+	// documentIDFields are the fields identifying a document , the first one present is used
+	documentIDFields = []string{"sha", "pull_request_no", "issue_no", "comment_id", "review_id", "deployment_id", "release_id"}
+)
+
+// KafkaClient holds config for kafka target , publishing with the native kafka protocol
+type KafkaClient struct {
+	// Brokers are comma separated host:port of brokers
+	Brokers string `yaml:"brokers" json:"brokers"`
+
+	// Topic name to send data to
+	Topic string `yaml:"topic" json:"topic"`
+
+	// ClientID is the client id sent to brokers , Default: github-audit
+	ClientID string `yaml:"client_id,omitempty" json:"client_id,omitempty"`
+
+	// Version is the kafka version of brokers , Default: 0.11.0.0
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+
+	// Acks is none , leader or all , Default: all
+	Acks string `yaml:"acks,omitempty" json:"acks,omitempty"`
+
+	// Compression is none , gzip , snappy , lz4 or zstd , Default: none
+	Compression string `yaml:"compression,omitempty" json:"compression,omitempty"`
+
+	// Idempotent turns on idempotent production so that retries do not duplicate messages , needs acks all
+	Idempotent bool `yaml:"idempotent,omitempty" json:"idempotent,omitempty,string"`
+
+	// TLS turns on tls connections to brokers
+	TLS bool `yaml:"tls,omitempty" json:"tls,omitempty,string"`
+
+	// CACert is the path of pem ca certificates of brokers , Default: system certificates
+	CACert string `yaml:"ca_cert,omitempty" json:"ca_cert,omitempty"`
+
+	// ClientCert is the path of pem client certificate for mutual tls
+	ClientCert string `yaml:"client_cert,omitempty" json:"client_cert,omitempty"`
+
+	// ClientKey is the path of pem client key for mutual tls
+	ClientKey string `yaml:"client_key,omitempty" json:"client_key,omitempty"`
+
+	// InsecureSkipVerify skips verification of broker certificates
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty,string"`
+
+	// SASLMechanism is PLAIN , SCRAM-SHA-256 or SCRAM-SHA-512 , empty if sasl is not used
+	SASLMechanism string `yaml:"sasl_mechanism,omitempty" json:"sasl_mechanism,omitempty"`
+
+	// Username for sasl
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+
+	// Password for sasl
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+
+	// producer sends messages , shared by clients with same config
+	producer sarama.SyncProducer
+}
+
+// Publish pushes the data to target , each document is a message keyed by repository , document type and document id.
+// Messages of a repository go to the same partition so they stay ordered per repository.
+func (kc *KafkaClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	producer, err := kc.getProducer()
+	if err != nil {
+		log.Errorf("error[%v] in creating kafka producer for brokers %v", err, kc.Brokers)
+		return err
+	}
+	messages := make([]*sarama.ProducerMessage, 0, len(data))
+	for _, doc := range data {
+		byteData, err := json.Marshal(doc)
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic: kc.Topic,
+			Key:   sarama.StringEncoder(documentKey(doc, byteData)),
+			Value: sarama.ByteEncoder(byteData),
+		})
+	}
+	err = producer.SendMessages(messages)
+	if err != nil {
+		var producerErrs sarama.ProducerErrors
+		if errors.As(err, &producerErrs) {
+			log.Errorf("error[%v] in sending %v of %v messages to kafka topic %v", producerErrs[0].Err, len(producerErrs), len(messages), kc.Topic)
+		} else {
+			log.Errorf("error[%v] in sending messages to kafka topic %v", err, kc.Topic)
+		}
+		return err
+	}
+	log.Infof("successfully sent %v messages to kafka topic %s", len(messages), kc.Topic)
+	return nil
+}
+
+// getProducer returns the producer of client , creating and sharing one for the config if needed. Clients may be
+// used by several goroutines , so producer of client is read and set only while holding kafkaProducersMutex.
+func (kc *KafkaClient) getProducer() (sarama.SyncProducer, error) {
+	kafkaProducersMutex.Lock()
+	defer kafkaProducersMutex.Unlock()
+	if kc.producer != nil {
+		return kc.producer, nil
+	}
+	key, err := json.Marshal(kc)
+	if err != nil {
+		return nil, err
+	}
+	if producer, ok := kafkaProducers[string(key)]; ok {
+		kc.producer = producer
+		return producer, nil
+	}
+	cfg, err := kc.saramaConfig()
+	if err != nil {
+		return nil, err
+	}
+	producer, err := sarama.NewSyncProducer(strings.Split(kc.Brokers, ","), cfg)
+	if err != nil {
+		return nil, err
+	}
+	kafkaProducers[string(key)] = producer
+	kc.producer = producer
+	return producer, nil
+}
+
+// saramaConfig returns the producer config of client
+func (kc *KafkaClient) saramaConfig() (*sarama.Config, error) {
+	cfg := sarama.NewConfig()
+	cfg.ClientID = kc.ClientID
+	if cfg.ClientID == "" {
+		cfg.ClientID = "github-audit"
+	}
+	version := kc.Version
+	if version == "" {
+		version = defaultKafkaVersion
+	}
+	var err error
+	if cfg.Version, err = sarama.ParseKafkaVersion(version); err != nil {
+		return nil, err
+	}
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Timeout = 15 * time.Second
+	cfg.Producer.Partitioner = newRepositoryPartitioner
+
+	switch strings.ToLower(kc.Acks) {
+	case KafkaAcksNone:
+		cfg.Producer.RequiredAcks = sarama.NoResponse
+	case KafkaAcksLeader:
+		cfg.Producer.RequiredAcks = sarama.WaitForLocal
+	case KafkaAcksAll, "":
+		cfg.Producer.RequiredAcks = sarama.WaitForAll
+	default:
+		return nil, ErrKafkaAcks
+	}
+	if kc.Idempotent {
+		if cfg.Producer.RequiredAcks != sarama.WaitForAll {
+			return nil, ErrKafkaIdempotentAcks
+		}
+		cfg.Producer.Idempotent = true
+		cfg.Net.MaxOpenRequests = 1
+	}
+	if kc.Compression != "" {
+		if err = cfg.Producer.Compression.UnmarshalText([]byte(strings.ToLower(kc.Compression))); err != nil {
+			return nil, ErrKafkaCompressionType
+		}
+	}
+
+	if kc.TLS {
+		cfg.Net.TLS.Enable = true
+		if cfg.Net.TLS.Config, err = kc.tlsConfig(); err != nil {
+			return nil, err
+		}
+	}
+	if kc.SASLMechanism != "" {
+		cfg.Net.SASL.Enable = true
+		cfg.Net.SASL.User = kc.Username
+		cfg.Net.SASL.Password = kc.Password
+		switch strings.ToUpper(kc.SASLMechanism) {
+		case KafkaSASLPlain:
+			cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case KafkaSASLScramSHA256:
+			cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hashGenerator: sha256.New} }
+		case KafkaSASLScramSHA512:
+			cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hashGenerator: sha512.New} }
+		default:
+			return nil, ErrKafkaSASLMechanism
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// tlsConfig returns the tls config with configured certificates
+func (kc *KafkaClient) tlsConfig() (*tls.Config, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: kc.InsecureSkipVerify}
+	if kc.CACert != "" {
+		pem, err := os.ReadFile(kc.CACert)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in kafka ca cert %v", kc.CACert)
+		}
+	}
+	if kc.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(kc.ClientCert, kc.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// scramClient implements sarama.SCRAMClient
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+	conversation  *scram.ClientConversation
+}
+
+// Begin starts a scram conversation
+func (sc *scramClient) Begin(userName, password, authzID string) error {
+	client, err := sc.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	sc.conversation = client.NewConversation()
+	return nil
+}
+
+// Step returns the response to a challenge of broker
+func (sc *scramClient) Step(challenge string) (string, error) {
+	return sc.conversation.Step(challenge)
+}
+
+// Done returns true when the conversation is complete
+func (sc *scramClient) Done() bool {
+	return sc.conversation.Done()
+}
+
+// repositoryPartitioner partitions messages by the repository part of their key
+type repositoryPartitioner struct {
+	sarama.Partitioner
+}
+
+// newRepositoryPartitioner returns a hash partitioner on the repository part of keys
+func newRepositoryPartitioner(topic string) sarama.Partitioner {
+	return repositoryPartitioner{Partitioner: sarama.NewHashPartitioner(topic)}
+}
+
+// Partition returns the partition of the repository of message
+func (rp repositoryPartitioner) Partition(message *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if message.Key == nil {
+		return rp.Partitioner.Partition(message, numPartitions)
+	}
+	key, err := message.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+	repo := strings.SplitN(string(key), "/", 2)[0]
+	return rp.Partitioner.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder(repo)}, numPartitions)
+}
+
+// documentKey returns repo_name/document_type/id of a document , id is the first present of document id fields
+// or a hash of the document
+func documentKey(doc interface{}, byteData []byte) string {
+	fields, _ := doc.(map[string]interface{})
+	id := ""
+	for _, f := range documentIDFields {
+		if v, ok := fields[f]; ok && v != nil && v != "" {
+			id = fmt.Sprint(v)
+			break
+		}
+	}
+	if id == "" {
+		sum := sha256.Sum256(byteData)
+		id = hex.EncodeToString(sum[:])
+	}
+	return fmt.Sprintf("%v/%v/%v", fields["repo_name"], fields["document_type"], id)
+}
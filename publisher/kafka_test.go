@@ -0,0 +1,88 @@
+package publisher
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func TestKafkaClient_Publish(t *testing.T) {
+	docs := []interface{}{
+		map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "sha": "9a5a338"},
+		map[string]interface{}{"document_type": "issue", "repo_name": "repo1", "issue_no": "12"},
+		map[string]interface{}{"document_type": "dora_metric", "repo_name": "repo2"},
+	}
+	wantKeys := []string{"repo1/commit/9a5a338", "repo1/issue/12", "repo2/dora_metric/"}
+
+	producer := mocks.NewSyncProducer(t, nil)
+	for range docs {
+		producer.ExpectSendMessageAndSucceed()
+	}
+	kc := &KafkaClient{Brokers: "localhost:9092", Topic: "audit", producer: producer}
+	if err := kc.Publish(docs); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := producer.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	partitioner := newRepositoryPartitioner("audit")
+	var partitions []int32
+	for i, doc := range docs {
+		b, _ := json.Marshal(doc)
+		key := documentKey(doc, b)
+		if i < 2 && key != wantKeys[i] {
+			t.Errorf("documentKey() = %v, want %v", key, wantKeys[i])
+		}
+		if i == 2 && len(key) != len(wantKeys[i])+64 {
+			t.Errorf("documentKey() = %v, want %v with hash of document", key, wantKeys[i])
+		}
+		p, err := partitioner.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder(key)}, 16)
+		if err != nil {
+			t.Fatalf("Partition() error = %v", err)
+		}
+		partitions = append(partitions, p)
+	}
+	if partitions[0] != partitions[1] {
+		t.Errorf("Partition() = %v, want same partition for documents of a repository", partitions)
+	}
+}
+
+func TestKafkaClient_saramaConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		kc      KafkaClient
+		wantErr error
+	}{
+		{"defaults", KafkaClient{}, nil},
+		{"idempotent", KafkaClient{Idempotent: true, Compression: "zstd", Version: "2.8.0"}, nil},
+		{"scram", KafkaClient{SASLMechanism: "scram-sha-512", Username: "user", Password: "secret"}, nil},
+		{"idempotent with leader acks", KafkaClient{Idempotent: true, Acks: "leader"}, ErrKafkaIdempotentAcks},
+		{"unknown acks", KafkaClient{Acks: "some"}, ErrKafkaAcks},
+		{"unknown sasl", KafkaClient{SASLMechanism: "GSSAPI"}, ErrKafkaSASLMechanism},
+		{"unknown compression", KafkaClient{Compression: "brotli"}, ErrKafkaCompressionType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.kc.saramaConfig()
+			if err != tt.wantErr {
+				t.Errorf("saramaConfig() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigKafka(t *testing.T) {
+	kc, err := loadConfigKafka(map[string]string{"brokers": "b1:9092,b2:9092", "topic": "audit", "idempotent": "true", "tls": "false"})
+	if err != nil {
+		t.Fatalf("loadConfigKafka() error = %v", err)
+	}
+	if !kc.Idempotent || kc.TLS || kc.Brokers != "b1:9092,b2:9092" {
+		t.Errorf("loadConfigKafka() = %+v", kc)
+	}
+	if _, err = loadConfigKafka(map[string]string{"topic": "audit"}); err != ErrMissingKafkaBrokers {
+		t.Errorf("loadConfigKafka() error = %v, want %v", err, ErrMissingKafkaBrokers)
+	}
+}
@@ -0,0 +1,398 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	retryhttp "github.com/hashicorp/go-retryablehttp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	lokiPushPath = "/loki/api/v1/push"
+
+	// loki encodings
+	LokiEncodingProtobuf = "protobuf"
+	LokiEncodingJSON     = "json"
+
+	// loki timestamps of entries , created_at of document or time of publishing
+	LokiTimestampCreatedAt = "created_at"
+	LokiTimestampNow       = "now"
+
+	// lokiLabelPrefix is the prefix of config keys holding static labels , for ex. label.job
+	lokiLabelPrefix = "label."
+
+	defaultLokiLabels     = "repo_name,document_type"
+	defaultLokiBatchSize  = 1000
+	defaultLokiMaxRetries = 5
+	defaultLokiJob        = "github-audit"
+)
+
+var (
+	ErrMissingLokiURL  = errors.New("missing loki url")
+	ErrLokiEncoding    = errors.New("loki encoding must be protobuf or json")
+	ErrLokiTimestamp   = errors.New("loki timestamp must be created_at or now")
+	ErrLokiRateLimited = errors.New("loki rate limit exceeded")
+
+	// lokiRejectedEntries matches the reason of entries rejected for their timestamp
+	lokiRejectedEntries = regexp.MustCompile(`out of order|too far behind|too old`)
+	lokiIgnoredCount    = regexp.MustCompile(`total ignored: (\d+) out of (\d+)`)
+	lokiInvalidLabel    = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// LokiClient holds config for grafana loki target
+type LokiClient struct {
+	// URL of loki , for ex. http://loki:3100
+	URL string `yaml:"url" json:"url"`
+
+	// Labels are comma separated document fields used as stream labels , Default: repo_name,document_type
+	Labels string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// Encoding of push request , protobuf for snappy compressed protobuf or json , Default: protobuf
+	Encoding string `yaml:"encoding,omitempty" json:"encoding,omitempty"`
+
+	// Timestamp of entries , created_at of document or now , Default: now as loki ignores entries older than the
+	// latest entry of their stream , like the ones of pull requests and issues published again when updated
+	Timestamp string `yaml:"timestamp,omitempty" json:"timestamp,omitempty"`
+
+	// TenantID is sent as X-Scope-OrgID for multi tenant loki
+	TenantID string `yaml:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+
+	// Username if basic auth is used
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+
+	// Password if basic auth is used
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+
+	// Token if bearer auth is used
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+
+	// BatchSize is the number of entries in a push request , Default: 1000
+	BatchSize int `yaml:"batch_size,omitempty" json:"batch_size,omitempty,string"`
+
+	// MaxRetries of a push request rejected by rate limits or server errors , Default: 5
+	MaxRetries int `yaml:"max_retries,omitempty" json:"max_retries,omitempty,string"`
+
+	// InsecureSkipVerify skips verification of server certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty,string"`
+
+	// StaticLabels are added to all streams , given as config keys label.<name>
+	StaticLabels map[string]string `yaml:"-" json:"-"`
+
+	labelFields []string
+}
+
+// lokiStream represents the entries of a stream
+type lokiStream struct {
+	labels   string
+	labelSet map[string]string
+	entries  []lokiEntry
+}
+
+// lokiEntry represents a log line
+type lokiEntry struct {
+	timestamp time.Time
+	line      string
+}
+
+// Publish pushes the data to target , documents become log lines of streams with labels from document fields
+func (lc *LokiClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	client := HTTPClientWithRetry()
+	client.RetryMax = lc.MaxRetries
+	client.ErrorHandler = retryhttp.PassthroughErrorHandler
+	if lc.InsecureSkipVerify {
+		client.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Proxy: http.ProxyFromEnvironment,
+		}
+	}
+
+	streams := lc.streams(data, time.Now())
+	var batch []lokiStream
+	var size int
+	for _, stream := range streams {
+		for start := 0; start < len(stream.entries); {
+			end := start + lc.BatchSize - size
+			if end > len(stream.entries) {
+				end = len(stream.entries)
+			}
+			batch = append(batch, lokiStream{labels: stream.labels, labelSet: stream.labelSet, entries: stream.entries[start:end]})
+			size += end - start
+			start = end
+			if size == lc.BatchSize {
+				if err := lc.push(client, batch); err != nil {
+					return err
+				}
+				batch, size = nil, 0
+			}
+		}
+	}
+	if size > 0 {
+		if err := lc.push(client, batch); err != nil {
+			return err
+		}
+	}
+	log.Infof("successfully sent %d docs in %d streams to %s", len(data), len(streams), lc.URL)
+	return nil
+}
+
+// streams groups documents by labels , entries of a stream are sorted by timestamp as loki may reject
+// entries older than the latest entry of stream
+func (lc *LokiClient) streams(data []interface{}, now time.Time) []lokiStream {
+	byLabels := make(map[string]*lokiStream)
+	for _, doc := range data {
+		byteData, err := json.Marshal(doc)
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		var fields map[string]interface{}
+		if err = json.Unmarshal(byteData, &fields); err != nil {
+			log.Errorf("error[%v] unable to unmarshal data", err)
+			continue
+		}
+		labels, labelSet := lc.labels(fields)
+		stream, ok := byLabels[labels]
+		if !ok {
+			stream = &lokiStream{labels: labels, labelSet: labelSet}
+			byLabels[labels] = stream
+		}
+		timestamp := now
+		if lc.Timestamp == LokiTimestampCreatedAt {
+			if createdAt, ok := fields["created_at"].(string); ok {
+				if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+					timestamp = t
+				}
+			}
+		}
+		stream.entries = append(stream.entries, lokiEntry{timestamp: timestamp, line: string(byteData)})
+	}
+	streams := make([]lokiStream, 0, len(byLabels))
+	for _, stream := range byLabels {
+		sort.SliceStable(stream.entries, func(i, j int) bool {
+			return stream.entries[i].timestamp.Before(stream.entries[j].timestamp)
+		})
+		streams = append(streams, *stream)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].labels < streams[j].labels
+	})
+	return streams
+}
+
+// labels returns the label set of a document in loki format and as map , fields which are missing or not
+// scalar are skipped
+func (lc *LokiClient) labels(fields map[string]interface{}) (string, map[string]string) {
+	labels := make(map[string]string)
+	for name, value := range lc.StaticLabels {
+		labels[name] = value
+	}
+	for _, field := range lc.labelFields {
+		switch v := fields[field].(type) {
+		case string:
+			if v != "" {
+				labels[lokiLabelName(field)] = v
+			}
+		case float64, bool:
+			labels[lokiLabelName(field)] = fmt.Sprint(v)
+		}
+	}
+	if len(labels) == 0 {
+		// loki needs at least a label in a stream
+		labels["job"] = defaultLokiJob
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+strconv.Quote(labels[name]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}", labels
+}
+
+// lokiLabelName returns a valid label name for field
+func lokiLabelName(field string) string {
+	name := lokiInvalidLabel.ReplaceAllString(field, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// push sends streams to loki , entries rejected for their timestamp are logged as loki keeps the other entries
+// of request , rejections by rate limits are retried after the time given by loki
+func (lc *LokiClient) push(client *retryhttp.Client, streams []lokiStream) error {
+	var (
+		body []byte
+		err  error
+	)
+	if lc.Encoding == LokiEncodingJSON {
+		body, err = encodeLokiJSON(streams)
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal push request", err)
+			return err
+		}
+	} else {
+		body = snappy.Encode(nil, encodeLokiProtobuf(streams))
+	}
+	request, err := retryhttp.NewRequest(http.MethodPost, strings.TrimSuffix(lc.URL, "/")+lokiPushPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if lc.Encoding == LokiEncodingJSON {
+		request.Header.Set("Content-Type", "application/json")
+	} else {
+		request.Header.Set("Content-Type", "application/x-protobuf")
+	}
+	if lc.TenantID != "" {
+		request.Header.Set("X-Scope-OrgID", lc.TenantID)
+	}
+	if lc.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+lc.Token)
+	}
+	if lc.Username != "" && lc.Password != "" {
+		request.SetBasicAuth(lc.Username, lc.Password)
+	}
+
+	timeout, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	response, err := client.Do(request.WithContext(timeout))
+	if err != nil {
+		log.Errorf("error[%v] request failed", err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return nil
+	}
+	respBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+	switch {
+	case response.StatusCode == http.StatusBadRequest && lokiRejectedEntries.Match(respBody):
+		ignored := "some"
+		if m := lokiIgnoredCount.FindSubmatch(respBody); m != nil {
+			ignored = string(m[1]) + " of " + string(m[2])
+		}
+		log.Errorf("error[%v] loki ignored %s entries older than latest entries of their streams , use timestamp now to push them", strings.TrimSpace(string(respBody)), ignored)
+		return nil
+	case response.StatusCode == http.StatusTooManyRequests:
+		log.Errorf("error[%v] in pushing to %s after %d retries", ErrLokiRateLimited, lc.URL, lc.MaxRetries)
+		return ErrLokiRateLimited
+	}
+	log.Errorf("error[%v] in sending data to %s , response: %s", response.Status, lc.URL, respBody)
+	return fmt.Errorf("error in sending data to %s , status: %s", lc.URL, response.Status)
+}
+
+// encodeLokiJSON returns the json push request of streams
+func encodeLokiJSON(streams []lokiStream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	req := struct {
+		Streams []jsonStream `json:"streams"`
+	}{}
+	for _, stream := range streams {
+		js := jsonStream{Stream: stream.labelSet}
+		for _, e := range stream.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.timestamp.UnixNano(), 10), e.line})
+		}
+		req.Streams = append(req.Streams, js)
+	}
+	return json.Marshal(req)
+}
+
+// encodeLokiProtobuf returns the protobuf push request of streams , as defined by logproto.PushRequest of loki
+func encodeLokiProtobuf(streams []lokiStream) []byte {
+	var req []byte
+	for _, stream := range streams {
+		var s []byte
+		s = protowire.AppendTag(s, 1, protowire.BytesType)
+		s = protowire.AppendString(s, stream.labels)
+		for _, e := range stream.entries {
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.timestamp.Unix()))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.timestamp.Nanosecond()))
+
+			var entry []byte
+			entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, ts)
+			entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+			entry = protowire.AppendString(entry, e.line)
+
+			s = protowire.AppendTag(s, 2, protowire.BytesType)
+			s = protowire.AppendBytes(s, entry)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, s)
+	}
+	return req
+}
+
+// init validates config and sets defaults
+func (lc *LokiClient) init(config map[string]string) error {
+	if lc.URL == "" {
+		return ErrMissingLokiURL
+	}
+	if lc.Labels == "" {
+		lc.Labels = defaultLokiLabels
+	}
+	for _, field := range strings.Split(lc.Labels, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			lc.labelFields = append(lc.labelFields, field)
+		}
+	}
+	lc.Encoding = strings.ToLower(lc.Encoding)
+	switch lc.Encoding {
+	case "":
+		lc.Encoding = LokiEncodingProtobuf
+	case LokiEncodingProtobuf, LokiEncodingJSON:
+	default:
+		return ErrLokiEncoding
+	}
+	lc.Timestamp = strings.ToLower(lc.Timestamp)
+	switch lc.Timestamp {
+	case "":
+		lc.Timestamp = LokiTimestampNow
+	case LokiTimestampCreatedAt, LokiTimestampNow:
+	default:
+		return ErrLokiTimestamp
+	}
+	if lc.BatchSize <= 0 {
+		lc.BatchSize = defaultLokiBatchSize
+	}
+	if lc.MaxRetries <= 0 {
+		lc.MaxRetries = defaultLokiMaxRetries
+	}
+	lc.StaticLabels = make(map[string]string)
+	for key, value := range config {
+		if strings.HasPrefix(key, lokiLabelPrefix) {
+			lc.StaticLabels[lokiLabelName(strings.TrimPrefix(key, lokiLabelPrefix))] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeLokiProtobuf returns the labels and timestamps of entries of a protobuf push request
+func decodeLokiProtobuf(t *testing.T, b []byte) map[string][]int64 {
+	fields := func(b []byte) map[protowire.Number][][]byte {
+		parsed := make(map[protowire.Number][][]byte)
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			b = b[n:]
+			var v []byte
+			if typ == protowire.VarintType {
+				x, m := protowire.ConsumeVarint(b)
+				v, n = protowire.AppendVarint(nil, x), m
+			} else {
+				v, n = protowire.ConsumeBytes(b)
+			}
+			if n < 0 {
+				t.Fatalf("invalid protobuf")
+			}
+			parsed[num] = append(parsed[num], v)
+			b = b[n:]
+		}
+		return parsed
+	}
+	streams := make(map[string][]int64)
+	for _, s := range fields(b)[1] {
+		stream := fields(s)
+		labels := string(stream[1][0])
+		for _, e := range stream[2] {
+			ts := fields(fields(e)[1][0])
+			sec, _ := protowire.ConsumeVarint(ts[1][0])
+			var nanos uint64
+			if len(ts[2]) > 0 {
+				nanos, _ = protowire.ConsumeVarint(ts[2][0])
+			}
+			streams[labels] = append(streams[labels], int64(sec)*1e9+int64(nanos))
+		}
+	}
+	return streams
+}
+
+func TestLokiClient_Publish(t *testing.T) {
+	docs := []interface{}{
+		map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "created_at": "2022-10-10T14:00:00Z"},
+		map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "created_at": "2022-10-10T12:00:00.5Z"},
+		map[string]interface{}{"document_type": "issue", "repo_name": "repo1", "created_at": "2022-10-09T12:00:00Z"},
+		map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "created_at": "2022-10-10T13:00:00Z"},
+	}
+	t1 := time.Date(2022, 10, 10, 12, 0, 0, 500000000, time.UTC).UnixNano()
+	t2 := time.Date(2022, 10, 10, 13, 0, 0, 0, time.UTC).UnixNano()
+	t3 := time.Date(2022, 10, 10, 14, 0, 0, 0, time.UTC).UnixNano()
+	t4 := time.Date(2022, 10, 9, 12, 0, 0, 0, time.UTC).UnixNano()
+	commits := `{document_type="commit", env="test", repo_name="repo1"}`
+	issues := `{document_type="issue", env="test", repo_name="repo1"}`
+
+	tests := []struct {
+		name     string
+		config   map[string]string
+		want     []map[string][]int64
+		response func(w http.ResponseWriter)
+		wantErr  bool
+	}{
+		{
+			"protobuf batches",
+			map[string]string{"batch_size": "2", "label.env": "test", "timestamp": "created_at"},
+			[]map[string][]int64{{commits: {t1, t2}}, {commits: {t3}, issues: {t4}}},
+			nil,
+			false,
+		},
+		{
+			"json",
+			map[string]string{"encoding": "json", "label.env": "test", "timestamp": "created_at"},
+			[]map[string][]int64{{commits: {t1, t2, t3}, issues: {t4}}},
+			nil,
+			false,
+		},
+		{
+			"out of order",
+			map[string]string{"label.env": "test", "timestamp": "created_at"},
+			[]map[string][]int64{{commits: {t1, t2, t3}, issues: {t4}}},
+			func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, "entry with timestamp 2022-10-10 12:00:00.5 +0000 UTC ignored, reason: 'entry out of order' for stream: {}, total ignored: 1 out of 4")
+			},
+			false,
+		},
+		{
+			"rate limited",
+			map[string]string{"label.env": "test", "max_retries": "1", "timestamp": "created_at"},
+			[]map[string][]int64{{commits: {t1, t2, t3}, issues: {t4}}, {commits: {t1, t2, t3}, issues: {t4}}},
+			func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []map[string][]int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				if r.Header.Get("Content-Type") == "application/json" {
+					var req struct {
+						Streams []struct {
+							Stream map[string]string `json:"stream"`
+							Values [][2]string       `json:"values"`
+						} `json:"streams"`
+					}
+					json.Unmarshal(b, &req)
+					streams := make(map[string][]int64)
+					for _, s := range req.Streams {
+						labels := fmt.Sprintf(`{document_type=%q, env=%q, repo_name=%q}`, s.Stream["document_type"], s.Stream["env"], s.Stream["repo_name"])
+						for _, v := range s.Values {
+							var ns int64
+							fmt.Sscan(v[0], &ns)
+							streams[labels] = append(streams[labels], ns)
+						}
+					}
+					got = append(got, streams)
+				} else {
+					b, err := snappy.Decode(nil, b)
+					if err != nil {
+						t.Fatalf("snappy.Decode() error = %v", err)
+					}
+					got = append(got, decodeLokiProtobuf(t, b))
+				}
+				if tt.response != nil {
+					tt.response(w)
+				}
+			}))
+			defer server.Close()
+			tt.config["url"] = server.URL
+			lc, err := loadConfigLoki(tt.config)
+			if err != nil {
+				t.Fatalf("loadConfigLoki() error = %v", err)
+			}
+			if err = lc.Publish(docs); (err != nil) != tt.wantErr {
+				t.Errorf("Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Publish() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// entries are pushed at time of publishing by default , so documents published again are not ignored
+	lc, err := loadConfigLoki(map[string]string{"url": "http://loki:3100"})
+	if err != nil || lc.Timestamp != LokiTimestampNow {
+		t.Errorf("loadConfigLoki() timestamp = %v , error = %v, want %v", lc.Timestamp, err, LokiTimestampNow)
+	}
+}
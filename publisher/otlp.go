@@ -0,0 +1,503 @@
+package publisher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	retryhttp "github.com/hashicorp/go-retryablehttp"
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// otlp protocols , as named by OTEL_EXPORTER_OTLP_PROTOCOL
+	OTLPProtocolGRPC         = "grpc"
+	OTLPProtocolHTTPProtobuf = "http/protobuf"
+	OTLPProtocolHTTPJSON     = "http/json"
+
+	// otlp compressions
+	OTLPCompressionNone = "none"
+	OTLPCompressionGzip = "gzip"
+
+	// otlpHeaderPrefix is the prefix of config keys holding headers , for ex. header.api-key
+	otlpHeaderPrefix = "header."
+
+	// otlpResourcePrefix is the prefix of config keys holding resource attributes , for ex. resource.deployment.environment
+	otlpResourcePrefix = "resource."
+
+	otlpLogsPath    = "/v1/logs"
+	otlpMetricsPath = "/v1/metrics"
+	otlpScopeName   = "github-audit"
+	otlpServiceName = "github-audit"
+
+	// otlpMetricPrefix is the prefix of names of metrics , followed by document type and field
+	otlpMetricPrefix = "github_audit."
+
+	defaultOTLPGRPCEndpoint      = "localhost:4317"
+	defaultOTLPHTTPEndpoint      = "http://localhost:4318"
+	defaultOTLPMetricTypes       = "pull_request_cycle_time,dora_metric,contributor_stats,code_frequency,commit_activity,work_pattern,knowledge_distribution,file_hotspot"
+	defaultOTLPMetricAttributes  = "branch,pull_request_no,window,environment,deployment_source,author,team,path,scope"
+	otlpGRPCDefaultServiceConfig = `{"methodConfig":[{"name":[{"service":"opentelemetry.proto.collector.logs.v1.LogsService"},{"service":"opentelemetry.proto.collector.metrics.v1.MetricsService"}],"retryPolicy":{"MaxAttempts":5,"InitialBackoff":"0.5s","MaxBackoff":"5s","BackoffMultiplier":2,"RetryableStatusCodes":["UNAVAILABLE","RESOURCE_EXHAUSTED"]}}]}`
+)
+
+var (
+	ErrOTLPProtocol    = errors.New("otlp protocol must be grpc , http/protobuf or http/json")
+	ErrOTLPCompression = errors.New("otlp compression must be none or gzip")
+
+	// otlpConns holds grpc connections with their config as key , as publishers are created for every run of a task
+	otlpConns      = make(map[string]*grpc.ClientConn)
+	otlpConnsMutex sync.Mutex
+
+	// otlpWarnDocumentTypes are the document types exported with severity WARN , others with INFO
+	otlpWarnDocumentTypes = map[string]bool{
+		"activity_anomaly":     true,
+		"compliance_violation": true,
+		"history_rewrite":      true,
+		"sla_breach":           true,
+	}
+)
+
+// OTLPClient holds config for opentelemetry protocol target , documents are exported as log records and
+// numeric fields of derived documents optionally as gauges
+type OTLPClient struct {
+	// Endpoint is host:port for grpc and base url for http , Default: localhost:4317 or http://localhost:4318
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+
+	// Protocol is grpc , http/protobuf or http/json , Default: http/protobuf
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+
+	// Insecure uses plaintext grpc connection
+	Insecure bool `yaml:"insecure,omitempty" json:"insecure,omitempty,string"`
+
+	// InsecureSkipVerify skips verification of server certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty,string"`
+
+	// Compression is none or gzip , Default: none
+	Compression string `yaml:"compression,omitempty" json:"compression,omitempty"`
+
+	// Metrics exports numeric fields of metric document types as gauges
+	Metrics bool `yaml:"metrics,omitempty" json:"metrics,omitempty,string"`
+
+	// MetricDocumentTypes are comma separated document types exported as metrics
+	MetricDocumentTypes string `yaml:"metric_document_types,omitempty" json:"metric_document_types,omitempty"`
+
+	// MetricAttributes are comma separated document fields used as attributes of data points
+	MetricAttributes string `yaml:"metric_attributes,omitempty" json:"metric_attributes,omitempty"`
+
+	// Headers are sent with requests , given as config keys header.<name>
+	Headers map[string]string `yaml:"-" json:"-"`
+
+	// ResourceAttributes are added to resources , given as config keys resource.<name>
+	ResourceAttributes map[string]string `yaml:"-" json:"-"`
+
+	metricTypes      map[string]bool
+	metricAttributes map[string]bool
+	config           string
+}
+
+// Publish pushes the data to target , log records and metrics are grouped in resources by repository
+func (oc *OTLPClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	docs := documentMaps(data)
+	now := time.Now()
+
+	logsReq := oc.logsRequest(docs, now)
+	var logsResp collectorlogs.ExportLogsServiceResponse
+	if err := oc.export(otlpLogsPath, logsReq, &logsResp); err != nil {
+		return err
+	}
+	if ps := logsResp.GetPartialSuccess(); ps != nil && ps.GetRejectedLogRecords() > 0 {
+		err := fmt.Errorf("%d of %d log records rejected: %s", ps.GetRejectedLogRecords(), len(docs), ps.GetErrorMessage())
+		log.Errorf("error[%v] in sending data to %s", err, oc.Endpoint)
+		return err
+	}
+
+	if oc.Metrics {
+		metricsReq := oc.metricsRequest(docs, now)
+		if len(metricsReq.ResourceMetrics) > 0 {
+			var metricsResp collectormetrics.ExportMetricsServiceResponse
+			if err := oc.export(otlpMetricsPath, metricsReq, &metricsResp); err != nil {
+				return err
+			}
+			if ps := metricsResp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+				err := fmt.Errorf("%d data points rejected: %s", ps.GetRejectedDataPoints(), ps.GetErrorMessage())
+				log.Errorf("error[%v] in sending metrics to %s", err, oc.Endpoint)
+				return err
+			}
+		}
+	}
+	log.Infof("successfully sent %d docs to %s", len(docs), oc.Endpoint)
+	return nil
+}
+
+// logsRequest returns the export request with a log record per document , fields of document other than
+// resource attributes are attributes of log record
+func (oc *OTLPClient) logsRequest(docs []map[string]interface{}, now time.Time) *collectorlogs.ExportLogsServiceRequest {
+	req := new(collectorlogs.ExportLogsServiceRequest)
+	byResource := make(map[string]*logspb.ScopeLogs)
+	for _, doc := range docs {
+		key, resource := oc.resource(doc)
+		scope, ok := byResource[key]
+		if !ok {
+			scope = &logspb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: otlpScopeName}}
+			byResource[key] = scope
+			req.ResourceLogs = append(req.ResourceLogs, &logspb.ResourceLogs{Resource: resource, ScopeLogs: []*logspb.ScopeLogs{scope}})
+		}
+		documentType, _ := doc["document_type"].(string)
+		record := &logspb.LogRecord{
+			TimeUnixNano:         uint64(documentTime(doc, now).UnixNano()),
+			ObservedTimeUnixNano: uint64(now.UnixNano()),
+			SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+			SeverityText:         "INFO",
+			Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: documentType}},
+			Attributes:           otlpAttributes(doc, func(name string) bool { return name != "repo_name" && name != "repo_owner" }),
+		}
+		if otlpWarnDocumentTypes[documentType] {
+			record.SeverityNumber = logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+			record.SeverityText = "WARN"
+		}
+		scope.LogRecords = append(scope.LogRecords, record)
+	}
+	return req
+}
+
+// metricsRequest returns the export request with a gauge per numeric field of metric document types ,
+// named github_audit.<document_type>.<field>
+func (oc *OTLPClient) metricsRequest(docs []map[string]interface{}, now time.Time) *collectormetrics.ExportMetricsServiceRequest {
+	req := new(collectormetrics.ExportMetricsServiceRequest)
+	byResource := make(map[string]*metricspb.ScopeMetrics)
+	metrics := make(map[string]*metricspb.Metric)
+	for _, doc := range docs {
+		documentType, _ := doc["document_type"].(string)
+		if !oc.metricTypes[documentType] {
+			continue
+		}
+		key, resource := oc.resource(doc)
+		scope, ok := byResource[key]
+		if !ok {
+			scope = &metricspb.ScopeMetrics{Scope: &commonpb.InstrumentationScope{Name: otlpScopeName}}
+			byResource[key] = scope
+			req.ResourceMetrics = append(req.ResourceMetrics, &metricspb.ResourceMetrics{Resource: resource, ScopeMetrics: []*metricspb.ScopeMetrics{scope}})
+		}
+		attributes := otlpAttributes(doc, func(name string) bool { return oc.metricAttributes[name] })
+		at := uint64(documentTime(doc, now).UnixNano())
+		fields := make([]string, 0, len(doc))
+		for field := range doc {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			value, ok := doc[field].(float64)
+			if !ok || field == "time" || oc.metricAttributes[field] {
+				continue
+			}
+			name := otlpMetricPrefix + documentType + "." + field
+			metric, ok := metrics[key+"|"+name]
+			if !ok {
+				metric = &metricspb.Metric{Name: name, Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}}
+				if strings.HasSuffix(field, "_seconds") {
+					metric.Unit = "s"
+				}
+				metrics[key+"|"+name] = metric
+				scope.Metrics = append(scope.Metrics, metric)
+			}
+			point := &metricspb.NumberDataPoint{TimeUnixNano: at, Attributes: attributes}
+			if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+				point.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(value)}
+			} else {
+				point.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: value}
+			}
+			gauge := metric.Data.(*metricspb.Metric_Gauge).Gauge
+			gauge.DataPoints = append(gauge.DataPoints, point)
+		}
+	}
+	return req
+}
+
+// resource returns the resource of repository of document and its key
+func (oc *OTLPClient) resource(doc map[string]interface{}) (string, *resourcepb.Resource) {
+	repoName, repoOwner := documentRepository(doc)
+	attributes := map[string]interface{}{"service.name": otlpServiceName}
+	for name, value := range oc.ResourceAttributes {
+		attributes[name] = value
+	}
+	if repoName != "" {
+		attributes["repo_name"] = repoName
+	}
+	if repoOwner != "" {
+		attributes["repo_owner"] = repoOwner
+	}
+	return repoOwner + "/" + repoName, &resourcepb.Resource{Attributes: otlpAttributes(attributes, func(string) bool { return true })}
+}
+
+// otlpAttributes returns the fields of m selected by include as attributes sorted by name , null fields are skipped
+func otlpAttributes(m map[string]interface{}, include func(string) bool) []*commonpb.KeyValue {
+	names := make([]string, 0, len(m))
+	for name, value := range m {
+		if value != nil && include(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	attributes := make([]*commonpb.KeyValue, 0, len(names))
+	for _, name := range names {
+		attributes = append(attributes, &commonpb.KeyValue{Key: name, Value: otlpValue(m[name])})
+	}
+	return attributes
+}
+
+// otlpValue returns the attribute value of a json value , integral numbers are int values
+func otlpValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, 0, len(v))
+		for _, e := range v {
+			values = append(values, otlpValue(e))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]interface{}:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: otlpAttributes(v, func(string) bool { return true })}}}
+	}
+	return &commonpb.AnyValue{}
+}
+
+// documentTime returns created_at of document , now if document has none
+func documentTime(doc map[string]interface{}, now time.Time) time.Time {
+	if createdAt, ok := doc["created_at"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil && !t.IsZero() {
+			return t
+		}
+	}
+	return now
+}
+
+// export sends request to the service at path and decodes response to resp
+func (oc *OTLPClient) export(path string, req proto.Message, resp proto.Message) error {
+	if oc.Protocol == OTLPProtocolGRPC {
+		return oc.exportGRPC(req, resp)
+	}
+	return oc.exportHTTP(path, req, resp)
+}
+
+// exportGRPC sends request over a grpc connection shared by runs of tasks , unavailable and exhausted
+// responses are retried by the retry policy of connection
+func (oc *OTLPClient) exportGRPC(req proto.Message, resp proto.Message) error {
+	conn, err := oc.getConn()
+	if err != nil {
+		log.Errorf("error[%v] in connecting to %s", err, oc.Endpoint)
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for name, value := range oc.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(name), value)
+	}
+	var opts []grpc.CallOption
+	if oc.Compression == OTLPCompressionGzip {
+		opts = append(opts, grpc.UseCompressor(grpcgzip.Name))
+	}
+	switch r := req.(type) {
+	case *collectorlogs.ExportLogsServiceRequest:
+		var got *collectorlogs.ExportLogsServiceResponse
+		got, err = collectorlogs.NewLogsServiceClient(conn).Export(ctx, r, opts...)
+		if err == nil {
+			proto.Merge(resp, got)
+		}
+	case *collectormetrics.ExportMetricsServiceRequest:
+		var got *collectormetrics.ExportMetricsServiceResponse
+		got, err = collectormetrics.NewMetricsServiceClient(conn).Export(ctx, r, opts...)
+		if err == nil {
+			proto.Merge(resp, got)
+		}
+	}
+	if err != nil {
+		log.Errorf("error[%v] in sending data to %s", err, oc.Endpoint)
+	}
+	return err
+}
+
+// getConn returns the grpc connection of config , creating it for first use
+func (oc *OTLPClient) getConn() (*grpc.ClientConn, error) {
+	otlpConnsMutex.Lock()
+	defer otlpConnsMutex.Unlock()
+	if conn, ok := otlpConns[oc.config]; ok {
+		return conn, nil
+	}
+	creds := credentials.NewTLS(&tls.Config{InsecureSkipVerify: oc.InsecureSkipVerify})
+	if oc.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.Dial(oc.Endpoint, grpc.WithTransportCredentials(creds), grpc.WithDefaultServiceConfig(otlpGRPCDefaultServiceConfig))
+	if err != nil {
+		return nil, err
+	}
+	otlpConns[oc.config] = conn
+	return conn, nil
+}
+
+// exportHTTP posts request as protobuf or json to endpoint with path
+func (oc *OTLPClient) exportHTTP(path string, req proto.Message, resp proto.Message) error {
+	var (
+		body        []byte
+		err         error
+		contentType = "application/x-protobuf"
+	)
+	if oc.Protocol == OTLPProtocolHTTPJSON {
+		contentType = "application/json"
+		body, err = protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(req)
+	} else {
+		body, err = proto.Marshal(req)
+	}
+	if err != nil {
+		log.Errorf("error[%v] unable to marshal export request", err)
+		return err
+	}
+	if oc.Compression == OTLPCompressionGzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		zw.Close()
+		body = buf.Bytes()
+	}
+
+	client := HTTPClientWithRetry()
+	if oc.InsecureSkipVerify {
+		client.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Proxy: http.ProxyFromEnvironment,
+		}
+	}
+	request, err := retryhttp.NewRequest(http.MethodPost, strings.TrimSuffix(oc.Endpoint, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	if oc.Compression == OTLPCompressionGzip {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	for name, value := range oc.Headers {
+		request.Header.Set(name, value)
+	}
+
+	timeout, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	response, err := client.Do(request.WithContext(timeout))
+	if err != nil {
+		log.Errorf("error[%v] request failed", err)
+		return err
+	}
+	defer response.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		log.Errorf("error[%v] in sending data to %s , response: %s", response.Status, oc.Endpoint, respBody)
+		return fmt.Errorf("error in sending data to %s , status: %s", oc.Endpoint, response.Status)
+	}
+	if len(respBody) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(response.Header.Get("Content-Type"), "application/json") {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(respBody, resp)
+	} else {
+		err = proto.Unmarshal(respBody, resp)
+	}
+	if err != nil {
+		log.Errorf("error[%v] failed to decode response of %s", err, oc.Endpoint)
+	}
+	return nil
+}
+
+// init validates config and sets defaults
+func (oc *OTLPClient) init(config map[string]string) error {
+	oc.Protocol = strings.ToLower(oc.Protocol)
+	switch oc.Protocol {
+	case "":
+		oc.Protocol = OTLPProtocolHTTPProtobuf
+	case OTLPProtocolGRPC, OTLPProtocolHTTPProtobuf, OTLPProtocolHTTPJSON:
+	default:
+		return ErrOTLPProtocol
+	}
+	if oc.Endpoint == "" {
+		oc.Endpoint = defaultOTLPHTTPEndpoint
+		if oc.Protocol == OTLPProtocolGRPC {
+			oc.Endpoint = defaultOTLPGRPCEndpoint
+		}
+	}
+	oc.Compression = strings.ToLower(oc.Compression)
+	switch oc.Compression {
+	case "":
+		oc.Compression = OTLPCompressionNone
+	case OTLPCompressionNone, OTLPCompressionGzip:
+	default:
+		return ErrOTLPCompression
+	}
+	if oc.MetricDocumentTypes == "" {
+		oc.MetricDocumentTypes = defaultOTLPMetricTypes
+	}
+	if oc.MetricAttributes == "" {
+		oc.MetricAttributes = defaultOTLPMetricAttributes
+	}
+	oc.metricTypes = splitSet(oc.MetricDocumentTypes)
+	oc.metricAttributes = splitSet(oc.MetricAttributes)
+	oc.Headers = make(map[string]string)
+	oc.ResourceAttributes = make(map[string]string)
+	for key, value := range config {
+		if strings.HasPrefix(key, otlpHeaderPrefix) {
+			oc.Headers[strings.TrimPrefix(key, otlpHeaderPrefix)] = value
+		} else if strings.HasPrefix(key, otlpResourcePrefix) {
+			oc.ResourceAttributes[strings.TrimPrefix(key, otlpResourcePrefix)] = value
+		}
+	}
+	b, _ := json.Marshal(config)
+	oc.config = string(b)
+	return nil
+}
+
+// splitSet returns the set of comma separated values
+func splitSet(values string) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range strings.Split(values, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,153 @@
+package publisher
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var otlpTestDocs = []interface{}{
+	map[string]interface{}{"document_type": "pull_request_cycle_time", "repo_name": "repo1", "repo_url": "https://github.com/owner1/repo1", "pull_request_no": "7", "created_at": "2022-10-10T12:00:00Z", "cycle_time_seconds": 3600, "review_rounds": 2, "time": 1665403200000},
+	map[string]interface{}{"document_type": "sla_breach", "repo_name": "repo1", "repo_url": "https://github.com/owner1/repo1", "labels": []string{"bug"}},
+	map[string]interface{}{"document_type": "commit", "repo_name": "repo2", "repo_owner": "owner2", "created_by": map[string]interface{}{"user": "dev1"}},
+}
+
+// attributes returns attributes as a map of their values
+func attributes(kvs []*commonpb.KeyValue) map[string]*commonpb.AnyValue {
+	m := make(map[string]*commonpb.AnyValue)
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+// checkLogs verifies the export request of otlpTestDocs
+func checkLogs(t *testing.T, req *collectorlogs.ExportLogsServiceRequest) {
+	if len(req.ResourceLogs) != 2 {
+		t.Fatalf("Publish() resources = %v, want 2", len(req.ResourceLogs))
+	}
+	resource := attributes(req.ResourceLogs[0].Resource.Attributes)
+	if resource["repo_name"].GetStringValue() != "repo1" || resource["repo_owner"].GetStringValue() != "owner1" || resource["service.name"].GetStringValue() != "github-audit" || resource["deployment.environment"].GetStringValue() != "test" {
+		t.Errorf("Publish() resource = %v", resource)
+	}
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatalf("Publish() log records = %v, want 2", len(records))
+	}
+	if records[0].TimeUnixNano != uint64(time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC).UnixNano()) || records[0].SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_INFO || records[0].Body.GetStringValue() != "pull_request_cycle_time" {
+		t.Errorf("Publish() log record = %v", records[0])
+	}
+	attrs := attributes(records[0].Attributes)
+	if _, ok := attrs["repo_name"]; ok || attrs["cycle_time_seconds"].GetIntValue() != 3600 || attrs["pull_request_no"].GetStringValue() != "7" {
+		t.Errorf("Publish() attributes = %v", attrs)
+	}
+	if records[1].SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_WARN || attributes(records[1].Attributes)["labels"].GetArrayValue().GetValues()[0].GetStringValue() != "bug" {
+		t.Errorf("Publish() log record = %v", records[1])
+	}
+	resource = attributes(req.ResourceLogs[1].Resource.Attributes)
+	user := req.ResourceLogs[1].ScopeLogs[0].LogRecords[0].Attributes[0].Value.GetKvlistValue().GetValues()[0]
+	if resource["repo_owner"].GetStringValue() != "owner2" || user.Key != "user" || user.Value.GetStringValue() != "dev1" {
+		t.Errorf("Publish() resource = %v user = %v", resource, user)
+	}
+}
+
+func TestOTLPClient_PublishHTTP(t *testing.T) {
+	for _, protocol := range []string{OTLPProtocolHTTPProtobuf, OTLPProtocolHTTPJSON} {
+		t.Run(protocol, func(t *testing.T) {
+			var logsReq collectorlogs.ExportLogsServiceRequest
+			var metricsReq collectormetrics.ExportMetricsServiceRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("api-key") != "xxxx" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				b, _ := ioutil.ReadAll(r.Body)
+				var m proto.Message = &logsReq
+				if r.URL.Path == otlpMetricsPath {
+					m = &metricsReq
+				}
+				var err error
+				if r.Header.Get("Content-Type") == "application/json" {
+					err = protojson.Unmarshal(b, m)
+				} else {
+					err = proto.Unmarshal(b, m)
+				}
+				if err != nil {
+					t.Errorf("Unmarshal() error = %v", err)
+				}
+			}))
+			defer server.Close()
+			oc, err := loadConfigOTLP(map[string]string{"endpoint": server.URL, "protocol": protocol, "metrics": "true", "header.api-key": "xxxx", "resource.deployment.environment": "test"})
+			if err != nil {
+				t.Fatalf("loadConfigOTLP() error = %v", err)
+			}
+			if err = oc.Publish(otlpTestDocs); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			checkLogs(t, &logsReq)
+
+			if len(metricsReq.ResourceMetrics) != 1 {
+				t.Fatalf("Publish() metric resources = %v, want 1", len(metricsReq.ResourceMetrics))
+			}
+			metrics := metricsReq.ResourceMetrics[0].ScopeMetrics[0].Metrics
+			if len(metrics) != 2 || metrics[0].Name != "github_audit.pull_request_cycle_time.cycle_time_seconds" || metrics[0].Unit != "s" || metrics[1].Name != "github_audit.pull_request_cycle_time.review_rounds" {
+				t.Fatalf("Publish() metrics = %v", metrics)
+			}
+			point := metrics[0].GetGauge().DataPoints[0]
+			if point.GetAsInt() != 3600 || len(point.Attributes) != 1 || point.Attributes[0].Key != "pull_request_no" {
+				t.Errorf("Publish() data point = %v", point)
+			}
+		})
+	}
+}
+
+// testLogsServer records export requests of logs
+type testLogsServer struct {
+	collectorlogs.UnimplementedLogsServiceServer
+	req    *collectorlogs.ExportLogsServiceRequest
+	apiKey []string
+}
+
+func (s *testLogsServer) Export(ctx context.Context, req *collectorlogs.ExportLogsServiceRequest) (*collectorlogs.ExportLogsServiceResponse, error) {
+	s.req = req
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.apiKey = md.Get("api-key")
+	return &collectorlogs.ExportLogsServiceResponse{}, nil
+}
+
+func TestOTLPClient_PublishGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	logsServer := new(testLogsServer)
+	server := grpc.NewServer()
+	collectorlogs.RegisterLogsServiceServer(server, logsServer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	oc, err := loadConfigOTLP(map[string]string{"endpoint": lis.Addr().String(), "protocol": "grpc", "insecure": "true", "compression": "gzip", "header.api-key": "xxxx", "resource.deployment.environment": "test"})
+	if err != nil {
+		t.Fatalf("loadConfigOTLP() error = %v", err)
+	}
+	if err = oc.Publish(otlpTestDocs); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(logsServer.apiKey) != 1 || logsServer.apiKey[0] != "xxxx" {
+		t.Errorf("Publish() api-key = %v", logsServer.apiKey)
+	}
+	checkLogs(t, logsServer.req)
+}
@@ -0,0 +1,329 @@
+package publisher
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// prometheus labels which can be dropped to limit cardinality
+	PrometheusLabelRepo   = "repo"
+	PrometheusLabelOwner  = "owner"
+	PrometheusLabelBranch = "branch"
+
+	// prometheusOtherBranch is the branch label of branches beyond max branches of a repository
+	prometheusOtherBranch = "other"
+
+	defaultPrometheusListenAddress = ":9464"
+	defaultPrometheusPath          = "/metrics"
+	defaultPrometheusLabels        = "repo,owner,branch"
+	defaultPrometheusMaxBranches   = 20
+)
+
+var (
+	ErrPrometheusLabel = errors.New("prometheus labels must be repo , owner or branch")
+
+	// prometheusExporters holds exporters with their listen address as key , as publishers are created for every
+	// run of a task while metrics are kept and served by exporters
+	prometheusExporters      = make(map[string]*prometheusExporter)
+	prometheusExportersMutex sync.Mutex
+
+	// prometheusCycleTimeStages are the fields of cycle time documents observed by stage
+	prometheusCycleTimeStages = map[string]string{
+		"coding_time_seconds": "coding",
+		"pickup_time_seconds": "pickup",
+		"review_time_seconds": "review",
+		"merge_time_seconds":  "merge",
+		"cycle_time_seconds":  "cycle",
+	}
+)
+
+// PrometheusClient holds config for prometheus target , nothing is pushed , metrics derived from documents
+// are served for scraping
+type PrometheusClient struct {
+	// ListenAddress of metrics endpoint , Default: :9464
+	ListenAddress string `yaml:"listen_address,omitempty" json:"listen_address,omitempty"`
+
+	// Path of metrics endpoint , Default: /metrics
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	// Labels are comma separated labels of metrics among repo , owner and branch , Default: repo,owner,branch
+	Labels string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// MaxBranches is the number of branches of a repository with own branch label , others are labelled other , Default: 20
+	MaxBranches int `yaml:"max_branches,omitempty" json:"max_branches,omitempty,string"`
+
+	exporter *prometheusExporter
+}
+
+// prometheusExporter keeps metrics of documents of all tasks publishing to a listen address
+type prometheusExporter struct {
+	mutex       sync.Mutex
+	registry    *prometheus.Registry
+	listener    net.Listener
+	labels      map[string]bool
+	maxBranches int
+
+	documents            *prometheus.CounterVec
+	commits              *prometheus.CounterVec
+	workflowRuns         *prometheus.CounterVec
+	workflowRunDurations *prometheus.HistogramVec
+	pullRequests         *prometheus.GaugeVec
+	issues               *prometheus.GaugeVec
+	cycleTimes           *prometheus.HistogramVec
+	lastPublished        *prometheus.GaugeVec
+
+	// pullRequestCounts and issueCounts hold the latest item counts by repository , each item count document
+	// set of a repository is a full count and replaces the previous one of the repository.
+	pullRequestCounts map[string][]prometheusCount
+	issueCounts       map[string][]prometheusCount
+
+	// branches holds the branches with own branch label by repository
+	branches map[string]map[string]bool
+}
+
+// prometheusCount is a count with the label values of its gauge
+type prometheusCount struct {
+	labels []string
+	count  float64
+}
+
+// Publish updates metrics with documents
+func (pc *PrometheusClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	pc.exporter.update(documentMaps(data), time.Now())
+	log.Debugf("updated prometheus metrics with %d docs", len(data))
+	return nil
+}
+
+// newPrometheusExporter returns an exporter with metrics having labels among repo , owner and branch
+func newPrometheusExporter(labels map[string]bool, maxBranches int) *prometheusExporter {
+	e := new(prometheusExporter)
+	e.labels = labels
+	e.maxBranches = maxBranches
+	e.registry = prometheus.NewRegistry()
+	e.registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	e.documents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "github_audit_documents_total",
+		Help: "Number of documents published by document type.",
+	}, e.labelNames(false, "document_type"))
+	e.commits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "github_audit_commits_total",
+		Help: "Number of commits to monitored branches.",
+	}, e.labelNames(true))
+	e.workflowRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "github_audit_workflow_runs_total",
+		Help: "Number of completed github actions workflow runs by conclusion.",
+	}, e.labelNames(false, "conclusion"))
+	e.workflowRunDurations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "github_audit_workflow_run_duration_seconds",
+		Help:    "Duration of completed github actions workflow runs by conclusion.",
+		Buckets: prometheus.ExponentialBuckets(10, 3, 10),
+	}, e.labelNames(false, "conclusion"))
+	e.pullRequests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "github_audit_open_pull_requests",
+		Help: "Number of open pull requests by base branch , as of the last item count of the repository.",
+	}, e.labelNames(true))
+	e.issues = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "github_audit_issues",
+		Help: "Number of issues by state , as of the last item count of the repository.",
+	}, e.labelNames(false, "state"))
+	e.cycleTimes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "github_audit_pull_request_cycle_time_seconds",
+		Help:    "Cycle time of merged pull requests by stage , coding , pickup , review , merge or cycle.",
+		Buckets: prometheus.ExponentialBuckets(60, 4, 10),
+	}, e.labelNames(true, "stage"))
+	e.lastPublished = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "github_audit_last_publish_timestamp_seconds",
+		Help: "Time of last documents published.",
+	}, e.labelNames(false))
+	e.registry.MustRegister(e.documents, e.commits, e.workflowRuns, e.workflowRunDurations, e.pullRequests, e.issues, e.cycleTimes, e.lastPublished)
+	e.pullRequestCounts = make(map[string][]prometheusCount)
+	e.issueCounts = make(map[string][]prometheusCount)
+	e.branches = make(map[string]map[string]bool)
+	return e
+}
+
+// labelNames returns the enabled labels among repo , owner and branch followed by extra labels
+func (e *prometheusExporter) labelNames(branch bool, extra ...string) []string {
+	var names []string
+	for _, name := range []string{PrometheusLabelRepo, PrometheusLabelOwner} {
+		if e.labels[name] {
+			names = append(names, name)
+		}
+	}
+	if branch && e.labels[PrometheusLabelBranch] {
+		names = append(names, PrometheusLabelBranch)
+	}
+	return append(names, extra...)
+}
+
+// labelValues returns the values of enabled labels followed by extra values , branches beyond max branches
+// of a repository are labelled other
+func (e *prometheusExporter) labelValues(repoName string, repoOwner string, branch *string, extra ...string) []string {
+	var values []string
+	if e.labels[PrometheusLabelRepo] {
+		values = append(values, repoName)
+	}
+	if e.labels[PrometheusLabelOwner] {
+		values = append(values, repoOwner)
+	}
+	if branch != nil && e.labels[PrometheusLabelBranch] {
+		repo := repoOwner + "/" + repoName
+		known, ok := e.branches[repo]
+		if !ok {
+			known = make(map[string]bool)
+			e.branches[repo] = known
+		}
+		b := *branch
+		if !known[b] {
+			if len(known) < e.maxBranches {
+				known[b] = true
+			} else {
+				b = prometheusOtherBranch
+			}
+		}
+		values = append(values, b)
+	}
+	return append(values, extra...)
+}
+
+// update updates metrics with documents
+func (e *prometheusExporter) update(docs []map[string]interface{}, now time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	// item counts of documents by repository , replacing the previous counts of these repositories
+	pullRequestCounts := make(map[string][]prometheusCount)
+	issueCounts := make(map[string][]prometheusCount)
+	for _, doc := range docs {
+		repoName, repoOwner := documentRepository(doc)
+		documentType, _ := doc["document_type"].(string)
+		e.documents.WithLabelValues(e.labelValues(repoName, repoOwner, nil, documentType)...).Inc()
+		e.lastPublished.WithLabelValues(e.labelValues(repoName, repoOwner, nil)...).Set(float64(now.Unix()))
+		switch documentType {
+		case "commit":
+			branch, _ := doc["branch"].(string)
+			e.commits.WithLabelValues(e.labelValues(repoName, repoOwner, &branch)...).Inc()
+		case "workflow_run":
+			conclusion, _ := doc["conclusion"].(string)
+			e.workflowRuns.WithLabelValues(e.labelValues(repoName, repoOwner, nil, conclusion)...).Inc()
+			if seconds, ok := doc["duration_seconds"].(float64); ok && seconds > 0 {
+				e.workflowRunDurations.WithLabelValues(e.labelValues(repoName, repoOwner, nil, conclusion)...).Observe(seconds)
+			}
+		case "item_count":
+			repo := repoOwner + "/" + repoName
+			if _, ok := pullRequestCounts[repo]; !ok {
+				pullRequestCounts[repo] = nil
+				issueCounts[repo] = nil
+			}
+			count, _ := doc["count"].(float64)
+			itemType, _ := doc["item_type"].(string)
+			state, _ := doc["state"].(string)
+			switch {
+			case itemType == "pull_request" && state == "open":
+				branch, _ := doc["branch"].(string)
+				pullRequestCounts[repo] = append(pullRequestCounts[repo], prometheusCount{e.labelValues(repoName, repoOwner, &branch), count})
+			case itemType == "issue":
+				issueCounts[repo] = append(issueCounts[repo], prometheusCount{e.labelValues(repoName, repoOwner, nil, state), count})
+			}
+		case "pull_request_cycle_time":
+			branch, _ := doc["branch"].(string)
+			for field, stage := range prometheusCycleTimeStages {
+				if seconds, ok := doc[field].(float64); ok && seconds > 0 {
+					e.cycleTimes.WithLabelValues(e.labelValues(repoName, repoOwner, &branch, stage)...).Observe(seconds)
+				}
+			}
+		}
+	}
+	if len(pullRequestCounts) > 0 {
+		setCounts(e.pullRequests, e.pullRequestCounts, pullRequestCounts)
+		setCounts(e.issues, e.issueCounts, issueCounts)
+	}
+}
+
+// setCounts replaces the counts of repositories in latest and sets gauges to the sum of counts having their labels ,
+// counts of repositories are summed when repo or owner labels are dropped
+func setCounts(gauges *prometheus.GaugeVec, latest map[string][]prometheusCount, counts map[string][]prometheusCount) {
+	for repo, repoCounts := range counts {
+		latest[repo] = repoCounts
+	}
+	gauges.Reset()
+	for _, repoCounts := range latest {
+		for _, c := range repoCounts {
+			gauges.WithLabelValues(c.labels...).Add(c.count)
+		}
+	}
+}
+
+// getExporter returns the exporter of listen address , starting its metrics endpoint for first use.
+// Exporters are shared by targets with the same listen address , with labels of the first one.
+func (pc *PrometheusClient) getExporter() (*prometheusExporter, error) {
+	prometheusExportersMutex.Lock()
+	defer prometheusExportersMutex.Unlock()
+	if e, ok := prometheusExporters[pc.ListenAddress]; ok {
+		return e, nil
+	}
+	labels := make(map[string]bool)
+	for _, label := range strings.Split(pc.Labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels[label] = true
+		}
+	}
+	e := newPrometheusExporter(labels, pc.MaxBranches)
+	listener, err := net.Listen("tcp", pc.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	e.listener = listener
+	mux := http.NewServeMux()
+	mux.Handle(pc.Path, promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{}))
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Errorf("error[%v] in serving prometheus metrics on %s", err, pc.ListenAddress)
+		}
+	}()
+	log.Infof("serving prometheus metrics on %s%s", listener.Addr(), pc.Path)
+	prometheusExporters[pc.ListenAddress] = e
+	return e, nil
+}
+
+// init validates config , sets defaults and gets exporter
+func (pc *PrometheusClient) init() error {
+	if pc.ListenAddress == "" {
+		pc.ListenAddress = defaultPrometheusListenAddress
+	}
+	if pc.Path == "" {
+		pc.Path = defaultPrometheusPath
+	}
+	if pc.Labels == "" {
+		pc.Labels = defaultPrometheusLabels
+	}
+	if pc.Labels != "none" {
+		for _, label := range strings.Split(pc.Labels, ",") {
+			switch strings.TrimSpace(label) {
+			case PrometheusLabelRepo, PrometheusLabelOwner, PrometheusLabelBranch:
+			default:
+				return ErrPrometheusLabel
+			}
+		}
+	}
+	if pc.MaxBranches <= 0 {
+		pc.MaxBranches = defaultPrometheusMaxBranches
+	}
+	exporter, err := pc.getExporter()
+	if err != nil {
+		return err
+	}
+	pc.exporter = exporter
+	return nil
+}
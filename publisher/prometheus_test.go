@@ -0,0 +1,97 @@
+package publisher
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusClient_Publish(t *testing.T) {
+	pc, err := loadConfigPrometheus(map[string]string{"listen_address": "127.0.0.1:0", "max_branches": "2"})
+	if err != nil {
+		t.Fatalf("loadConfigPrometheus() error = %v", err)
+	}
+	commit := func(branch string) map[string]interface{} {
+		return map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "repo_url": "https://github.com/owner1/repo1", "branch": branch}
+	}
+	itemCount := func(itemType string, state string, branch string, count int) map[string]interface{} {
+		return map[string]interface{}{"document_type": "item_count", "repo_name": "repo1", "repo_url": "https://github.com/owner1/repo1",
+			"item_type": itemType, "state": state, "branch": branch, "count": count}
+	}
+	runs := [][]interface{}{
+		{
+			commit("main"), commit("main"), commit("dev"), commit("feature"),
+			itemCount("pull_request", "open", "main", 2), itemCount("pull_request", "open", "dev", 1),
+			itemCount("issue", "open", "", 2), itemCount("issue", "closed", "", 5),
+			map[string]interface{}{"document_type": "pull_request_cycle_time", "repo_name": "repo1", "repo_url": "https://github.com/owner1/repo1", "branch": "main", "cycle_time_seconds": 7200, "pickup_time_seconds": 0},
+		},
+		{
+			// a later count replaces the previous one , dev has no open pull requests anymore
+			itemCount("pull_request", "open", "main", 1),
+			itemCount("issue", "open", "", 1), itemCount("issue", "closed", "", 6),
+			map[string]interface{}{"document_type": "workflow_run", "repo_name": "repo1", "repo_url": "https://github.com/owner1/repo1", "conclusion": "success", "duration_seconds": 95},
+			map[string]interface{}{"document_type": "workflow_run", "repo_name": "repo1", "repo_url": "https://github.com/owner1/repo1", "conclusion": "failure", "duration_seconds": 0},
+		},
+	}
+	for _, docs := range runs {
+		if err = pc.Publish(docs); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	// publishers of later runs share the exporter of listen address
+	again, err := loadConfigPrometheus(map[string]string{"listen_address": "127.0.0.1:0", "max_branches": "2"})
+	if err != nil || again.exporter != pc.exporter {
+		t.Fatalf("loadConfigPrometheus() error = %v , want same exporter", err)
+	}
+
+	resp, err := http.Get("http://" + pc.exporter.listener.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	metrics := string(b)
+	for _, want := range []string{
+		`github_audit_commits_total{branch="main",owner="owner1",repo="repo1"} 2`,
+		`github_audit_commits_total{branch="dev",owner="owner1",repo="repo1"} 1`,
+		`github_audit_commits_total{branch="other",owner="owner1",repo="repo1"} 1`,
+		`github_audit_open_pull_requests{branch="main",owner="owner1",repo="repo1"} 1`,
+		`github_audit_issues{owner="owner1",repo="repo1",state="open"} 1`,
+		`github_audit_issues{owner="owner1",repo="repo1",state="closed"} 6`,
+		`github_audit_workflow_runs_total{conclusion="success",owner="owner1",repo="repo1"} 1`,
+		`github_audit_workflow_runs_total{conclusion="failure",owner="owner1",repo="repo1"} 1`,
+		`github_audit_workflow_run_duration_seconds_count{conclusion="success",owner="owner1",repo="repo1"} 1`,
+		`github_audit_workflow_run_duration_seconds_sum{conclusion="success",owner="owner1",repo="repo1"} 95`,
+		`github_audit_pull_request_cycle_time_seconds_count{branch="main",owner="owner1",repo="repo1",stage="cycle"} 1`,
+		`github_audit_documents_total{document_type="commit",owner="owner1",repo="repo1"} 4`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics do not have %v", want)
+		}
+	}
+	if strings.Contains(metrics, `stage="pickup"`) {
+		t.Errorf("metrics have cycle time of stage without duration")
+	}
+	if strings.Contains(metrics, `github_audit_open_pull_requests{branch="dev"`) {
+		t.Errorf("metrics have open pull requests of previous count")
+	}
+	if strings.Contains(metrics, `github_audit_workflow_run_duration_seconds_count{conclusion="failure"`) {
+		t.Errorf("metrics have workflow run duration of run without duration")
+	}
+}
+
+func TestPrometheusExporter_labels(t *testing.T) {
+	e := newPrometheusExporter(map[string]bool{PrometheusLabelRepo: true}, 20)
+	branch := "main"
+	if names := e.labelNames(true, "state"); strings.Join(names, ",") != "repo,state" {
+		t.Errorf("labelNames() = %v, want repo,state", names)
+	}
+	if values := e.labelValues("repo1", "owner1", &branch, "open"); strings.Join(values, ",") != "repo1,open" {
+		t.Errorf("labelValues() = %v, want repo1,open", values)
+	}
+	if _, err := loadConfigPrometheus(map[string]string{"labels": "repo,author"}); err != ErrPrometheusLabel {
+		t.Errorf("loadConfigPrometheus() error = %v, want %v", err, ErrPrometheusLabel)
+	}
+}
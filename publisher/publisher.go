@@ -6,6 +6,7 @@ package publisher
 import (
 	"encoding/json"
 	"errors"
+	"net/url"
 	"strings"
 	"time"
 
@@ -26,7 +27,17 @@ func init() {
 //Add various client constant here
 const (
 	KAFKAREST     = "kafka-rest"
+	KAFKA         = "kafka"
 	ELASTICSEARCH = "elasticsearch"
+	HTTP          = "http"
+	SPLUNKHEC     = "splunk-hec"
+	LOKI          = "loki"
+	OTLP          = "otlp"
+	PROMETHEUS    = "prometheus"
+	INFLUXDB      = "influxdb"
+	FILE          = "file"
+	S3            = "s3"
+	CLICKHOUSE    = "clickhouse"
 )
 
 // Publisher is implemented by any client that has Publish method.
@@ -39,8 +50,28 @@ func NewPublisher(pubType string, config map[string]string) (Publisher, error) {
 	switch strings.ToLower(pubType) {
 	case KAFKAREST:
 		return loadConfigKafkaRest(config)
+	case KAFKA:
+		return loadConfigKafka(config)
 	case ELASTICSEARCH:
 		return loadConfigElasticSearch(config)
+	case HTTP:
+		return loadConfigHTTP(config)
+	case SPLUNKHEC:
+		return loadConfigSplunkHEC(config)
+	case LOKI:
+		return loadConfigLoki(config)
+	case OTLP:
+		return loadConfigOTLP(config)
+	case PROMETHEUS:
+		return loadConfigPrometheus(config)
+	case INFLUXDB:
+		return loadConfigInfluxDB(config)
+	case FILE:
+		return loadConfigFile(config)
+	case S3:
+		return loadConfigS3(config)
+	case CLICKHOUSE:
+		return loadConfigClickHouse(config)
 	default:
 		return nil, ErrUnknownPubType
 	}
@@ -66,6 +97,28 @@ func loadConfigKafkaRest(config map[string]string) (*KafkaRestClient, error) {
 	return &kafkaRest, nil
 }
 
+// loadConfigKafka loads config to Kafka and return pointer to Kafka
+func loadConfigKafka(config map[string]string) (*KafkaClient, error) {
+	var (
+		kafka KafkaClient
+		err   error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling kafka config", err)
+		return &kafka, err
+	}
+	err = json.Unmarshal(cfgByte, &kafka)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling kafka config", err)
+		return &kafka, err
+	}
+	if kafka.Brokers == "" || kafka.Topic == "" {
+		return &kafka, ErrMissingKafkaBrokers
+	}
+	return &kafka, nil
+}
+
 // loadConfigElasticSearch loads config to ElasticSearch and return pointer to ElasticSearch
 func loadConfigElasticSearch(config map[string]string) (*ElasticSearchClient, error) {
 	var (
@@ -85,6 +138,222 @@ func loadConfigElasticSearch(config map[string]string) (*ElasticSearchClient, er
 	return &es, nil
 }
 
+// loadConfigHTTP loads config to HTTP and return pointer to HTTP
+func loadConfigHTTP(config map[string]string) (*HTTPClient, error) {
+	var (
+		hc  HTTPClient
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling http config", err)
+		return &hc, err
+	}
+	err = json.Unmarshal(cfgByte, &hc)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling http config", err)
+		return &hc, err
+	}
+	err = hc.init(config)
+	if err != nil {
+		log.Errorf("error[%v] in http config", err)
+		return &hc, err
+	}
+	return &hc, nil
+}
+
+// loadConfigSplunkHEC loads config to SplunkHEC and return pointer to SplunkHEC
+func loadConfigSplunkHEC(config map[string]string) (*SplunkHECClient, error) {
+	var (
+		sc  SplunkHECClient
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling splunk hec config", err)
+		return &sc, err
+	}
+	err = json.Unmarshal(cfgByte, &sc)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling splunk hec config", err)
+		return &sc, err
+	}
+	err = sc.init()
+	if err != nil {
+		log.Errorf("error[%v] in splunk hec config", err)
+		return &sc, err
+	}
+	return &sc, nil
+}
+
+// loadConfigLoki loads config to Loki and return pointer to Loki
+func loadConfigLoki(config map[string]string) (*LokiClient, error) {
+	var (
+		lc  LokiClient
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling loki config", err)
+		return &lc, err
+	}
+	err = json.Unmarshal(cfgByte, &lc)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling loki config", err)
+		return &lc, err
+	}
+	err = lc.init(config)
+	if err != nil {
+		log.Errorf("error[%v] in loki config", err)
+		return &lc, err
+	}
+	return &lc, nil
+}
+
+// loadConfigOTLP loads config to OTLP and return pointer to OTLP
+func loadConfigOTLP(config map[string]string) (*OTLPClient, error) {
+	var (
+		oc  OTLPClient
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling otlp config", err)
+		return &oc, err
+	}
+	err = json.Unmarshal(cfgByte, &oc)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling otlp config", err)
+		return &oc, err
+	}
+	err = oc.init(config)
+	if err != nil {
+		log.Errorf("error[%v] in otlp config", err)
+		return &oc, err
+	}
+	return &oc, nil
+}
+
+// loadConfigPrometheus loads config to Prometheus and return pointer to Prometheus
+func loadConfigPrometheus(config map[string]string) (*PrometheusClient, error) {
+	var (
+		pc  PrometheusClient
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling prometheus config", err)
+		return &pc, err
+	}
+	err = json.Unmarshal(cfgByte, &pc)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling prometheus config", err)
+		return &pc, err
+	}
+	err = pc.init()
+	if err != nil {
+		log.Errorf("error[%v] in prometheus config", err)
+		return &pc, err
+	}
+	return &pc, nil
+}
+
+// loadConfigInfluxDB loads config to InfluxDB and return pointer to InfluxDB
+func loadConfigInfluxDB(config map[string]string) (*InfluxDBClient, error) {
+	var (
+		ic  InfluxDBClient
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling influxdb config", err)
+		return &ic, err
+	}
+	err = json.Unmarshal(cfgByte, &ic)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling influxdb config", err)
+		return &ic, err
+	}
+	err = ic.init(config)
+	if err != nil {
+		log.Errorf("error[%v] in influxdb config", err)
+		return &ic, err
+	}
+	return &ic, nil
+}
+
+// loadConfigFile loads config to File and return pointer to File
+func loadConfigFile(config map[string]string) (*FileClient, error) {
+	var (
+		fc  FileClient
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling file config", err)
+		return &fc, err
+	}
+	err = json.Unmarshal(cfgByte, &fc)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling file config", err)
+		return &fc, err
+	}
+	err = fc.init()
+	if err != nil {
+		log.Errorf("error[%v] in file config", err)
+		return &fc, err
+	}
+	return &fc, nil
+}
+
+// loadConfigS3 loads config to S3 and return pointer to S3
+func loadConfigS3(config map[string]string) (*S3Client, error) {
+	var (
+		s3c S3Client
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling s3 config", err)
+		return &s3c, err
+	}
+	err = json.Unmarshal(cfgByte, &s3c)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling s3 config", err)
+		return &s3c, err
+	}
+	err = s3c.init()
+	if err != nil {
+		log.Errorf("error[%v] in s3 config", err)
+		return &s3c, err
+	}
+	return &s3c, nil
+}
+
+// loadConfigClickHouse loads config to ClickHouse and return pointer to ClickHouse
+func loadConfigClickHouse(config map[string]string) (*ClickHouseClient, error) {
+	var (
+		cc  ClickHouseClient
+		err error
+	)
+	cfgByte, err := json.Marshal(config)
+	if err != nil {
+		log.Errorf("error[%v] in marshalling clickhouse config", err)
+		return &cc, err
+	}
+	err = json.Unmarshal(cfgByte, &cc)
+	if err != nil {
+		log.Errorf("error[%v] in unmarshalling clickhouse config", err)
+		return &cc, err
+	}
+	err = cc.init(config)
+	if err != nil {
+		log.Errorf("error[%v] in clickhouse config", err)
+		return &cc, err
+	}
+	return &cc, nil
+}
+
 // HTTPClientWithRetry creates a HTTP client
 func HTTPClientWithRetry() *retryhttp.Client {
 	client := retryhttp.NewClient()
@@ -93,3 +362,36 @@ func HTTPClientWithRetry() *retryhttp.Client {
 	client.Logger = log
 	return client
 }
+
+// documentRepository returns the repository name and owner of a document , owner is taken from repo_url
+// when document does not have repo_owner tag
+func documentRepository(doc map[string]interface{}) (string, string) {
+	repoName, _ := doc["repo_name"].(string)
+	repoOwner, _ := doc["repo_owner"].(string)
+	if repoURL, ok := doc["repo_url"].(string); ok && repoOwner == "" {
+		if u, err := url.Parse(repoURL); err == nil {
+			if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) >= 2 {
+				repoOwner = parts[len(parts)-2]
+			}
+		}
+	}
+	return repoName, repoOwner
+}
+
+// documentMaps returns documents as maps of their json fields , documents which can not be marshalled are skipped
+func documentMaps(data []interface{}) []map[string]interface{} {
+	docs := make([]map[string]interface{}, 0, len(data))
+	for _, doc := range data {
+		var m map[string]interface{}
+		byteData, err := json.Marshal(doc)
+		if err == nil {
+			err = json.Unmarshal(byteData, &m)
+		}
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		docs = append(docs, m)
+	}
+	return docs
+}
@@ -0,0 +1,827 @@
+package publisher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	retryhttp "github.com/hashicorp/go-retryablehttp"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	// formats of objects
+	S3FormatNDJSON  = "ndjson"
+	S3FormatParquet = "parquet"
+
+	// compressions of ndjson objects , parquet objects are compressed with snappy
+	S3CompressionGzip = "gzip"
+	S3CompressionNone = "none"
+
+	defaultS3Region        = "us-east-1"
+	defaultS3FlushSizeMB   = 64
+	defaultS3FlushInterval = "5m"
+	defaultS3PartSizeMB    = 16
+	defaultS3BufferDir     = "s3buffer"
+	minS3PartSizeMB        = 5
+
+	// s3BufferFileExt is the extension of buffer files , named <escaped partition>.<created at in unix nanoseconds>-<id>
+	s3BufferFileExt = ".ndjson"
+
+	// s3MaxBufferedFlushes is the number of flush sizes buffered while uploads fail , documents are refused after it
+	// so that the task publishes them again in its next run
+	s3MaxBufferedFlushes = 4
+
+	// s3FlushCheckInterval is the interval at which buffers are checked for batches older than flush interval
+	s3FlushCheckInterval = 10 * time.Second
+
+	// hiveDefaultPartition is the partition of documents without a value of partition column , as named by hive
+	hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+	s3TimeFormat  = "20060102T150405Z"
+	s3SignAlgo    = "AWS4-HMAC-SHA256"
+	s3ServiceName = "s3"
+)
+
+var (
+	ErrMissingS3Bucket      = errors.New("missing s3 bucket")
+	ErrMissingS3Credentials = errors.New("missing s3 access key id and secret access key")
+	ErrS3Format             = errors.New("s3 format must be ndjson or parquet")
+	ErrS3Compression        = errors.New("s3 compression must be gzip or none")
+	ErrS3BufferFull         = errors.New("s3 buffer is full as uploads are failing")
+
+	// s3Buffers holds documents waiting to be uploaded with their destination as key , as publishers are created
+	// for every run of a task while objects hold documents of many runs
+	s3Buffers      = make(map[string]*s3Buffer)
+	s3BuffersMutex sync.Mutex
+
+	s3PartitionEscaper = strings.NewReplacer("/", "_", `\`, "_", "=", "_")
+	parquetColumnName  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// S3Client holds config for s3 target , documents are uploaded to any s3 compatible storage as objects of a
+// hive style layout <prefix>/repo=<repo_name>/document_type=<document_type>/date=<yyyy-mm-dd>/<time>-<id>.<format>
+type S3Client struct {
+	// Endpoint of storage , for ex. http://minio:9000 , Default: https://s3.<region>.amazonaws.com
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+
+	// Region of bucket , Default: us-east-1
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+
+	// Bucket to upload objects to
+	Bucket string `yaml:"bucket" json:"bucket"`
+
+	// Prefix of object keys
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// AccessKeyID for signing requests , Default: AWS_ACCESS_KEY_ID environment variable
+	AccessKeyID string `yaml:"access_key_id,omitempty" json:"access_key_id,omitempty"`
+
+	// SecretAccessKey for signing requests , Default: AWS_SECRET_ACCESS_KEY environment variable
+	SecretAccessKey string `yaml:"secret_access_key,omitempty" json:"secret_access_key,omitempty"`
+
+	// SessionToken of temporary credentials , Default: AWS_SESSION_TOKEN environment variable
+	SessionToken string `yaml:"session_token,omitempty" json:"session_token,omitempty"`
+
+	// PathStyle puts bucket in path of url instead of host , needed by minio and most other s3 compatible storages
+	PathStyle bool `yaml:"path_style,omitempty" json:"path_style,omitempty,string"`
+
+	// Format of objects , ndjson or parquet , Default: ndjson
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+
+	// Compression of ndjson objects , gzip or none , Default: gzip
+	Compression string `yaml:"compression,omitempty" json:"compression,omitempty"`
+
+	// FlushSize in megabytes of documents of a partition after which they are uploaded , Default: 64
+	FlushSize int `yaml:"flush_size,omitempty" json:"flush_size,omitempty,string"`
+
+	// FlushInterval after which documents of a partition are uploaded , Default: 5m
+	FlushInterval string `yaml:"flush_interval,omitempty" json:"flush_interval,omitempty"`
+
+	// PartSize in megabytes of multipart uploads , objects larger than it are uploaded in parts , Default: 16
+	PartSize int `yaml:"part_size,omitempty" json:"part_size,omitempty,string"`
+
+	// BufferDir is the directory where documents are kept until they are uploaded , documents are written and synced
+	// to it before publish returns and uploaded after a restart , Default: s3buffer
+	BufferDir string `yaml:"buffer_dir,omitempty" json:"buffer_dir,omitempty"`
+
+	// InsecureSkipVerify skips verification of server certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty,string"`
+
+	endpoint      *url.URL
+	flushInterval time.Duration
+}
+
+// s3Buffer holds documents of a destination by partition until they are uploaded , along with the files in buffer
+// directory holding the same documents
+type s3Buffer struct {
+	mutex   sync.Mutex
+	client  *S3Client
+	dir     string
+	batches map[string]*s3Batch
+	size    int
+
+	// flushMutex makes flushes of publishers and background flushes upload one after another
+	flushMutex sync.Mutex
+}
+
+// s3Batch holds documents of a partition as json lines , files are the buffer files of documents which are
+// removed once documents are uploaded
+type s3Batch struct {
+	lines     []byte
+	count     int
+	createdAt time.Time
+	files     []string
+}
+
+// s3ErrorResponse is the error returned by s3
+type s3ErrorResponse struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// Publish buffers documents by partition and uploads partitions larger than flush size , other partitions
+// are uploaded by a background flush after flush interval. Documents are written to buffer directory before
+// it returns , so that they are uploaded even if github-audit is killed before.
+func (s3c *S3Client) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	buf, err := getS3Buffer(s3c)
+	if err != nil {
+		log.Errorf("error[%v] in loading buffer directory %s for bucket %s", err, s3c.BufferDir, s3c.Bucket)
+		return err
+	}
+	now := time.Now()
+	if err = buf.add(s3c, data, now); err != nil {
+		log.Errorf("error[%v] in buffering %d docs for bucket %s", err, len(data), s3c.Bucket)
+		return err
+	}
+	buf.flush(now, false)
+	return nil
+}
+
+// Flush uploads documents buffered by targets , it is called before github-audit exits
+func Flush() {
+	s3BuffersMutex.Lock()
+	buffers := make([]*s3Buffer, 0, len(s3Buffers))
+	for _, buf := range s3Buffers {
+		buffers = append(buffers, buf)
+	}
+	s3BuffersMutex.Unlock()
+	for _, buf := range buffers {
+		buf.flush(time.Now(), true)
+	}
+}
+
+// getS3Buffer returns the buffer of destination of client , creating it with its background flush for first use.
+// Documents left in buffer directory of destination by a previous run of github-audit are loaded to new buffers.
+func getS3Buffer(s3c *S3Client) (*s3Buffer, error) {
+	key := strings.Join([]string{s3c.endpoint.String(), s3c.Bucket, s3c.Prefix, s3c.Format, s3c.Compression}, "|")
+	s3BuffersMutex.Lock()
+	defer s3BuffersMutex.Unlock()
+	buf, ok := s3Buffers[key]
+	if !ok {
+		keyHash := sha256.Sum256([]byte(key))
+		buf = &s3Buffer{client: s3c, dir: filepath.Join(s3c.BufferDir, hex.EncodeToString(keyHash[:8])), batches: make(map[string]*s3Batch)}
+		if err := buf.load(); err != nil {
+			return nil, err
+		}
+		s3Buffers[key] = buf
+		interval := s3FlushCheckInterval
+		if s3c.flushInterval < interval {
+			interval = s3c.flushInterval
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for now := range ticker.C {
+				buf.flush(now, false)
+			}
+		}()
+	}
+	return buf, nil
+}
+
+// load creates buffer directory and adds documents of its files to batches
+func (buf *s3Buffer) load() error {
+	if err := os.MkdirAll(buf.dir, 0750); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(buf.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), s3BufferFileExt)
+		dot := strings.LastIndex(name, ".")
+		if entry.IsDir() || name == entry.Name() || dot < 0 {
+			continue
+		}
+		partition, err := url.QueryUnescape(name[:dot])
+		if err != nil {
+			log.Errorf("error[%v] in reading partition of buffer file %s", err, entry.Name())
+			continue
+		}
+		nanos, _ := strconv.ParseInt(strings.SplitN(name[dot+1:], "-", 2)[0], 10, 64)
+		file := filepath.Join(buf.dir, entry.Name())
+		lines, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		// lines of a partial write are dropped , the task publishes them again as publish failed
+		if end := bytes.LastIndexByte(lines, '\n'); end+1 < len(lines) {
+			lines = lines[:end+1]
+		}
+		batch, ok := buf.batches[partition]
+		if !ok {
+			batch = &s3Batch{createdAt: time.Unix(0, nanos)}
+			buf.batches[partition] = batch
+		}
+		if createdAt := time.Unix(0, nanos); createdAt.Before(batch.createdAt) {
+			batch.createdAt = createdAt
+		}
+		batch.lines = append(batch.lines, lines...)
+		batch.count += bytes.Count(lines, []byte{'\n'})
+		batch.files = append(batch.files, file)
+		buf.size += len(lines)
+	}
+	if len(buf.batches) > 0 {
+		log.Infof("loaded %d partitions to upload from buffer directory %s", len(buf.batches), buf.dir)
+	}
+	return nil
+}
+
+// write appends lines to the last buffer file of batch , creating it for new batches , and syncs it to disk
+func (buf *s3Buffer) write(partition string, batch *s3Batch, lines []byte) error {
+	if len(batch.files) == 0 {
+		id := make([]byte, 4)
+		rand.Read(id)
+		name := url.QueryEscape(partition) + "." + strconv.FormatInt(batch.createdAt.UnixNano(), 10) + "-" + hex.EncodeToString(id) + s3BufferFileExt
+		batch.files = append(batch.files, filepath.Join(buf.dir, name))
+	}
+	f, err := os.OpenFile(batch.files[len(batch.files)-1], os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(lines); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// add appends documents to batches of their partitions and to their buffer files , documents are refused when
+// uploads are failing and buffer holds more than max buffered flushes
+func (buf *s3Buffer) add(s3c *S3Client, data []interface{}, now time.Time) error {
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
+	// latest config is used for uploads , for ex. for rotated credentials
+	buf.client = s3c
+	if buf.size >= s3MaxBufferedFlushes*s3c.FlushSize*1024*1024 {
+		return ErrS3BufferFull
+	}
+	partitionLines := make(map[string][]byte)
+	partitionCounts := make(map[string]int)
+	var partitions []string
+	for _, doc := range data {
+		byteData, err := json.Marshal(doc)
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		var fields map[string]interface{}
+		if err = json.Unmarshal(byteData, &fields); err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		partition := s3Partition(fields, now)
+		if _, ok := partitionLines[partition]; !ok {
+			partitions = append(partitions, partition)
+		}
+		partitionLines[partition] = append(append(partitionLines[partition], byteData...), '\n')
+		partitionCounts[partition]++
+	}
+	for _, partition := range partitions {
+		batch, ok := buf.batches[partition]
+		if !ok {
+			batch = &s3Batch{createdAt: now}
+		}
+		lines := partitionLines[partition]
+		if err := buf.write(partition, batch, lines); err != nil {
+			return err
+		}
+		buf.batches[partition] = batch
+		batch.lines = append(batch.lines, lines...)
+		batch.count += partitionCounts[partition]
+		buf.size += len(lines)
+	}
+	return nil
+}
+
+// flush uploads batches larger than flush size or older than flush interval , all batches if all is set ,
+// batches which are not uploaded are kept for next flush
+func (buf *s3Buffer) flush(now time.Time, all bool) {
+	buf.flushMutex.Lock()
+	defer buf.flushMutex.Unlock()
+
+	buf.mutex.Lock()
+	s3c := buf.client
+	due := make(map[string]*s3Batch)
+	for partition, batch := range buf.batches {
+		if all || len(batch.lines) >= s3c.FlushSize*1024*1024 || now.Sub(batch.createdAt) >= s3c.flushInterval {
+			due[partition] = batch
+			delete(buf.batches, partition)
+		}
+	}
+	buf.mutex.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	client := s3c.httpClient()
+	partitions := make([]string, 0, len(due))
+	for partition := range due {
+		partitions = append(partitions, partition)
+	}
+	sort.Strings(partitions)
+	for _, partition := range partitions {
+		batch := due[partition]
+		key, err := s3c.upload(client, partition, batch.lines, now)
+		if err == nil {
+			log.Infof("successfully uploaded %d docs to %s/%s", batch.count, s3c.Bucket, key)
+			for _, file := range batch.files {
+				if err = os.Remove(file); err != nil {
+					log.Errorf("error[%v] in removing uploaded buffer file %s", err, file)
+				}
+			}
+			buf.mutex.Lock()
+			buf.size -= len(batch.lines)
+			buf.mutex.Unlock()
+			continue
+		}
+		log.Errorf("error[%v] in uploading %d docs of partition %s to bucket %s", err, batch.count, partition, s3c.Bucket)
+		// documents added while uploading are appended to the batch which failed , along with their files
+		buf.mutex.Lock()
+		if added, ok := buf.batches[partition]; ok {
+			batch.lines = append(batch.lines, added.lines...)
+			batch.count += added.count
+			batch.files = append(batch.files, added.files...)
+		}
+		buf.batches[partition] = batch
+		buf.mutex.Unlock()
+	}
+}
+
+// s3Partition returns the hive style partition of a document , date is the utc date of created_at of document
+func s3Partition(doc map[string]interface{}, now time.Time) string {
+	repoName, _ := doc["repo_name"].(string)
+	documentType, _ := doc["document_type"].(string)
+	return "repo=" + hivePartitionValue(repoName) + "/document_type=" + hivePartitionValue(documentType) +
+		"/date=" + documentTime(doc, now).UTC().Format("2006-01-02")
+}
+
+// hivePartitionValue returns value usable in a partition path , the default partition of hive if it is empty
+func hivePartitionValue(value string) string {
+	if value == "" {
+		return hiveDefaultPartition
+	}
+	return s3PartitionEscaper.Replace(value)
+}
+
+// upload encodes lines in format of client and uploads them as a new object of partition , objects larger
+// than part size are uploaded in parts
+func (s3c *S3Client) upload(client *retryhttp.Client, partition string, lines []byte, now time.Time) (string, error) {
+	var (
+		body        []byte
+		ext         string
+		contentType string
+		err         error
+	)
+	switch {
+	case s3c.Format == S3FormatParquet:
+		body, err = encodeParquet(lines)
+		ext, contentType = ".parquet", "application/vnd.apache.parquet"
+	case s3c.Compression == S3CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err = zw.Write(lines); err == nil {
+			err = zw.Close()
+		}
+		body = buf.Bytes()
+		ext, contentType = ".ndjson.gz", "application/x-ndjson"
+	default:
+		body = lines
+		ext, contentType = ".ndjson", "application/x-ndjson"
+	}
+	if err != nil {
+		return "", err
+	}
+	id := make([]byte, 4)
+	rand.Read(id)
+	key := path.Join(strings.Trim(s3c.Prefix, "/"), partition, now.UTC().Format(s3TimeFormat)+"-"+hex.EncodeToString(id)+ext)
+
+	if len(body) <= s3c.PartSize*1024*1024 {
+		_, err = s3c.do(client, http.MethodPut, key, nil, body, contentType, nil)
+		return key, err
+	}
+	return key, s3c.multipartUpload(client, key, body, contentType)
+}
+
+// multipartUpload uploads body in parts of part size , the upload is aborted if a part fails
+func (s3c *S3Client) multipartUpload(client *retryhttp.Client, key string, body []byte, contentType string) error {
+	var initiate struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if _, err := s3c.do(client, http.MethodPost, key, url.Values{"uploads": {""}}, nil, contentType, &initiate); err != nil {
+		return err
+	}
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var complete struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}
+	partSize := s3c.PartSize * 1024 * 1024
+	for start, number := 0, 1; start < len(body); start, number = start+partSize, number+1 {
+		end := start + partSize
+		if end > len(body) {
+			end = len(body)
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiate.UploadID}}
+		header, err := s3c.do(client, http.MethodPut, key, query, body[start:end], "", nil)
+		if err != nil {
+			s3c.abortMultipartUpload(client, key, initiate.UploadID)
+			return err
+		}
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: number, ETag: header.Get("ETag")})
+	}
+	completeBody, _ := xml.Marshal(complete)
+	if _, err := s3c.do(client, http.MethodPost, key, url.Values{"uploadId": {initiate.UploadID}}, completeBody, "application/xml", &struct{}{}); err != nil {
+		s3c.abortMultipartUpload(client, key, initiate.UploadID)
+		return err
+	}
+	log.Debugf("uploaded %s in %d parts", key, len(complete.Parts))
+	return nil
+}
+
+// abortMultipartUpload aborts an upload so that storage removes its parts
+func (s3c *S3Client) abortMultipartUpload(client *retryhttp.Client, key string, uploadID string) {
+	if _, err := s3c.do(client, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, "", nil); err != nil {
+		log.Errorf("error[%v] in aborting multipart upload of %s", err, key)
+	}
+}
+
+// do sends a signed request for object key and decodes xml response to result , an error response sent with
+// status 200 , as by complete multipart upload , is returned as error
+func (s3c *S3Client) do(client *retryhttp.Client, method string, key string, query url.Values, body []byte, contentType string, result interface{}) (http.Header, error) {
+	u := s3c.objectURL(key, query)
+	request, err := retryhttp.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	payloadHash := sha256.Sum256(body)
+	s3c.sign(request.Request, hex.EncodeToString(payloadHash[:]), time.Now())
+
+	timeout, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	response, err := client.Do(request.WithContext(timeout))
+	if err != nil {
+		log.Errorf("error[%v] request failed", err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	respBody, err := ioutil.ReadAll(io.LimitReader(response.Body, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	var s3Err s3ErrorResponse
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message := strings.TrimSpace(string(respBody))
+		if xml.Unmarshal(respBody, &s3Err) == nil {
+			message = s3Err.Code + ": " + s3Err.Message
+		}
+		return nil, fmt.Errorf("s3 %s request failed with status %s: %s", strings.ToLower(method), response.Status, message)
+	}
+	if result != nil {
+		if xml.Unmarshal(respBody, &s3Err) == nil {
+			return nil, fmt.Errorf("s3 %s request failed: %s: %s", strings.ToLower(method), s3Err.Code, s3Err.Message)
+		}
+		if err = xml.Unmarshal(respBody, result); err != nil {
+			return nil, fmt.Errorf("invalid s3 response: %w", err)
+		}
+	}
+	return response.Header, nil
+}
+
+// objectURL returns the url of object key , bucket is in path for path style and in host otherwise
+func (s3c *S3Client) objectURL(key string, query url.Values) *url.URL {
+	u := *s3c.endpoint
+	objectPath := "/" + key
+	if s3c.PathStyle {
+		objectPath = "/" + s3c.Bucket + objectPath
+	} else {
+		u.Host = s3c.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + objectPath
+	u.RawPath = s3URIEncode(u.Path, false)
+	u.RawQuery = s3CanonicalQuery(query)
+	return &u
+}
+
+// sign adds the authorization header of aws signature version 4 to request , host , content type and
+// x-amz-* headers are signed
+func (s3c *S3Client) sign(request *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format(s3TimeFormat)
+	date := amzDate[:8]
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s3c.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", s3c.SessionToken)
+	}
+
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range request.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "content-md5" || name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join([]string{date, s3c.Region, s3ServiceName, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{s3SignAlgo, amzDate, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s3c.SecretAccessKey), date)
+	key = hmacSHA256(key, s3c.Region)
+	key = hmacSHA256(key, s3ServiceName)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SignAlgo, s3c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the hmac of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3URIEncode encodes all characters except unreserved ones as required by signature version 4 , slashes
+// are kept unless encodeSlash is set
+func s3URIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// s3CanonicalQuery returns the query encoded and sorted by key as required by signature version 4
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, s3URIEncode(key, true)+"="+s3URIEncode(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// encodeParquet returns json lines as a parquet file , columns are the fields of documents , strings ,
+// numbers and booleans keep their type while nested fields and fields with values of different types
+// are json strings
+func encodeParquet(lines []byte) ([]byte, error) {
+	var docs []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(lines))
+	for decoder.More() {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	columns := make(map[string]string)
+	for _, doc := range docs {
+		for name, value := range doc {
+			if value == nil {
+				continue
+			}
+			if !parquetColumnName.MatchString(name) {
+				log.Debugf("skipping field %s which is not a valid parquet column", name)
+				continue
+			}
+			columnType := "BYTE_ARRAY, convertedtype=UTF8"
+			switch value.(type) {
+			case float64:
+				columnType = "DOUBLE"
+			case bool:
+				columnType = "BOOLEAN"
+			}
+			if existing, ok := columns[name]; ok && existing != columnType {
+				columnType = "BYTE_ARRAY, convertedtype=UTF8"
+			}
+			columns[name] = columnType
+		}
+	}
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type parquetField struct {
+		Tag    string         `json:"Tag"`
+		Fields []parquetField `json:"Fields,omitempty"`
+	}
+	schema := parquetField{Tag: "name=parquet_go_root, repetitiontype=REQUIRED"}
+	for _, name := range names {
+		schema.Fields = append(schema.Fields, parquetField{Tag: "name=" + name + ", type=" + columns[name] + ", repetitiontype=OPTIONAL"})
+	}
+	schemaByte, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	pw, err := writer.NewJSONWriterFromWriter(string(schemaByte), &buf, 1)
+	if err != nil {
+		return nil, err
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	for _, doc := range docs {
+		row := make(map[string]interface{})
+		for name, value := range doc {
+			columnType, ok := columns[name]
+			if !ok || value == nil {
+				continue
+			}
+			if _, isString := value.(string); strings.HasPrefix(columnType, "BYTE_ARRAY") && !isString {
+				valueByte, _ := json.Marshal(value)
+				value = string(valueByte)
+			}
+			row[name] = value
+		}
+		rowByte, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+		if err = pw.Write(string(rowByte)); err != nil {
+			return nil, err
+		}
+	}
+	if err = pw.WriteStop(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// httpClient returns the client for requests to storage
+func (s3c *S3Client) httpClient() *retryhttp.Client {
+	client := HTTPClientWithRetry()
+	if s3c.InsecureSkipVerify {
+		client.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Proxy: http.ProxyFromEnvironment,
+		}
+	}
+	return client
+}
+
+// init validates config and sets defaults , credentials not in config are taken from environment
+func (s3c *S3Client) init() error {
+	if s3c.Bucket == "" {
+		return ErrMissingS3Bucket
+	}
+	if s3c.AccessKeyID == "" && s3c.SecretAccessKey == "" {
+		s3c.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		s3c.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if s3c.SessionToken == "" {
+			s3c.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+	if s3c.AccessKeyID == "" || s3c.SecretAccessKey == "" {
+		return ErrMissingS3Credentials
+	}
+	if s3c.Region == "" {
+		s3c.Region = defaultS3Region
+	}
+	if s3c.Endpoint == "" {
+		s3c.Endpoint = "https://s3." + s3c.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(s3c.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return fmt.Errorf("invalid s3 endpoint %s", s3c.Endpoint)
+	}
+	s3c.endpoint = endpoint
+	switch s3c.Format {
+	case "":
+		s3c.Format = S3FormatNDJSON
+	case S3FormatNDJSON, S3FormatParquet:
+	default:
+		return ErrS3Format
+	}
+	switch s3c.Compression {
+	case "":
+		s3c.Compression = S3CompressionGzip
+	case S3CompressionGzip, S3CompressionNone:
+	default:
+		return ErrS3Compression
+	}
+	if s3c.FlushSize <= 0 {
+		s3c.FlushSize = defaultS3FlushSizeMB
+	}
+	if s3c.FlushInterval == "" {
+		s3c.FlushInterval = defaultS3FlushInterval
+	}
+	if s3c.flushInterval, err = utils.ParseDuration(s3c.FlushInterval); err != nil {
+		return err
+	}
+	if s3c.flushInterval <= 0 {
+		return fmt.Errorf("invalid s3 flush interval %s", s3c.FlushInterval)
+	}
+	if s3c.PartSize <= 0 {
+		s3c.PartSize = defaultS3PartSizeMB
+	}
+	if s3c.PartSize < minS3PartSizeMB {
+		s3c.PartSize = minS3PartSizeMB
+	}
+	if s3c.BufferDir == "" {
+		s3c.BufferDir = defaultS3BufferDir
+	}
+	// loading buffer so that documents left by a previous run are uploaded before the first publish
+	_, err = getS3Buffer(s3c)
+	return err
+}
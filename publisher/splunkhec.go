@@ -0,0 +1,329 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	retryhttp "github.com/hashicorp/go-retryablehttp"
+
+	"github.com/maplelabs/github-audit/utils"
+)
+
+const (
+	splunkEventPath     = "/services/collector/event"
+	splunkAckPath       = "/services/collector/ack"
+	splunkChannelHeader = "X-Splunk-Request-Channel"
+
+	defaultSplunkSource     = "github-audit"
+	defaultSplunkSourcetype = "_json"
+	defaultSplunkBatchSize  = 100
+	defaultSplunkAckTimeout = "1m"
+	splunkAckPollInterval   = time.Second
+
+	// hec status codes , https://docs.splunk.com/Documentation/Splunk/latest/Data/TroubleshootHTTPEventCollector
+	splunkCodeSuccess          = 0
+	splunkCodeNoData           = 5
+	splunkCodeInvalidFormat    = 6
+	splunkCodeEventRequired    = 12
+	splunkCodeEventBlank       = 13
+	splunkCodeIndexedFieldsErr = 15
+)
+
+var (
+	ErrMissingSplunkURL = errors.New("missing splunk hec url or token")
+	ErrSplunkAckTimeout = errors.New("timed out waiting for splunk indexer acknowledgement")
+
+	// splunkChannels holds the channel of a hec endpoint and token , as publishers are created for every run of a
+	// task while splunk keeps state of acknowledgements per channel
+	splunkChannels      = make(map[string]string)
+	splunkChannelsMutex sync.Mutex
+)
+
+// SplunkHECClient holds config for splunk http event collector target
+type SplunkHECClient struct {
+	// URL of http event collector , for ex. https://splunk:8088
+	URL string `yaml:"url" json:"url"`
+
+	// Token of http event collector
+	Token string `yaml:"token" json:"token"`
+
+	// Index of events , default index of token if empty
+	Index string `yaml:"index,omitempty" json:"index,omitempty"`
+
+	// Source of events , Default: github-audit
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+
+	// Sourcetype of events , Default: _json
+	Sourcetype string `yaml:"sourcetype,omitempty" json:"sourcetype,omitempty"`
+
+	// Host of events , splunk sets the host of request if empty
+	Host string `yaml:"host,omitempty" json:"host,omitempty"`
+
+	// BatchSize is the number of events in a request , Default: 100
+	BatchSize int `yaml:"batch_size,omitempty" json:"batch_size,omitempty,string"`
+
+	// Ack waits for indexer acknowledgement of batches , needs acknowledgement enabled for token
+	Ack bool `yaml:"ack,omitempty" json:"ack,omitempty,string"`
+
+	// AckTimeout is the time to wait for acknowledgement of batches , Default: 1m
+	AckTimeout string `yaml:"ack_timeout,omitempty" json:"ack_timeout,omitempty"`
+
+	// Channel is the request channel , a generated one is used if empty
+	Channel string `yaml:"channel,omitempty" json:"channel,omitempty"`
+
+	// InsecureSkipVerify skips verification of server certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty,string"`
+}
+
+// SplunkEvent is the hec envelope of a document
+type SplunkEvent struct {
+	Time       *float64    `json:"time,omitempty"`
+	Host       string      `json:"host,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	Sourcetype string      `json:"sourcetype,omitempty"`
+	Index      string      `json:"index,omitempty"`
+	Event      interface{} `json:"event"`
+}
+
+// SplunkResponse is the response of hec
+type SplunkResponse struct {
+	Text               string `json:"text"`
+	Code               int    `json:"code"`
+	AckID              *int64 `json:"ackId,omitempty"`
+	InvalidEventNumber *int   `json:"invalid-event-number,omitempty"`
+}
+
+// SplunkAckResponse is the response of hec ack endpoint
+type SplunkAckResponse struct {
+	SplunkResponse
+	Acks map[string]bool `json:"acks"`
+}
+
+// Publish pushes the data to target in batches. When hec rejects an event of a batch for invalid data , the events
+// before it are indexed , so only the invalid event is logged and skipped and the events after it are sent again.
+// A batch rejected without the invalid event is skipped. They are not reported as an error as sending them again
+// fails the same way. Errors of token , index or channel stop publishing and batches not acknowledged within ack
+// timeout are reported as an error.
+func (sc *SplunkHECClient) Publish(data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	client := HTTPClientWithRetry()
+	if sc.InsecureSkipVerify {
+		client.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Proxy: http.ProxyFromEnvironment,
+		}
+	}
+
+	var (
+		skipped int
+		ackIDs  = make(map[int64]int)
+	)
+	for start := 0; start < len(data); start += sc.BatchSize {
+		end := start + sc.BatchSize
+		if end > len(data) {
+			end = len(data)
+		}
+		batch := data[start:end]
+		for len(batch) > 0 {
+			body := sc.envelope(batch)
+			resp, err := sc.post(client, splunkEventPath, body)
+			if err != nil {
+				return err
+			}
+			switch resp.Code {
+			case splunkCodeSuccess:
+				if sc.Ack && resp.AckID != nil {
+					ackIDs[*resp.AckID] = len(batch)
+				}
+				batch = nil
+			case splunkCodeNoData, splunkCodeInvalidFormat, splunkCodeEventRequired, splunkCodeEventBlank, splunkCodeIndexedFieldsErr:
+				err = fmt.Errorf("splunk hec error code %d: %s", resp.Code, resp.Text)
+				if n := resp.InvalidEventNumber; n != nil && *n >= 0 && *n < len(batch) {
+					skipped++
+					log.Errorf("error[%v] in batch of %d events at event %d , skipping event and sending the %d events after it", err, len(batch), *n, len(batch)-*n-1)
+					batch = batch[*n+1:]
+				} else {
+					skipped += len(batch)
+					log.Errorf("error[%v] in batch of %d events , skipping batch", err, len(batch))
+					batch = nil
+				}
+			default:
+				err = fmt.Errorf("splunk hec error code %d: %s", resp.Code, resp.Text)
+				log.Errorf("error[%v] in sending data to %s", err, sc.URL)
+				return err
+			}
+		}
+	}
+	if len(ackIDs) > 0 {
+		unacked, err := sc.waitForAcks(client, ackIDs)
+		if err != nil {
+			return err
+		}
+		if unacked > 0 {
+			return fmt.Errorf("%d of %d documents not indexed by splunk: %w", unacked, len(data), ErrSplunkAckTimeout)
+		}
+	}
+	log.Infof("successfully sent %d docs to %s , skipped %d invalid docs", len(data)-skipped, sc.URL, skipped)
+	return nil
+}
+
+// envelope returns hec events of documents , time of event is created_at of document
+func (sc *SplunkHECClient) envelope(data []interface{}) []byte {
+	var body []byte
+	for _, doc := range data {
+		byteData, err := json.Marshal(doc)
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal data", err)
+			continue
+		}
+		event := SplunkEvent{Host: sc.Host, Source: sc.Source, Sourcetype: sc.Sourcetype, Index: sc.Index, Event: json.RawMessage(byteData)}
+		var fields struct {
+			CreatedAt time.Time `json:"created_at"`
+		}
+		if json.Unmarshal(byteData, &fields) == nil && !fields.CreatedAt.IsZero() {
+			t := float64(fields.CreatedAt.UnixNano()/int64(time.Millisecond)) / 1000
+			event.Time = &t
+		}
+		eventData, err := json.Marshal(event)
+		if err != nil {
+			log.Errorf("error[%v] unable to marshal event", err)
+			continue
+		}
+		body = append(body, eventData...)
+		body = append(body, '\n')
+	}
+	return body
+}
+
+// waitForAcks polls hec for acknowledgement of batches till all are acknowledged or ack timeout ,
+// returns the number of documents in batches not acknowledged
+func (sc *SplunkHECClient) waitForAcks(client *retryhttp.Client, ackIDs map[int64]int) (int, error) {
+	timeout, _ := utils.ParseDuration(sc.AckTimeout)
+	deadline := time.Now().Add(timeout)
+	for {
+		ids := make([]int64, 0, len(ackIDs))
+		for id := range ackIDs {
+			ids = append(ids, id)
+		}
+		body, _ := json.Marshal(map[string][]int64{"acks": ids})
+		var acks SplunkAckResponse
+		if err := sc.postJSON(client, splunkAckPath, body, &acks); err != nil {
+			return 0, err
+		}
+		if acks.Code != splunkCodeSuccess {
+			err := fmt.Errorf("splunk hec error code %d: %s", acks.Code, acks.Text)
+			log.Errorf("error[%v] in polling acknowledgement of %s", err, sc.URL)
+			return 0, err
+		}
+		for id, acked := range acks.Acks {
+			var ackID int64
+			if _, err := fmt.Sscan(id, &ackID); err == nil && acked {
+				delete(ackIDs, ackID)
+			}
+		}
+		if len(ackIDs) == 0 {
+			return 0, nil
+		}
+		if time.Now().Add(splunkAckPollInterval).After(deadline) {
+			var unacked int
+			for _, n := range ackIDs {
+				unacked += n
+			}
+			log.Errorf("error[%v] for %d batches of %s", ErrSplunkAckTimeout, len(ackIDs), sc.URL)
+			return unacked, nil
+		}
+		time.Sleep(splunkAckPollInterval)
+	}
+}
+
+// post sends body to hec endpoint at path and returns its status
+func (sc *SplunkHECClient) post(client *retryhttp.Client, path string, body []byte) (SplunkResponse, error) {
+	var resp SplunkResponse
+	err := sc.postJSON(client, path, body, &resp)
+	return resp, err
+}
+
+// postJSON sends body to hec endpoint at path and decodes response to v , hec responds with a status code
+// in body for both successful and failed requests , callers check it
+func (sc *SplunkHECClient) postJSON(client *retryhttp.Client, path string, body []byte, v interface{}) error {
+	request, err := retryhttp.NewRequest(http.MethodPost, strings.TrimSuffix(sc.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Splunk "+sc.Token)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(splunkChannelHeader, sc.Channel)
+
+	timeout, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	response, err := client.Do(request.WithContext(timeout))
+	if err != nil {
+		log.Errorf("error[%v] request failed", err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if err = json.NewDecoder(response.Body).Decode(v); err != nil {
+		log.Errorf("error[%v] failed to decode response of %s", err, sc.URL)
+		return fmt.Errorf("error in sending data to %s , status: %s", sc.URL, response.Status)
+	}
+	return nil
+}
+
+// init validates config and sets defaults
+func (sc *SplunkHECClient) init() error {
+	if sc.URL == "" || sc.Token == "" {
+		return ErrMissingSplunkURL
+	}
+	if sc.Source == "" {
+		sc.Source = defaultSplunkSource
+	}
+	if sc.Sourcetype == "" {
+		sc.Sourcetype = defaultSplunkSourcetype
+	}
+	if sc.BatchSize <= 0 {
+		sc.BatchSize = defaultSplunkBatchSize
+	}
+	if sc.AckTimeout == "" {
+		sc.AckTimeout = defaultSplunkAckTimeout
+	}
+	if _, err := utils.ParseDuration(sc.AckTimeout); err != nil {
+		return err
+	}
+	if sc.Channel == "" {
+		sc.Channel = splunkChannel(sc.URL + "|" + sc.Token)
+	}
+	return nil
+}
+
+// splunkChannel returns the channel for key , creating a new one for first use
+func splunkChannel(key string) string {
+	splunkChannelsMutex.Lock()
+	defer splunkChannelsMutex.Unlock()
+	if channel, ok := splunkChannels[key]; ok {
+		return channel
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	channel := h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+	splunkChannels[key] = channel
+	return channel
+}
@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSplunkHECClient_Publish(t *testing.T) {
+	docs := []interface{}{
+		map[string]interface{}{"document_type": "commit", "repo_name": "repo1", "created_at": "2022-10-10T14:00:00.250+05:30"},
+		map[string]interface{}{"document_type": "invalid", "repo_name": "repo1"},
+		map[string]interface{}{"document_type": "issue", "repo_name": "repo2", "created_at": "2022-10-10T08:30:00Z"},
+	}
+	var events []SplunkEvent
+	var channels []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Splunk xxxx" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"text":"Invalid token","code":4}`)
+			return
+		}
+		channels = append(channels, r.Header.Get(splunkChannelHeader))
+		if r.URL.Path == splunkAckPath {
+			var req map[string][]int64
+			json.NewDecoder(r.Body).Decode(&req)
+			acks := make(map[string]bool)
+			for _, id := range req["acks"] {
+				acks[fmt.Sprint(id)] = true
+			}
+			json.NewEncoder(w).Encode(SplunkAckResponse{Acks: acks})
+			return
+		}
+		scanner := bufio.NewScanner(r.Body)
+		var batch []SplunkEvent
+		for scanner.Scan() {
+			var e SplunkEvent
+			json.Unmarshal(scanner.Bytes(), &e)
+			// like hec , events before an invalid event are indexed
+			if strings.Contains(fmt.Sprint(e.Event), "invalid") {
+				events = append(events, batch...)
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"text":"Invalid data format","code":6,"invalid-event-number":%d}`, len(batch))
+				return
+			}
+			batch = append(batch, e)
+		}
+		events = append(events, batch...)
+		fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, len(events))
+	}))
+	defer server.Close()
+
+	sc, err := loadConfigSplunkHEC(map[string]string{"url": server.URL, "token": "xxxx", "index": "audit", "batch_size": "3", "ack": "true"})
+	if err != nil {
+		t.Fatalf("loadConfigSplunkHEC() error = %v", err)
+	}
+	// invalid event is skipped and events after it are sent again
+	if err = sc.Publish(docs); err != nil {
+		t.Errorf("Publish() error = %v", err)
+	}
+	if len(events) != 2 || len(channels) != 3 {
+		t.Fatalf("Publish() sent %v events in %v requests, want 2 events in 2 batches and an ack request", len(events), len(channels))
+	}
+	wantTimes := []float64{1665390600.25, 1665390600}
+	for i, e := range events {
+		if e.Time == nil || *e.Time != wantTimes[i] || e.Index != "audit" || e.Source != defaultSplunkSource || e.Sourcetype != defaultSplunkSourcetype {
+			t.Errorf("Publish() event = %+v, want time %v", e, wantTimes[i])
+		}
+	}
+	for _, channel := range channels {
+		if channel == "" || channel != channels[0] {
+			t.Errorf("Publish() channels = %v, want same channel for all requests", channels)
+			break
+		}
+	}
+
+	sc.Token = "wrong"
+	if err = sc.Publish(docs[:1]); err == nil || !strings.Contains(err.Error(), "code 4") {
+		t.Errorf("Publish() error = %v, want invalid token error", err)
+	}
+}
@@ -3,6 +3,7 @@ package utils
 
 import (
 	"encoding/base64"
+	"strconv"
 	"strings"
 	"time"
 
@@ -42,3 +43,17 @@ func ConvertIntervalToDuration(interval string) time.Duration {
 	}
 	return duration
 }
+
+// ParseDuration converts durations like 30s , 5m , 4h or 3d to golang's duration. Days are not supported by
+// time.ParseDuration so they are converted to hours before parsing.
+func ParseDuration(interval string) (time.Duration, error) {
+	interval = strings.ToLower(strings.TrimSpace(interval))
+	if strings.HasSuffix(interval, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(interval, "d"), 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(interval)
+}